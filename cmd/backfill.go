@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	database "github.com/ovrrtd/openidea-bank/db"
+	"github.com/ovrrtd/openidea-bank/internal/repository"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/pkgerrors"
+)
+
+// BackfillBalanceHistory fills balance before and after on history entries written before they were recorded
+func BackfillBalanceHistory() error {
+	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+	logger := zerolog.New(os.Stdout)
+	db, err := database.NewDBDefaultSql()
+	if err != nil {
+		logger.Info().Msg(fmt.Sprintf("Postgres connection error: %s", err.Error()))
+		return err
+	}
+	defer db.Close()
+
+	balanceRepo := repository.NewBalanceRepository(logger, db)
+	updated, _, err := balanceRepo.BackfillBalanceHistory(context.Background())
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to backfill balance history")
+		return err
+	}
+
+	logger.Info().Int64("updated", updated).Msg("backfilled balance history")
+	return nil
+}
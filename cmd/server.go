@@ -1,19 +1,31 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 	database "github.com/ovrrtd/openidea-bank/db"
 	mw "github.com/ovrrtd/openidea-bank/internal/delivery/middleware"
 	"github.com/ovrrtd/openidea-bank/internal/delivery/restapi"
+	"github.com/ovrrtd/openidea-bank/internal/helper/bank"
+	"github.com/ovrrtd/openidea-bank/internal/helper/jwt"
+	"github.com/ovrrtd/openidea-bank/internal/helper/validator"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/ovrrtd/openidea-bank/internal/model/money"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
 	"github.com/ovrrtd/openidea-bank/internal/repository"
 	"github.com/ovrrtd/openidea-bank/internal/service"
+	"github.com/ovrrtd/openidea-bank/internal/worker"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/pkgerrors"
@@ -45,25 +57,208 @@ func Server() error {
 	userRepo := repository.NewUserRepository(logger, db)
 	balanceRepo := repository.NewBalanceRepository(logger, db)
 	s3Repo := repository.NewS3Repository(logger)
+	idempotencyRepo := repository.NewIdempotencyRepository(logger, db)
+	tokenRepo := repository.NewTokenRepository(logger, db)
+	fxRepo := repository.NewFxRepository(logger, db)
+	bankGateway := repository.NewSimulatedBankGateway(logger, simulatedBankConfig())
 
 	salt, err := strconv.Atoi(os.Getenv("BCRYPT_SALT"))
 	if err != nil {
 		salt = 8
 	}
+	accessTokenTTL, err := time.ParseDuration(os.Getenv("ACCESS_TOKEN_TTL"))
+	if err != nil {
+		accessTokenTTL = 15 * time.Minute
+	}
+	refreshTokenTTL, err := time.ParseDuration(os.Getenv("REFRESH_TOKEN_TTL"))
+	if err != nil {
+		refreshTokenTTL = 30 * 24 * time.Hour
+	}
+	jwtKeyGrace, err := time.ParseDuration(os.Getenv("JWT_KEY_GRACE"))
+	if err != nil {
+		jwtKeyGrace = 24 * time.Hour
+	}
+	// a replaced key has to keep verifying until the last token signed with it expires
+	if jwtKeyGrace < accessTokenTTL {
+		err := fmt.Errorf("JWT_KEY_GRACE %s is shorter than ACCESS_TOKEN_TTL %s", jwtKeyGrace, accessTokenTTL)
+		logger.Error().Err(err).Msg("invalid jwt key grace period")
+		return err
+	}
+	jwtEphemeralKeys, _ := strconv.ParseBool(os.Getenv("JWT_EPHEMERAL_KEYS"))
+	jwtKeys, err := loadJwtKeys(logger, os.Getenv("JWT_KEYS_DIR"), jwtKeyGrace, jwtEphemeralKeys)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to load jwt keys")
+		return err
+	}
+	idempotencyRetention, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_RETENTION"))
+	if err != nil {
+		idempotencyRetention = 24 * time.Hour
+	}
+	fxQuoteTTL, err := time.ParseDuration(os.Getenv("FX_QUOTE_TTL"))
+	if err != nil {
+		fxQuoteTTL = 30 * time.Second
+	}
+	holdTTL, err := time.ParseDuration(os.Getenv("HOLD_TTL"))
+	if err != nil {
+		holdTTL = 72 * time.Hour
+	}
+	transferMaxAttempts, err := strconv.Atoi(os.Getenv("TRANSFER_MAX_ATTEMPTS"))
+	if err != nil {
+		transferMaxAttempts = 5
+	}
+	transferReturnWindow, err := time.ParseDuration(os.Getenv("TRANSFER_RETURN_WINDOW"))
+	if err != nil {
+		transferReturnWindow = 14 * 24 * time.Hour
+	}
+	standingOrderMaxAttempts, err := strconv.Atoi(os.Getenv("STANDING_ORDER_MAX_ATTEMPTS"))
+	if err != nil {
+		standingOrderMaxAttempts = 3
+	}
+	beneficiaryCoolingOff, err := time.ParseDuration(os.Getenv("BENEFICIARY_COOLING_OFF"))
+	if err != nil {
+		beneficiaryCoolingOff = 24 * time.Hour
+	}
+	beneficiaryCoolingOffLimit, err := strconv.ParseInt(os.Getenv("BENEFICIARY_COOLING_OFF_LIMIT"), 10, 64)
+	if err != nil {
+		beneficiaryCoolingOffLimit = 1000000
+	}
+	banks, err := loadBankDirectory(os.Getenv("BANK_DIRECTORY_FILE"))
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to load bank directory")
+		return err
+	}
+	transferLimits, err := parseTransferLimits(os.Getenv("TRANSFER_LIMITS"))
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to parse transfer limits")
+		return err
+	}
+	// an empty key would let anyone sign a receipt that verifies
+	receiptSecret, err := requireEnv("RECEIPT_SECRET")
+	if err != nil {
+		logger.Error().Err(err).Msg("receipt signing secret is missing")
+		return err
+	}
+	pinMaxAttempts, err := strconv.Atoi(os.Getenv("PIN_MAX_ATTEMPTS"))
+	if err != nil {
+		pinMaxAttempts = 5
+	}
+	pinLockout, err := time.ParseDuration(os.Getenv("PIN_LOCKOUT"))
+	if err != nil {
+		pinLockout = 30 * time.Minute
+	}
+	loginFreeAttempts, err := strconv.Atoi(os.Getenv("LOGIN_FREE_ATTEMPTS"))
+	if err != nil {
+		loginFreeAttempts = 3
+	}
+	loginMaxAttempts, err := strconv.Atoi(os.Getenv("LOGIN_MAX_ATTEMPTS"))
+	if err != nil {
+		loginMaxAttempts = 10
+	}
+	loginIPFreeAttempts, err := strconv.Atoi(os.Getenv("LOGIN_IP_FREE_ATTEMPTS"))
+	if err != nil {
+		loginIPFreeAttempts = 20
+	}
+	loginIPMaxAttempts, err := strconv.Atoi(os.Getenv("LOGIN_IP_MAX_ATTEMPTS"))
+	if err != nil {
+		loginIPMaxAttempts = 100
+	}
+	loginBackoffBase, err := time.ParseDuration(os.Getenv("LOGIN_BACKOFF_BASE"))
+	if err != nil {
+		loginBackoffBase = time.Second
+	}
+	loginLockout, err := time.ParseDuration(os.Getenv("LOGIN_LOCKOUT"))
+	if err != nil {
+		loginLockout = 15 * time.Minute
+	}
+	trustedProxies, err := parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to parse trusted proxies")
+		return err
+	}
+	// stored TOTP secrets are only as safe as this key, an empty one would leave them readable
+	mfaSecretKey, err := requireEnv("MFA_SECRET_KEY")
+	if err != nil {
+		logger.Error().Err(err).Msg("mfa secret key is missing")
+		return err
+	}
+	mfaIssuer := os.Getenv("MFA_ISSUER")
+	if mfaIssuer == "" {
+		mfaIssuer = "OpenIdea Bank"
+	}
+	publicURL := os.Getenv("PUBLIC_URL")
+	if publicURL == "" {
+		publicURL = "http://localhost:" + APP_PORT
+	}
 	// service registry
 	service := service.New(
-		service.Config{Salt: salt, JwtSecret: os.Getenv("JWT_SECRET")},
+		service.Config{
+			Salt:            salt,
+			JwtKeys:         jwtKeys,
+			AccessTokenTTL:  accessTokenTTL,
+			RefreshTokenTTL: refreshTokenTTL,
+			MfaIssuer:       mfaIssuer,
+			MfaSecretKey:    mfaSecretKey,
+			PinMaxAttempts:  pinMaxAttempts,
+			PinLockout:      pinLockout,
+			LoginThrottle: entity.LoginThrottle{
+				FreeAttempts: loginFreeAttempts,
+				MaxAttempts:  loginMaxAttempts,
+				BackoffBase:  loginBackoffBase,
+				Lockout:      loginLockout,
+			},
+			LoginIPThrottle: entity.LoginThrottle{
+				FreeAttempts: loginIPFreeAttempts,
+				MaxAttempts:  loginIPMaxAttempts,
+				BackoffBase:  loginBackoffBase,
+				Lockout:      loginLockout,
+			},
+			IdempotencyRetention:       idempotencyRetention,
+			FxQuoteTTL:                 fxQuoteTTL,
+			HoldTTL:                    holdTTL,
+			TransferMaxAttempts:        transferMaxAttempts,
+			TransferReturnWindow:       transferReturnWindow,
+			StandingOrderMaxAttempts:   standingOrderMaxAttempts,
+			BeneficiaryCoolingOff:      beneficiaryCoolingOff,
+			BeneficiaryCoolingOffLimit: beneficiaryCoolingOffLimit,
+			Banks:                      banks,
+			TransferLimits:             transferLimits,
+			ReceiptSecret:              receiptSecret,
+			PublicURL:                  publicURL,
+		},
 		logger,
 		userRepo,
 		s3Repo,
 		balanceRepo,
+		idempotencyRepo,
+		tokenRepo,
+		fxRepo,
+		bankGateway,
 	)
 
+	if path := os.Getenv("FX_RATES_FILE"); path != "" {
+		if err := loadFxRates(service, path); err != nil {
+			logger.Error().Err(err).Str("path", path).Msg("failed to load fx rates")
+			return err
+		}
+	}
+
+	// background workers
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go worker.Run(ctx, logger, "idempotency-purge", time.Hour, service.PurgeExpiredIdempotencyKeys)
+	go worker.Run(ctx, logger, "token-purge", time.Hour, service.PurgeExpiredTokens)
+	go worker.Run(ctx, logger, "login-throttle-purge", time.Hour, service.PurgeStaleLoginThrottles)
+	go worker.Run(ctx, logger, "jwt-key-reload", time.Minute, func(ctx context.Context) error { return jwtKeys.Reload() })
+	go worker.Run(ctx, logger, "hold-expiry", time.Minute, service.ExpireHolds)
+	go worker.Run(ctx, logger, "outbound-transactions", 2*time.Second, service.ProcessOutboundTransactions)
+	go worker.Run(ctx, logger, "balance-snapshots", time.Hour, service.SnapshotBalances)
+	go worker.Run(ctx, logger, "standing-orders", 30*time.Second, service.RunStandingOrders)
+
 	// middleware init
 	md := mw.New(logger, service)
 
 	// restapi init
-	rest := restapi.New(logger, md, service)
+	rest := restapi.New(logger, md, service, trustedProxies)
 
 	router := mux.NewRouter()
 
@@ -84,3 +279,144 @@ func Server() error {
 
 	return <-errs
 }
+
+// loadFxRates seeds the fx rate table from a JSON file shaped like the admin rates payload
+func loadFxRates(s service.Service, path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var payload request.UpsertFxRates
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return err
+	}
+
+	_, err = s.UpsertFxRates(context.Background(), payload)
+	return err
+}
+
+// defaultTransferLimits apply when TRANSFER_LIMITS is not set, amounts are in major units like in the api
+const defaultTransferLimits = `{
+	"IDR": {"perTransaction": "25000000", "daily": "50000000", "monthly": "200000000"},
+	"USD": {"perTransaction": "5000", "daily": "10000", "monthly": "50000"}
+}`
+
+// requireEnv returns the value of the environment variable name, which must be set and not empty
+func requireEnv(name string) (string, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return "", fmt.Errorf("%s is not set", name)
+	}
+	return value, nil
+}
+
+// parseTransferLimits reads the default transfer limits from a JSON object keyed by currency
+func parseTransferLimits(raw string) (map[string]entity.TransferLimit, error) {
+	if raw == "" {
+		raw = defaultTransferLimits
+	}
+
+	var payload map[string]request.TransferLimit
+	if err := json.Unmarshal([]byte(raw), &payload); err != nil {
+		return nil, err
+	}
+
+	limits := make(map[string]entity.TransferLimit, len(payload))
+	for currency, v := range payload {
+		v.Currency = strings.ToUpper(currency)
+		if err := validator.ValidateStruct(&v); err != nil {
+			return nil, err
+		}
+		limit := entity.TransferLimit{Currency: v.Currency}
+		for _, amount := range []struct {
+			value request.Amount
+			dst   *int64
+		}{
+			{v.PerTransaction, &limit.PerTransaction},
+			{v.Daily, &limit.Daily},
+			{v.Monthly, &limit.Monthly},
+		} {
+			if amount.value == "" {
+				continue
+			}
+			m, err := money.Parse(string(amount.value), v.Currency)
+			if err != nil || m.Amount < 0 {
+				return nil, fmt.Errorf("%s: invalid amount %q", v.Currency, amount.value)
+			}
+			*amount.dst = m.Amount
+		}
+		limits[v.Currency] = limit
+	}
+
+	return limits, nil
+}
+
+// parseTrustedProxies reads a comma separated list of proxy ips or cidrs
+func parseTrustedProxies(raw string) ([]*net.IPNet, error) {
+	var proxies []*net.IPNet
+	for _, v := range strings.Split(raw, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if !strings.Contains(v, "/") {
+			if ip := net.ParseIP(v); ip != nil && ip.To4() != nil {
+				v += "/32"
+			} else {
+				v += "/128"
+			}
+		}
+		_, cidr, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, err
+		}
+		proxies = append(proxies, cidr)
+	}
+
+	return proxies, nil
+}
+
+// loadBankDirectory reads the bank directory from path, which replaces the embedded one when set
+func loadBankDirectory(path string) (*bank.Directory, error) {
+	if path == "" {
+		return bank.Default()
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return bank.Load(f)
+}
+
+// loadJwtKeys reads the token signing keys from dir, picking up rotated keys is left to a worker.
+// A directory is required unless ephemeral is set, then tokens are signed with a key that is gone after a restart.
+func loadJwtKeys(logger zerolog.Logger, dir string, grace time.Duration, ephemeral bool) (*jwt.KeySet, error) {
+	if dir == "" {
+		if !ephemeral {
+			return nil, fmt.Errorf("JWT_KEYS_DIR is not set, set JWT_EPHEMERAL_KEYS=true to sign with a throwaway key in development")
+		}
+		logger.Warn().Msg("JWT_KEYS_DIR is not set, signing access tokens with an ephemeral key")
+		return jwt.NewEphemeralKeySet()
+	}
+
+	return jwt.LoadKeySet(dir, grace)
+}
+
+// simulatedBankConfig reads the simulated bank behaviour from the environment
+func simulatedBankConfig() repository.SimulatedBankConfig {
+	cfg := repository.SimulatedBankConfig{Latency: 5 * time.Second}
+	if latency, err := time.ParseDuration(os.Getenv("BANK_SIM_LATENCY")); err == nil {
+		cfg.Latency = latency
+	}
+	if rate, err := strconv.ParseFloat(os.Getenv("BANK_SIM_FAILURE_RATE"), 64); err == nil {
+		cfg.FailureRate = rate
+	}
+	if rate, err := strconv.ParseFloat(os.Getenv("BANK_SIM_RETURN_RATE"), 64); err == nil {
+		cfg.ReturnRate = rate
+	}
+	return cfg
+}
@@ -0,0 +1,5 @@
+DROP TRIGGER trg_ledger_postings_balanced ON LEDGER_POSTINGS;
+DROP FUNCTION ledger_check_journal_balanced;
+DROP TABLE LEDGER_POSTINGS;
+DROP TABLE LEDGER_JOURNALS;
+DROP TABLE LEDGER_ACCOUNTS;
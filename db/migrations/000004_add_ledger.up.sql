@@ -0,0 +1,84 @@
+CREATE TABLE LEDGER_ACCOUNTS (
+    ID VARCHAR(36) PRIMARY KEY,
+    CODE VARCHAR(100) NOT NULL,
+    USER_ID VARCHAR(36) NULL,
+    CURRENCY VARCHAR(10) NOT NULL,
+    TYPE VARCHAR(20) NOT NULL,
+    CREATED_AT BIGINT NOT NULL,
+    CONSTRAINT fk_ledger_accounts_user FOREIGN KEY(USER_ID) REFERENCES USERS(ID),
+    CONSTRAINT uq_ledger_accounts_code_currency UNIQUE (CODE, CURRENCY)
+);
+
+CREATE TABLE LEDGER_JOURNALS (
+    ID VARCHAR(36) PRIMARY KEY,
+    TRANSACTION_ID VARCHAR(36) NULL,
+    REFERENCE_ID VARCHAR(36) NULL,
+    DESCRIPTION VARCHAR(255) NOT NULL,
+    CREATED_AT BIGINT NOT NULL
+);
+
+CREATE TABLE LEDGER_POSTINGS (
+    ID VARCHAR(36) PRIMARY KEY,
+    JOURNAL_ID VARCHAR(36) NOT NULL,
+    ACCOUNT_ID VARCHAR(36) NOT NULL,
+    DIRECTION VARCHAR(6) NOT NULL,
+    AMOUNT BIGINT NOT NULL,
+    CURRENCY VARCHAR(10) NOT NULL,
+    CREATED_AT BIGINT NOT NULL,
+    CONSTRAINT fk_ledger_postings_journal FOREIGN KEY(JOURNAL_ID) REFERENCES LEDGER_JOURNALS(ID),
+    CONSTRAINT fk_ledger_postings_account FOREIGN KEY(ACCOUNT_ID) REFERENCES LEDGER_ACCOUNTS(ID),
+    CONSTRAINT ck_ledger_postings_direction CHECK (DIRECTION IN ('debit', 'credit')),
+    CONSTRAINT ck_ledger_postings_amount CHECK (AMOUNT > 0)
+);
+
+CREATE INDEX idx_ledger_postings_journal ON LEDGER_POSTINGS(JOURNAL_ID);
+CREATE INDEX idx_ledger_postings_account ON LEDGER_POSTINGS(ACCOUNT_ID);
+
+-- every journal must net to zero per currency once its transaction commits
+CREATE FUNCTION ledger_check_journal_balanced() RETURNS TRIGGER AS $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM LEDGER_POSTINGS
+        WHERE JOURNAL_ID = NEW.JOURNAL_ID
+        GROUP BY CURRENCY
+        HAVING SUM(CASE DIRECTION WHEN 'debit' THEN AMOUNT ELSE -AMOUNT END) <> 0
+    ) THEN
+        RAISE EXCEPTION 'ledger journal % is not balanced', NEW.JOURNAL_ID;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER trg_ledger_postings_balanced
+    AFTER INSERT ON LEDGER_POSTINGS
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION ledger_check_journal_balanced();
+
+-- open a wallet account for every existing balance and book it against the opening balance account
+INSERT INTO LEDGER_ACCOUNTS (ID, CODE, USER_ID, CURRENCY, TYPE, CREATED_AT)
+SELECT gen_random_uuid()::VARCHAR, 'wallet:' || USER_ID, USER_ID, CURRENCY, 'liability', (EXTRACT(EPOCH FROM NOW()) * 1000)::BIGINT
+FROM BALANCES;
+
+INSERT INTO LEDGER_ACCOUNTS (ID, CODE, USER_ID, CURRENCY, TYPE, CREATED_AT)
+SELECT DISTINCT ON (CURRENCY) gen_random_uuid()::VARCHAR, 'system:opening_balance', NULL, CURRENCY, 'equity', (EXTRACT(EPOCH FROM NOW()) * 1000)::BIGINT
+FROM BALANCES;
+
+CREATE TEMPORARY TABLE LEDGER_OPENING AS
+SELECT gen_random_uuid()::VARCHAR AS JOURNAL_ID, b.ID AS BALANCE_ID, b.BALANCE, b.CURRENCY, w.ID AS WALLET_ID, o.ID AS OPENING_ID
+FROM BALANCES b
+JOIN LEDGER_ACCOUNTS w ON w.CODE = 'wallet:' || b.USER_ID AND w.CURRENCY = b.CURRENCY
+JOIN LEDGER_ACCOUNTS o ON o.CODE = 'system:opening_balance' AND o.CURRENCY = b.CURRENCY
+WHERE b.BALANCE > 0;
+
+INSERT INTO LEDGER_JOURNALS (ID, TRANSACTION_ID, REFERENCE_ID, DESCRIPTION, CREATED_AT)
+SELECT JOURNAL_ID, NULL, BALANCE_ID, 'opening balance', (EXTRACT(EPOCH FROM NOW()) * 1000)::BIGINT
+FROM LEDGER_OPENING;
+
+INSERT INTO LEDGER_POSTINGS (ID, JOURNAL_ID, ACCOUNT_ID, DIRECTION, AMOUNT, CURRENCY, CREATED_AT)
+SELECT gen_random_uuid()::VARCHAR, JOURNAL_ID, OPENING_ID, 'debit', BALANCE, CURRENCY, (EXTRACT(EPOCH FROM NOW()) * 1000)::BIGINT
+FROM LEDGER_OPENING
+UNION ALL
+SELECT gen_random_uuid()::VARCHAR, JOURNAL_ID, WALLET_ID, 'credit', BALANCE, CURRENCY, (EXTRACT(EPOCH FROM NOW()) * 1000)::BIGINT
+FROM LEDGER_OPENING;
+
+DROP TABLE LEDGER_OPENING;
@@ -0,0 +1 @@
+DROP TABLE IDEMPOTENCY_KEYS;
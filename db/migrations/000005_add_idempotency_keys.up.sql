@@ -0,0 +1,13 @@
+CREATE TABLE IDEMPOTENCY_KEYS (
+    USER_ID VARCHAR(36) NOT NULL,
+    IDEMPOTENCY_KEY VARCHAR(255) NOT NULL,
+    REQUEST_HASH VARCHAR(64) NOT NULL,
+    STATUS_CODE INT NULL,
+    RESPONSE_BODY TEXT NULL,
+    CREATED_AT BIGINT NOT NULL,
+    EXPIRES_AT BIGINT NOT NULL,
+    CONSTRAINT pk_idempotency_keys PRIMARY KEY (USER_ID, IDEMPOTENCY_KEY),
+    CONSTRAINT fk_idempotency_keys_user FOREIGN KEY(USER_ID) REFERENCES USERS(ID)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON IDEMPOTENCY_KEYS(EXPIRES_AT);
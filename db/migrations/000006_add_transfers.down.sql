@@ -0,0 +1,7 @@
+DROP INDEX idx_balances_history_transaction_id;
+ALTER TABLE BALANCES_HISTORY DROP CONSTRAINT fk_balances_history_counterparty;
+ALTER TABLE BALANCES_HISTORY DROP COLUMN COUNTERPARTY_USER_ID;
+ALTER TABLE BALANCES_HISTORY DROP COLUMN TYPE;
+
+ALTER TABLE USERS DROP CONSTRAINT uq_users_phone;
+ALTER TABLE USERS DROP COLUMN PHONE;
@@ -0,0 +1,9 @@
+ALTER TABLE USERS ADD COLUMN PHONE VARCHAR(20) NULL;
+ALTER TABLE USERS ADD CONSTRAINT uq_users_phone UNIQUE (PHONE);
+
+ALTER TABLE BALANCES_HISTORY ADD COLUMN TYPE VARCHAR(20) NOT NULL DEFAULT 'topup';
+ALTER TABLE BALANCES_HISTORY ADD COLUMN COUNTERPARTY_USER_ID VARCHAR(36) NULL;
+ALTER TABLE BALANCES_HISTORY ADD CONSTRAINT fk_balances_history_counterparty FOREIGN KEY(COUNTERPARTY_USER_ID) REFERENCES USERS(ID);
+UPDATE BALANCES_HISTORY SET TYPE = 'transaction' WHERE BALANCE < 0;
+
+CREATE INDEX idx_balances_history_transaction_id ON BALANCES_HISTORY(TRANSACTION_ID);
@@ -0,0 +1,4 @@
+ALTER TABLE BALANCES_HISTORY DROP COLUMN FX_RATE;
+DROP TABLE FX_QUOTES;
+DROP TABLE FX_RATES;
+ALTER TABLE USERS DROP COLUMN ROLE;
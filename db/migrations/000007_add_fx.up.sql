@@ -0,0 +1,27 @@
+ALTER TABLE USERS ADD COLUMN ROLE VARCHAR(10) NOT NULL DEFAULT 'user';
+
+CREATE TABLE FX_RATES (
+    BASE_CURRENCY VARCHAR(10) NOT NULL,
+    QUOTE_CURRENCY VARCHAR(10) NOT NULL,
+    RATE NUMERIC(30, 12) NOT NULL,
+    UPDATED_AT BIGINT NOT NULL,
+    CONSTRAINT pk_fx_rates PRIMARY KEY (BASE_CURRENCY, QUOTE_CURRENCY),
+    CONSTRAINT ck_fx_rates_rate CHECK (RATE > 0)
+);
+
+CREATE TABLE FX_QUOTES (
+    ID VARCHAR(36) PRIMARY KEY,
+    USER_ID VARCHAR(36) NOT NULL,
+    FROM_CURRENCY VARCHAR(10) NOT NULL,
+    TO_CURRENCY VARCHAR(10) NOT NULL,
+    FROM_AMOUNT BIGINT NOT NULL,
+    TO_AMOUNT BIGINT NOT NULL,
+    RATE NUMERIC(30, 12) NOT NULL,
+    CREATED_AT BIGINT NOT NULL,
+    EXPIRES_AT BIGINT NOT NULL,
+    EXECUTED_AT BIGINT NULL,
+    TRANSACTION_ID VARCHAR(36) NULL,
+    CONSTRAINT fk_fx_quotes_user FOREIGN KEY(USER_ID) REFERENCES USERS(ID)
+);
+
+ALTER TABLE BALANCES_HISTORY ADD COLUMN FX_RATE NUMERIC(30, 12) NULL;
@@ -0,0 +1,32 @@
+-- back to whole units, refused while any amount has a part below one major unit that would be lost
+CREATE FUNCTION money_minor_unit_scale(currency VARCHAR) RETURNS BIGINT AS $$
+    SELECT CASE
+        WHEN UPPER(currency) IN ('BIF', 'CLP', 'DJF', 'GNF', 'ISK', 'JPY', 'KMF', 'KRW', 'PYG', 'RWF', 'UGX', 'UYI', 'VND', 'VUV',
+            'XAF', 'XOF', 'XPF', 'XAG', 'XAU', 'XBA', 'XBB', 'XBC', 'XBD', 'XDR', 'XPD', 'XPT', 'XSU', 'XTS', 'XUA', 'XXX') THEN 1
+        WHEN UPPER(currency) IN ('BHD', 'IQD', 'JOD', 'KWD', 'LYD', 'OMR', 'TND') THEN 1000
+        WHEN UPPER(currency) IN ('CLF', 'UYW') THEN 10000
+        ELSE 100
+    END
+$$ LANGUAGE SQL IMMUTABLE;
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM BALANCES WHERE BALANCE % money_minor_unit_scale(CURRENCY) <> 0)
+        OR EXISTS (SELECT 1 FROM BALANCES_HISTORY WHERE BALANCE % money_minor_unit_scale(CURRENCY) <> 0)
+        OR EXISTS (SELECT 1 FROM LEDGER_POSTINGS WHERE AMOUNT % money_minor_unit_scale(CURRENCY) <> 0)
+        OR EXISTS (SELECT 1 FROM FX_QUOTES WHERE FROM_AMOUNT % money_minor_unit_scale(FROM_CURRENCY) <> 0 OR TO_AMOUNT % money_minor_unit_scale(TO_CURRENCY) <> 0)
+    THEN
+        RAISE EXCEPTION 'amounts with minor units cannot be converted back to whole units';
+    END IF;
+END;
+$$;
+
+UPDATE FX_QUOTES SET FROM_AMOUNT = FROM_AMOUNT / money_minor_unit_scale(FROM_CURRENCY), TO_AMOUNT = TO_AMOUNT / money_minor_unit_scale(TO_CURRENCY);
+UPDATE LEDGER_POSTINGS SET AMOUNT = AMOUNT / money_minor_unit_scale(CURRENCY);
+UPDATE BALANCES_HISTORY SET BALANCE = BALANCE / money_minor_unit_scale(CURRENCY);
+UPDATE BALANCES SET BALANCE = BALANCE / money_minor_unit_scale(CURRENCY);
+
+DROP FUNCTION money_minor_unit_scale(VARCHAR);
+
+ALTER TABLE BALANCES_HISTORY ALTER COLUMN BALANCE TYPE INT;
+ALTER TABLE BALANCES ALTER COLUMN BALANCE TYPE INT;
@@ -0,0 +1,21 @@
+ALTER TABLE BALANCES ALTER COLUMN BALANCE TYPE BIGINT;
+ALTER TABLE BALANCES_HISTORY ALTER COLUMN BALANCE TYPE BIGINT;
+
+-- amounts so far were whole units of their currency, from here on they are minor units (ISO 4217 exponent)
+CREATE FUNCTION money_minor_unit_scale(currency VARCHAR) RETURNS BIGINT AS $$
+    SELECT CASE
+        WHEN UPPER(currency) IN ('BIF', 'CLP', 'DJF', 'GNF', 'ISK', 'JPY', 'KMF', 'KRW', 'PYG', 'RWF', 'UGX', 'UYI', 'VND', 'VUV',
+            'XAF', 'XOF', 'XPF', 'XAG', 'XAU', 'XBA', 'XBB', 'XBC', 'XBD', 'XDR', 'XPD', 'XPT', 'XSU', 'XTS', 'XUA', 'XXX') THEN 1
+        WHEN UPPER(currency) IN ('BHD', 'IQD', 'JOD', 'KWD', 'LYD', 'OMR', 'TND') THEN 1000
+        WHEN UPPER(currency) IN ('CLF', 'UYW') THEN 10000
+        ELSE 100
+    END
+$$ LANGUAGE SQL IMMUTABLE;
+
+UPDATE BALANCES SET BALANCE = BALANCE * money_minor_unit_scale(CURRENCY);
+UPDATE BALANCES_HISTORY SET BALANCE = BALANCE * money_minor_unit_scale(CURRENCY);
+-- every posting of a journal is scaled by the same factor per currency, so journals stay balanced
+UPDATE LEDGER_POSTINGS SET AMOUNT = AMOUNT * money_minor_unit_scale(CURRENCY);
+UPDATE FX_QUOTES SET FROM_AMOUNT = FROM_AMOUNT * money_minor_unit_scale(FROM_CURRENCY), TO_AMOUNT = TO_AMOUNT * money_minor_unit_scale(TO_CURRENCY);
+
+DROP FUNCTION money_minor_unit_scale(VARCHAR);
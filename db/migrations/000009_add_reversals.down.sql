@@ -0,0 +1,3 @@
+DROP TABLE AUDIT_LOGS;
+ALTER TABLE BALANCES_HISTORY DROP COLUMN REVERSAL_OF;
+ALTER TABLE BALANCES_HISTORY DROP COLUMN REVERSED_AMOUNT;
@@ -0,0 +1,16 @@
+ALTER TABLE BALANCES_HISTORY ADD COLUMN REVERSED_AMOUNT BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE BALANCES_HISTORY ADD COLUMN REVERSAL_OF VARCHAR(36) NULL;
+
+CREATE TABLE AUDIT_LOGS (
+    ID VARCHAR(36) PRIMARY KEY,
+    ACTOR_ID VARCHAR(36) NOT NULL,
+    ACTION VARCHAR(50) NOT NULL,
+    TARGET_TYPE VARCHAR(50) NOT NULL,
+    TARGET_ID VARCHAR(36) NOT NULL,
+    REASON VARCHAR(255) NOT NULL,
+    METADATA TEXT NOT NULL DEFAULT '{}',
+    CREATED_AT BIGINT NOT NULL,
+    CONSTRAINT fk_audit_logs_actor FOREIGN KEY(ACTOR_ID) REFERENCES USERS(ID)
+);
+
+CREATE INDEX idx_audit_logs_target ON AUDIT_LOGS(TARGET_TYPE, TARGET_ID);
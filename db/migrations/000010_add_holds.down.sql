@@ -0,0 +1,3 @@
+DROP TABLE HOLDS;
+ALTER TABLE BALANCES DROP CONSTRAINT ck_balances_held;
+ALTER TABLE BALANCES DROP COLUMN HELD;
@@ -0,0 +1,22 @@
+ALTER TABLE BALANCES ADD COLUMN HELD BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE BALANCES ADD CONSTRAINT ck_balances_held CHECK (HELD >= 0 AND HELD <= BALANCE);
+
+CREATE TABLE HOLDS (
+    ID VARCHAR(36) PRIMARY KEY,
+    USER_ID VARCHAR(36) NOT NULL,
+    BALANCE_ID VARCHAR(36) NOT NULL,
+    AMOUNT BIGINT NOT NULL,
+    CURRENCY VARCHAR(10) NOT NULL,
+    STATUS VARCHAR(20) NOT NULL,
+    DESCRIPTION VARCHAR(255) NOT NULL DEFAULT '',
+    TRANSACTION_ID VARCHAR(36) NULL,
+    EXPIRES_AT BIGINT NOT NULL,
+    CREATED_AT BIGINT NOT NULL,
+    UPDATED_AT BIGINT NOT NULL,
+    CONSTRAINT fk_holds_user FOREIGN KEY(USER_ID) REFERENCES USERS(ID),
+    CONSTRAINT fk_holds_balance FOREIGN KEY(BALANCE_ID) REFERENCES BALANCES(ID),
+    CONSTRAINT ck_holds_amount CHECK (AMOUNT > 0)
+);
+
+CREATE INDEX idx_holds_user ON HOLDS(USER_ID);
+CREATE INDEX idx_holds_status_expires_at ON HOLDS(STATUS, EXPIRES_AT);
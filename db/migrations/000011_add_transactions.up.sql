@@ -0,0 +1,26 @@
+CREATE TABLE TRANSACTIONS (
+    ID VARCHAR(36) PRIMARY KEY,
+    USER_ID VARCHAR(36) NOT NULL,
+    AMOUNT BIGINT NOT NULL,
+    CURRENCY VARCHAR(10) NOT NULL,
+    BANK_ACCOUNT_NUMBER VARCHAR(30) NOT NULL,
+    BANK_NAME VARCHAR(30) NOT NULL,
+    STATUS VARCHAR(20) NOT NULL,
+    GATEWAY_REFERENCE VARCHAR(100) NULL,
+    FAILURE_REASON VARCHAR(255) NOT NULL DEFAULT '',
+    ATTEMPTS INT NOT NULL DEFAULT 0,
+    NEXT_ATTEMPT_AT BIGINT NOT NULL,
+    CREATED_AT BIGINT NOT NULL,
+    UPDATED_AT BIGINT NOT NULL,
+    CONSTRAINT fk_transactions_user FOREIGN KEY(USER_ID) REFERENCES USERS(ID),
+    CONSTRAINT ck_transactions_status CHECK (STATUS IN ('pending', 'processing', 'completed', 'failed', 'returned'))
+);
+
+CREATE INDEX idx_transactions_user ON TRANSACTIONS(USER_ID);
+CREATE INDEX idx_transactions_status_next_attempt_at ON TRANSACTIONS(STATUS, NEXT_ATTEMPT_AT);
+
+-- transactions made before the lifecycle existed were fire and forget, treat them as settled
+INSERT INTO TRANSACTIONS (ID, USER_ID, AMOUNT, CURRENCY, BANK_ACCOUNT_NUMBER, BANK_NAME, STATUS, NEXT_ATTEMPT_AT, CREATED_AT, UPDATED_AT)
+SELECT TRANSACTION_ID, USER_ID, -BALANCE, CURRENCY, SOURCE_BANK_ACCOUNT_NUMBER, SOURCE_BANK_NAME, 'completed', CREATED_AT, CREATED_AT, CREATED_AT
+FROM BALANCES_HISTORY
+WHERE TYPE = 'transaction' AND TRANSACTION_ID IS NOT NULL AND TRANSACTION_ID <> '';
@@ -0,0 +1,19 @@
+CREATE TABLE TOPUPS (
+    ID VARCHAR(36) PRIMARY KEY,
+    USER_ID VARCHAR(36) NOT NULL,
+    AMOUNT BIGINT NOT NULL,
+    CURRENCY VARCHAR(10) NOT NULL,
+    PROOF_IMAGE_URL VARCHAR(255) NOT NULL,
+    SENDER_BANK_ACCOUNT_NUMBER VARCHAR(30) NOT NULL,
+    SENDER_BANK_NAME VARCHAR(30) NOT NULL,
+    STATUS VARCHAR(20) NOT NULL,
+    REVIEWER_ID VARCHAR(36) NULL,
+    REJECTION_REASON VARCHAR(255) NOT NULL DEFAULT '',
+    REVIEWED_AT BIGINT NULL,
+    CREATED_AT BIGINT NOT NULL,
+    CONSTRAINT fk_topups_user FOREIGN KEY(USER_ID) REFERENCES USERS(ID),
+    CONSTRAINT fk_topups_reviewer FOREIGN KEY(REVIEWER_ID) REFERENCES USERS(ID),
+    CONSTRAINT ck_topups_status CHECK (STATUS IN ('pending_review', 'approved', 'rejected'))
+);
+
+CREATE INDEX idx_topups_status_created_at ON TOPUPS(STATUS, CREATED_AT);
@@ -0,0 +1 @@
+DROP INDEX idx_balances_history_user_created_at_id;
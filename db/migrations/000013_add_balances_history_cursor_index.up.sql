@@ -0,0 +1 @@
+CREATE INDEX idx_balances_history_user_created_at_id ON BALANCES_HISTORY(USER_ID, CREATED_AT DESC, ID DESC);
@@ -0,0 +1,2 @@
+ALTER TABLE BALANCES_HISTORY DROP COLUMN BALANCE_AFTER;
+ALTER TABLE BALANCES_HISTORY DROP COLUMN BALANCE_BEFORE;
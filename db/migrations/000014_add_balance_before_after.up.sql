@@ -0,0 +1,3 @@
+-- left NULL for existing rows, the backfill command reconstructs them
+ALTER TABLE BALANCES_HISTORY ADD COLUMN BALANCE_BEFORE BIGINT NULL;
+ALTER TABLE BALANCES_HISTORY ADD COLUMN BALANCE_AFTER BIGINT NULL;
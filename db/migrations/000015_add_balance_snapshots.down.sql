@@ -0,0 +1,2 @@
+DROP INDEX idx_balances_history_created_at;
+DROP TABLE BALANCE_SNAPSHOTS;
@@ -0,0 +1,12 @@
+-- BALANCE is the sum of every history entry created before SNAPSHOT_AT, a UTC midnight
+CREATE TABLE BALANCE_SNAPSHOTS (
+    USER_ID VARCHAR(36) NOT NULL,
+    CURRENCY VARCHAR(10) NOT NULL,
+    SNAPSHOT_AT BIGINT NOT NULL,
+    BALANCE BIGINT NOT NULL,
+    PRIMARY KEY (USER_ID, CURRENCY, SNAPSHOT_AT),
+    CONSTRAINT fk_balance_snapshots_user FOREIGN KEY(USER_ID) REFERENCES USERS(ID)
+);
+
+CREATE INDEX idx_balance_snapshots_snapshot_at ON BALANCE_SNAPSHOTS(SNAPSHOT_AT);
+CREATE INDEX idx_balances_history_created_at ON BALANCES_HISTORY(CREATED_AT);
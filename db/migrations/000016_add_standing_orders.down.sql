@@ -0,0 +1,3 @@
+ALTER TABLE BALANCES_HISTORY DROP COLUMN STANDING_ORDER_ID;
+ALTER TABLE TRANSACTIONS DROP COLUMN STANDING_ORDER_ID;
+DROP TABLE STANDING_ORDERS;
@@ -0,0 +1,35 @@
+CREATE TABLE STANDING_ORDERS (
+    ID VARCHAR(36) PRIMARY KEY,
+    USER_ID VARCHAR(36) NOT NULL,
+    DESCRIPTION VARCHAR(255) NOT NULL DEFAULT '',
+    SCHEDULE_TYPE VARCHAR(10) NOT NULL,
+    CRON_EXPRESSION VARCHAR(100) NOT NULL DEFAULT '',
+    TIMEZONE VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    START_AT BIGINT NOT NULL,
+    AMOUNT BIGINT NOT NULL,
+    CURRENCY VARCHAR(10) NOT NULL,
+    BANK_ACCOUNT_NUMBER VARCHAR(30) NOT NULL,
+    BANK_NAME VARCHAR(30) NOT NULL,
+    STATUS VARCHAR(20) NOT NULL,
+    -- NEXT_RUN_AT is the occurrence due, ATTEMPTS counts failed tries of it
+    NEXT_RUN_AT BIGINT NULL,
+    ATTEMPTS INT NOT NULL DEFAULT 0,
+    LAST_RUN_AT BIGINT NULL,
+    LAST_TRANSACTION_ID VARCHAR(36) NULL,
+    LAST_ERROR VARCHAR(255) NOT NULL DEFAULT '',
+    LAST_FAILED_AT BIGINT NULL,
+    CREATED_AT BIGINT NOT NULL,
+    UPDATED_AT BIGINT NOT NULL,
+    CONSTRAINT fk_standing_orders_user FOREIGN KEY(USER_ID) REFERENCES USERS(ID),
+    CONSTRAINT ck_standing_orders_schedule_type CHECK (SCHEDULE_TYPE IN ('once', 'daily', 'weekly', 'monthly', 'cron')),
+    CONSTRAINT ck_standing_orders_status CHECK (STATUS IN ('active', 'paused', 'completed', 'failed', 'cancelled')),
+    CONSTRAINT ck_standing_orders_amount CHECK (AMOUNT > 0)
+);
+
+CREATE INDEX idx_standing_orders_user ON STANDING_ORDERS(USER_ID);
+CREATE INDEX idx_standing_orders_status_next_run_at ON STANDING_ORDERS(STATUS, NEXT_RUN_AT);
+
+ALTER TABLE TRANSACTIONS ADD COLUMN STANDING_ORDER_ID VARCHAR(36) NULL;
+ALTER TABLE TRANSACTIONS ADD CONSTRAINT fk_transactions_standing_order FOREIGN KEY(STANDING_ORDER_ID) REFERENCES STANDING_ORDERS(ID);
+ALTER TABLE BALANCES_HISTORY ADD COLUMN STANDING_ORDER_ID VARCHAR(36) NULL;
+ALTER TABLE BALANCES_HISTORY ADD CONSTRAINT fk_balances_history_standing_order FOREIGN KEY(STANDING_ORDER_ID) REFERENCES STANDING_ORDERS(ID);
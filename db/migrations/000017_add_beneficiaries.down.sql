@@ -0,0 +1,3 @@
+ALTER TABLE STANDING_ORDERS DROP COLUMN BENEFICIARY_ID;
+ALTER TABLE TRANSACTIONS DROP COLUMN BENEFICIARY_ID;
+DROP TABLE BENEFICIARIES;
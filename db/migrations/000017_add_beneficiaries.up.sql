@@ -0,0 +1,19 @@
+CREATE TABLE BENEFICIARIES (
+    ID VARCHAR(36) PRIMARY KEY,
+    USER_ID VARCHAR(36) NOT NULL,
+    NICKNAME VARCHAR(50) NOT NULL DEFAULT '',
+    BANK_ACCOUNT_NUMBER VARCHAR(30) NOT NULL,
+    BANK_NAME VARCHAR(30) NOT NULL,
+    IS_FAVORITE BOOLEAN NOT NULL DEFAULT FALSE,
+    CREATED_AT BIGINT NOT NULL,
+    UPDATED_AT BIGINT NOT NULL,
+    CONSTRAINT fk_beneficiaries_user FOREIGN KEY(USER_ID) REFERENCES USERS(ID),
+    CONSTRAINT uq_beneficiaries_account UNIQUE (USER_ID, BANK_NAME, BANK_ACCOUNT_NUMBER)
+);
+
+-- a deleted beneficiary leaves its transactions and standing orders with the account details they copied
+ALTER TABLE TRANSACTIONS ADD COLUMN BENEFICIARY_ID VARCHAR(36) NULL;
+ALTER TABLE TRANSACTIONS ADD CONSTRAINT fk_transactions_beneficiary FOREIGN KEY(BENEFICIARY_ID) REFERENCES BENEFICIARIES(ID) ON DELETE SET NULL;
+CREATE INDEX idx_transactions_beneficiary ON TRANSACTIONS(BENEFICIARY_ID);
+ALTER TABLE STANDING_ORDERS ADD COLUMN BENEFICIARY_ID VARCHAR(36) NULL;
+ALTER TABLE STANDING_ORDERS ADD CONSTRAINT fk_standing_orders_beneficiary FOREIGN KEY(BENEFICIARY_ID) REFERENCES BENEFICIARIES(ID) ON DELETE SET NULL;
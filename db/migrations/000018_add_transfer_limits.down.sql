@@ -0,0 +1,2 @@
+DROP INDEX idx_transactions_user_currency_created_at;
+DROP TABLE TRANSFER_LIMITS;
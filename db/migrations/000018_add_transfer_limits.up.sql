@@ -0,0 +1,14 @@
+-- per user overrides of the configured default limits, zero leaves a cap off
+CREATE TABLE TRANSFER_LIMITS (
+    USER_ID VARCHAR(36) NOT NULL,
+    CURRENCY VARCHAR(10) NOT NULL,
+    PER_TRANSACTION BIGINT NOT NULL DEFAULT 0,
+    DAILY BIGINT NOT NULL DEFAULT 0,
+    MONTHLY BIGINT NOT NULL DEFAULT 0,
+    UPDATED_AT BIGINT NOT NULL,
+    PRIMARY KEY (USER_ID, CURRENCY),
+    CONSTRAINT fk_transfer_limits_user FOREIGN KEY(USER_ID) REFERENCES USERS(ID),
+    CONSTRAINT ck_transfer_limits_amounts CHECK (PER_TRANSACTION >= 0 AND DAILY >= 0 AND MONTHLY >= 0)
+);
+
+CREATE INDEX idx_transactions_user_currency_created_at ON TRANSACTIONS(USER_ID, CURRENCY, CREATED_AT);
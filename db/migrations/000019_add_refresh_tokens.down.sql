@@ -0,0 +1,2 @@
+DROP TABLE REVOKED_ACCESS_TOKENS;
+DROP TABLE REFRESH_TOKENS;
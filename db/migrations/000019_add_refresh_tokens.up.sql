@@ -0,0 +1,24 @@
+-- refresh tokens rotate on every use, all tokens descending from one login share a FAMILY_ID
+CREATE TABLE REFRESH_TOKENS (
+    ID VARCHAR(36) PRIMARY KEY,
+    FAMILY_ID VARCHAR(36) NOT NULL,
+    USER_ID VARCHAR(36) NOT NULL,
+    TOKEN_HASH VARCHAR(64) NOT NULL,
+    EXPIRES_AT BIGINT NOT NULL,
+    CREATED_AT BIGINT NOT NULL,
+    USED_AT BIGINT NULL,
+    REVOKED_AT BIGINT NULL,
+    CONSTRAINT uq_refresh_tokens_token_hash UNIQUE (TOKEN_HASH),
+    CONSTRAINT fk_refresh_tokens_user FOREIGN KEY(USER_ID) REFERENCES USERS(ID)
+);
+
+CREATE INDEX idx_refresh_tokens_family ON REFRESH_TOKENS(FAMILY_ID);
+CREATE INDEX idx_refresh_tokens_expires_at ON REFRESH_TOKENS(EXPIRES_AT);
+
+-- access tokens revoked before they expire, keyed by their jti claim
+CREATE TABLE REVOKED_ACCESS_TOKENS (
+    JTI VARCHAR(36) PRIMARY KEY,
+    EXPIRES_AT BIGINT NOT NULL
+);
+
+CREATE INDEX idx_revoked_access_tokens_expires_at ON REVOKED_ACCESS_TOKENS(EXPIRES_AT);
@@ -0,0 +1,3 @@
+DROP TABLE MFA_CHALLENGES;
+DROP TABLE MFA_RECOVERY_CODES;
+DROP TABLE USER_MFA;
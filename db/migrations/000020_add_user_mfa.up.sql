@@ -0,0 +1,35 @@
+-- SECRET is the TOTP secret sealed with the server's mfa key, ENABLED_AT stays NULL until the user confirms a code
+CREATE TABLE USER_MFA (
+    USER_ID VARCHAR(36) PRIMARY KEY,
+    SECRET TEXT NOT NULL,
+    ENABLED_AT BIGINT NULL,
+    -- the last TOTP time step accepted, a code is never accepted twice
+    LAST_USED_STEP BIGINT NOT NULL DEFAULT 0,
+    CREATED_AT BIGINT NOT NULL,
+    UPDATED_AT BIGINT NOT NULL,
+    CONSTRAINT fk_user_mfa_user FOREIGN KEY(USER_ID) REFERENCES USERS(ID)
+);
+
+CREATE TABLE MFA_RECOVERY_CODES (
+    ID VARCHAR(36) PRIMARY KEY,
+    USER_ID VARCHAR(36) NOT NULL,
+    CODE_HASH VARCHAR(64) NOT NULL,
+    CREATED_AT BIGINT NOT NULL,
+    USED_AT BIGINT NULL,
+    CONSTRAINT uq_mfa_recovery_codes_user_code UNIQUE (USER_ID, CODE_HASH),
+    CONSTRAINT fk_mfa_recovery_codes_user FOREIGN KEY(USER_ID) REFERENCES USERS(ID)
+);
+
+-- challenges are handed out by login when the password was right and a second factor is still needed
+CREATE TABLE MFA_CHALLENGES (
+    ID VARCHAR(36) PRIMARY KEY,
+    USER_ID VARCHAR(36) NOT NULL,
+    TOKEN_HASH VARCHAR(64) NOT NULL,
+    ATTEMPTS INT NOT NULL DEFAULT 0,
+    EXPIRES_AT BIGINT NOT NULL,
+    CREATED_AT BIGINT NOT NULL,
+    CONSTRAINT uq_mfa_challenges_token_hash UNIQUE (TOKEN_HASH),
+    CONSTRAINT fk_mfa_challenges_user FOREIGN KEY(USER_ID) REFERENCES USERS(ID)
+);
+
+CREATE INDEX idx_mfa_challenges_expires_at ON MFA_CHALLENGES(EXPIRES_AT);
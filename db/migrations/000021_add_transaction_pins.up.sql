@@ -0,0 +1,10 @@
+-- FAILED_ATTEMPTS counts PIN entries since the last correct one, it is separate from password login
+CREATE TABLE USER_PINS (
+    USER_ID VARCHAR(36) PRIMARY KEY,
+    PIN_HASH TEXT NOT NULL,
+    FAILED_ATTEMPTS INT NOT NULL DEFAULT 0,
+    LOCKED_UNTIL BIGINT NULL,
+    CREATED_AT BIGINT NOT NULL,
+    UPDATED_AT BIGINT NOT NULL,
+    CONSTRAINT fk_user_pins_user FOREIGN KEY(USER_ID) REFERENCES USERS(ID)
+);
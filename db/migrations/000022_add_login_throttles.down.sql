@@ -0,0 +1 @@
+DROP TABLE LOGIN_THROTTLES;
@@ -0,0 +1,11 @@
+-- failed logins counted per account (by email, so unknown emails are throttled the same) and per client ip
+CREATE TABLE LOGIN_THROTTLES (
+    SCOPE VARCHAR(10) NOT NULL,
+    THROTTLE_KEY VARCHAR(255) NOT NULL,
+    FAILED_ATTEMPTS INT NOT NULL DEFAULT 0,
+    LAST_FAILED_AT BIGINT NOT NULL,
+    BLOCKED_UNTIL BIGINT NULL,
+    PRIMARY KEY (SCOPE, THROTTLE_KEY)
+);
+
+CREATE INDEX idx_login_throttles_last_failed_at ON LOGIN_THROTTLES(LAST_FAILED_AT);
@@ -0,0 +1 @@
+DROP INDEX idx_transactions_user_recipient;
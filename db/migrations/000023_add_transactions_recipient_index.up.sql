@@ -0,0 +1,2 @@
+-- cooling off looks up what a user sent to a recipient account, saved as a beneficiary or not
+CREATE INDEX idx_transactions_user_recipient ON TRANSACTIONS(USER_ID, BANK_NAME, BANK_ACCOUNT_NUMBER, CREATED_AT);
@@ -0,0 +1,2 @@
+ALTER TABLE USER_MFA DROP COLUMN LOCKED_UNTIL;
+ALTER TABLE USER_MFA DROP COLUMN FAILED_ATTEMPTS;
@@ -0,0 +1,3 @@
+-- wrong codes entered for an enabled 2FA lock code entry for a while once there were too many in a row
+ALTER TABLE USER_MFA ADD COLUMN FAILED_ATTEMPTS INT NOT NULL DEFAULT 0;
+ALTER TABLE USER_MFA ADD COLUMN LOCKED_UNTIL BIGINT NULL;
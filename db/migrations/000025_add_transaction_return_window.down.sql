@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_transactions_status_settled_at;
+UPDATE TRANSACTIONS SET STATUS = 'processing' WHERE STATUS = 'unresolved';
+ALTER TABLE TRANSACTIONS DROP CONSTRAINT ck_transactions_status;
+ALTER TABLE TRANSACTIONS ADD CONSTRAINT ck_transactions_status CHECK (STATUS IN ('pending', 'processing', 'completed', 'failed', 'returned'));
+ALTER TABLE TRANSACTIONS DROP COLUMN POLL_ERRORS;
+ALTER TABLE TRANSACTIONS DROP COLUMN SETTLED_AT;
@@ -0,0 +1,7 @@
+-- completed transfers are watched for bank returns until their return window after SETTLED_AT runs out
+ALTER TABLE TRANSACTIONS ADD COLUMN SETTLED_AT BIGINT NULL;
+-- POLL_ERRORS counts status checks in a row the bank did not answer
+ALTER TABLE TRANSACTIONS ADD COLUMN POLL_ERRORS INT NOT NULL DEFAULT 0;
+ALTER TABLE TRANSACTIONS DROP CONSTRAINT ck_transactions_status;
+ALTER TABLE TRANSACTIONS ADD CONSTRAINT ck_transactions_status CHECK (STATUS IN ('pending', 'processing', 'completed', 'failed', 'returned', 'unresolved'));
+CREATE INDEX idx_transactions_status_settled_at ON TRANSACTIONS(STATUS, SETTLED_AT);
@@ -0,0 +1 @@
+UPDATE BALANCES_HISTORY SET SOURCE_BANK_ACCOUNT_NUMBER = COUNTERPARTY_USER_ID WHERE TYPE IN ('transfer_in', 'transfer_out') AND SOURCE_BANK_ACCOUNT_NUMBER = '';
@@ -0,0 +1,2 @@
+-- internal transfers have no bank account, the other user is in COUNTERPARTY_USER_ID
+UPDATE BALANCES_HISTORY SET SOURCE_BANK_ACCOUNT_NUMBER = '' WHERE TYPE IN ('transfer_in', 'transfer_out') AND SOURCE_BANK_ACCOUNT_NUMBER = COUNTERPARTY_USER_ID;
@@ -0,0 +1,5 @@
+ALTER TABLE BENEFICIARIES ALTER COLUMN BANK_ACCOUNT_NUMBER TYPE VARCHAR(30);
+ALTER TABLE STANDING_ORDERS ALTER COLUMN BANK_ACCOUNT_NUMBER TYPE VARCHAR(30);
+ALTER TABLE TOPUPS ALTER COLUMN SENDER_BANK_ACCOUNT_NUMBER TYPE VARCHAR(30);
+ALTER TABLE TRANSACTIONS ALTER COLUMN BANK_ACCOUNT_NUMBER TYPE VARCHAR(30);
+ALTER TABLE BALANCES_HISTORY ALTER COLUMN SOURCE_BANK_ACCOUNT_NUMBER TYPE VARCHAR(30);
@@ -0,0 +1,6 @@
+-- account numbers are accepted up to 42 characters, IBANs are written with spaces
+ALTER TABLE BALANCES_HISTORY ALTER COLUMN SOURCE_BANK_ACCOUNT_NUMBER TYPE VARCHAR(42);
+ALTER TABLE TRANSACTIONS ALTER COLUMN BANK_ACCOUNT_NUMBER TYPE VARCHAR(42);
+ALTER TABLE TOPUPS ALTER COLUMN SENDER_BANK_ACCOUNT_NUMBER TYPE VARCHAR(42);
+ALTER TABLE STANDING_ORDERS ALTER COLUMN BANK_ACCOUNT_NUMBER TYPE VARCHAR(42);
+ALTER TABLE BENEFICIARIES ALTER COLUMN BANK_ACCOUNT_NUMBER TYPE VARCHAR(42);
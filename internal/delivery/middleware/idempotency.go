@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	httpHelper "github.com/ovrrtd/openidea-bank/internal/helper/http"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/ovrrtd/openidea-bank/internal/model/response"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyCompleteTimeout bounds storing the response once the request context can no longer do so
+const idempotencyCompleteTimeout = 5 * time.Second
+
+// Idempotency replays the stored response when a request is retried with the same Idempotency-Key header.
+// It must run after Authentication since keys are scoped per user.
+func (m *middleware) Idempotency(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := r.Context()
+		user, ok := ctx.Value(common.EncodedUserJwtCtxKey).(*response.User)
+		if !ok {
+			httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			httpHelper.ResponseJSONHTTP(w, http.StatusBadRequest, "", nil, nil, errorer.ErrInputRequest(err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+		hash.Write(body)
+
+		replay, code, err := m.service.ReserveIdempotencyKey(ctx, request.ReserveIdempotencyKey{
+			UserID:      user.ID,
+			Key:         key,
+			RequestHash: hex.EncodeToString(hash.Sum(nil)),
+		})
+		if err != nil {
+			httpHelper.ResponseJSONHTTP(w, code, "", nil, nil, err)
+			return
+		}
+		if replay != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(replay.StatusCode)
+			w.Write(replay.Response)
+			return
+		}
+
+		// a handler panic may come after the money moved, so it is stored like any other server error
+		// instead of leaving the key reserved until it expires
+		defer func() {
+			if rec := recover(); rec != nil {
+				res, _ := json.Marshal(map[string]interface{}{"data": nil, "message": errorer.ErrInternalServer.Error()})
+				m.completeIdempotencyKey(r, request.CompleteIdempotencyKey{
+					UserID:     user.ID,
+					Key:        key,
+					StatusCode: http.StatusInternalServerError,
+					Response:   res,
+				})
+				panic(rec)
+			}
+		}()
+
+		recorder := &recordingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		m.completeIdempotencyKey(r, request.CompleteIdempotencyKey{
+			UserID:      user.ID,
+			Key:         key,
+			StatusCode:  recorder.status,
+			Response:    recorder.body.Bytes(),
+			NotExecuted: recorder.notExecuted,
+		})
+	}
+}
+
+// completeIdempotencyKey stores the outcome even when the client has gone away, which is when it
+// is needed most. The handler has already answered, so a failure here only costs the replay.
+func (m *middleware) completeIdempotencyKey(r *http.Request, payload request.CompleteIdempotencyKey) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), idempotencyCompleteTimeout)
+	defer cancel()
+
+	_, err := m.service.CompleteIdempotencyKey(ctx, payload)
+	if err != nil {
+		m.logger.Error().Err(err).Str("idempotencyKey", payload.Key).Msg("failed to store idempotent response")
+	}
+}
+
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	notExecuted bool
+}
+
+func (rw *recordingResponseWriter) MarkNotExecuted() {
+	rw.notExecuted = true
+}
+
+func (rw *recordingResponseWriter) WriteHeader(code int) {
+	rw.status = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingResponseWriter) Write(b []byte) (int, error) {
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
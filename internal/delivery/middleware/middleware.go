@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"os"
 	"runtime"
 	"strconv"
 	"strings"
@@ -14,7 +13,8 @@ import (
 	"github.com/ovrrtd/openidea-bank/internal/helper/common"
 	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
 	httpHelper "github.com/ovrrtd/openidea-bank/internal/helper/http"
-	"github.com/ovrrtd/openidea-bank/internal/helper/jwt"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/ovrrtd/openidea-bank/internal/model/response"
 	"github.com/ovrrtd/openidea-bank/internal/service"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -37,6 +37,9 @@ type middleware struct {
 
 type Middleware interface {
 	Authentication(isThrowError bool) func(next http.HandlerFunc) http.HandlerFunc
+	Idempotency(next http.HandlerFunc) http.HandlerFunc
+	TransactionPin(next http.HandlerFunc) http.HandlerFunc
+	Admin(next http.HandlerFunc) http.HandlerFunc
 	LoggingMiddleware(h http.Handler) http.Handler
 	RemoveTrailingSlash(h http.Handler) http.Handler
 	NewRoute(router *mux.Router, method string, path string, handler http.HandlerFunc)
@@ -61,14 +64,9 @@ func (m *middleware) Authentication(isThrowError bool) func(next http.HandlerFun
 				return
 			}
 			if token != "" {
-				claims := &common.UserClaims{}
-				err := jwt.VerifyJwt(token, claims, os.Getenv("JWT_SECRET"))
+				claims, code, err := m.service.VerifyAccessToken(ctx, token)
 				if err != nil {
-					if err == errorer.ErrUnauthorized {
-						httpHelper.ResponseJSONHTTP(w, http.StatusUnauthorized, "", nil, nil, errorer.ErrUnauthorized)
-						return
-					}
-					httpHelper.ResponseJSONHTTP(w, http.StatusForbidden, "", nil, nil, errorer.ErrForbidden)
+					httpHelper.ResponseJSONHTTP(w, code, "", nil, nil, err)
 					return
 				}
 
@@ -77,6 +75,7 @@ func (m *middleware) Authentication(isThrowError bool) func(next http.HandlerFun
 					httpHelper.ResponseJSONHTTP(w, code, "", nil, nil, err)
 					return
 				}
+				ctx = context.WithValue(ctx, common.JwtCtxKey, claims)
 				ctx = context.WithValue(ctx, common.EncodedUserJwtCtxKey, usr)
 			}
 
@@ -85,6 +84,23 @@ func (m *middleware) Authentication(isThrowError bool) func(next http.HandlerFun
 	}
 }
 
+// Admin only lets users with the admin role through, it must run after Authentication
+func (m *middleware) Admin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		usr, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+		if !ok {
+			httpHelper.ResponseJSONHTTP(w, http.StatusUnauthorized, "", nil, nil, errorer.ErrUnauthorized)
+			return
+		}
+		if usr.Role != entity.UserRoleAdmin {
+			httpHelper.ResponseJSONHTTP(w, http.StatusForbidden, "", nil, nil, errorer.ErrForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
 // LoggingMiddleware logs the incoming HTTP request & its duration.
 func (m *middleware) LoggingMiddleware(next http.Handler) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
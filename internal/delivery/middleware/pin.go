@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	httpHelper "github.com/ovrrtd/openidea-bank/internal/helper/http"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/ovrrtd/openidea-bank/internal/model/response"
+)
+
+const TransactionPinHeader = "X-Transaction-Pin"
+
+// TransactionPin asks for the user's transaction PIN on endpoints that move money out. It must run after
+// Authentication and before Idempotency, so a wrong PIN does not use up the idempotency key.
+func (m *middleware) TransactionPin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+		if !ok {
+			httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+			return
+		}
+
+		code, err := m.service.VerifyTransactionPin(r.Context(), request.VerifyPin{
+			UserID: user.ID,
+			Pin:    r.Header.Get(TransactionPinHeader),
+		})
+		if err != nil {
+			httpHelper.ResponseJSONHTTP(w, code, "", nil, nil, err)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
@@ -2,14 +2,19 @@ package restapi
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/ovrrtd/openidea-bank/internal/helper/common"
 	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
 	httpHelper "github.com/ovrrtd/openidea-bank/internal/helper/http"
+	"github.com/ovrrtd/openidea-bank/internal/helper/statement"
 	"github.com/ovrrtd/openidea-bank/internal/model/request"
 	"github.com/ovrrtd/openidea-bank/internal/model/response"
+	"github.com/pkg/errors"
 )
 
 func (api *Restapi) AddBalance(w http.ResponseWriter, r *http.Request) {
@@ -29,8 +34,8 @@ func (api *Restapi) AddBalance(w http.ResponseWriter, r *http.Request) {
 
 	payload.UserID = user.ID
 
-	code, err := api.service.AddBalance(r.Context(), payload)
-	httpHelper.ResponseJSONHTTP(w, code, "", nil, nil, err)
+	topUp, code, err := api.service.AddBalance(r.Context(), payload)
+	httpHelper.ResponseJSONHTTP(w, code, "", topUp, nil, err)
 	api.debugError(err)
 }
 
@@ -49,8 +54,98 @@ func (api *Restapi) CreateTransaction(w http.ResponseWriter, r *http.Request) {
 
 	payload.UserID = user.ID
 
-	code, err := api.service.CreateTransaction(r.Context(), payload)
-	httpHelper.ResponseJSONHTTP(w, code, "", nil, nil, err)
+	transaction, code, err := api.service.CreateTransaction(r.Context(), payload)
+	httpHelper.ResponseJSONHTTP(w, code, "", transaction, nil, err)
+	api.debugError(err)
+}
+
+func (api *Restapi) GetTransaction(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	transaction, code, err := api.service.GetTransaction(r.Context(), request.GetTransaction{
+		TransactionID: mux.Vars(r)["id"],
+		UserID:        user.ID,
+	})
+	httpHelper.ResponseJSONHTTP(w, code, "", transaction, nil, err)
+	api.debugError(err)
+}
+
+func (api *Restapi) GetTransactionReceipt(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	transactionID := mux.Vars(r)["id"]
+	pdf, code, err := api.service.GetTransactionReceipt(r.Context(), request.GetTransaction{
+		TransactionID: transactionID,
+		UserID:        user.ID,
+	})
+	if err != nil {
+		httpHelper.ResponseJSONHTTP(w, code, "", nil, nil, err)
+		api.debugError(err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="receipt-%s.pdf"`, transactionID))
+	w.Header().Set("Content-Length", strconv.Itoa(len(pdf)))
+	w.WriteHeader(code)
+	w.Write(pdf)
+}
+
+// VerifyReceipt is public, it is what the QR code on a receipt links to
+func (api *Restapi) VerifyReceipt(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	verification, code, err := api.service.VerifyReceipt(r.Context(), request.VerifyReceipt{
+		TransactionID: query.Get("transactionId"),
+		Signature:     query.Get("signature"),
+	})
+	httpHelper.ResponseJSONHTTP(w, code, "", verification, nil, err)
+	api.debugError(err)
+}
+
+func (api *Restapi) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	var payload request.CreateTransfer
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		httpHelper.ResponseJSONHTTP(w, http.StatusBadRequest, "", nil, nil, errorer.ErrInputRequest(err))
+		return
+	}
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	payload.UserID = user.ID
+
+	transfer, code, err := api.service.CreateTransfer(r.Context(), payload)
+	httpHelper.ResponseJSONHTTP(w, code, "", transfer, nil, err)
+	api.debugError(err)
+}
+
+func (api *Restapi) ReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	var payload request.ReverseTransaction
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		httpHelper.ResponseJSONHTTP(w, http.StatusBadRequest, "", nil, nil, errorer.ErrInputRequest(err))
+		return
+	}
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	payload.TransactionID = mux.Vars(r)["id"]
+	payload.ActorID = user.ID
+
+	reversal, code, err := api.service.ReverseTransaction(r.Context(), payload)
+	httpHelper.ResponseJSONHTTP(w, code, "", reversal, nil, err)
 	api.debugError(err)
 }
 
@@ -61,11 +156,67 @@ func (api *Restapi) GetBalances(w http.ResponseWriter, r *http.Request) {
 		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
 		return
 	}
+	if query := r.URL.Query(); query.Has("at") {
+		balances, code, err := api.service.GetBalancesAt(r.Context(), request.GetBalancesAt{UserID: user.ID, At: query.Get("at")})
+		httpHelper.ResponseJSONHTTP(w, code, "", balances, nil, err)
+		api.debugError(err)
+		return
+	}
 	balances, code, err := api.service.GetBalances(r.Context(), user.ID)
 	httpHelper.ResponseJSONHTTP(w, code, "", balances, nil, err)
 	api.debugError(err)
 }
 
+// GetStatement streams the user's statement for a period as a file download
+func (api *Restapi) GetStatement(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = statement.FormatCSV
+	}
+
+	stmt, code, err := api.service.PrepareStatement(r.Context(), request.GetStatement{
+		UserID:   user.ID,
+		From:     query.Get("from"),
+		To:       query.Get("to"),
+		Currency: query.Get("currency"),
+		Format:   format,
+	})
+	if err != nil {
+		httpHelper.ResponseJSONHTTP(w, code, "", nil, nil, err)
+		api.debugError(err)
+		return
+	}
+
+	w.Header().Set("Content-Type", stmt.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, stmt.Filename))
+	w.WriteHeader(http.StatusOK)
+	// the status is already sent, a failure halfway can only cut the file short
+	if err := api.service.WriteStatement(r.Context(), stmt, w); err != nil {
+		api.log.Error().Err(err).Str("userId", user.ID).Msg("failed to write statement")
+	}
+}
+
+// GetUserBalancesAt lets an admin look up any user's balance at an instant, now when at is left out
+func (api *Restapi) GetUserBalancesAt(w http.ResponseWriter, r *http.Request) {
+	payload := request.GetBalancesAt{
+		UserID: mux.Vars(r)["id"],
+		At:     r.URL.Query().Get("at"),
+	}
+	if payload.At == "" {
+		payload.At = time.Now().Format(time.RFC3339)
+	}
+
+	balances, code, err := api.service.GetBalancesAt(r.Context(), payload)
+	httpHelper.ResponseJSONHTTP(w, code, "", balances, nil, err)
+	api.debugError(err)
+}
+
 func (api *Restapi) GetBalancesHistory(w http.ResponseWriter, r *http.Request) {
 	var payload request.GetBalancesHistory
 	query := r.URL.Query()
@@ -91,13 +242,44 @@ func (api *Restapi) GetBalancesHistory(w http.ResponseWriter, r *http.Request) {
 		payload.Offset = 0
 	}
 
+	// dates are unix milliseconds
+	for name, dst := range map[string]*int64{
+		"from": &payload.From,
+		"to":   &payload.To,
+	} {
+		if !query.Has(name) {
+			continue
+		}
+		v, err := strconv.ParseInt(query.Get(name), 10, 64)
+		if err != nil {
+			httpHelper.ResponseJSONHTTP(w, http.StatusBadRequest, "", nil, nil, errors.Wrap(errorer.ErrBadRequest, "invalid "+name))
+			return
+		}
+		*dst = v
+	}
+	payload.MinAmount = request.Amount(query.Get("minAmount"))
+	payload.MaxAmount = request.Amount(query.Get("maxAmount"))
+	payload.Currency = query.Get("currency")
+	payload.Direction = query.Get("direction")
+	payload.BankName = query.Get("bankName")
+	payload.TransactionID = query.Get("transactionId")
+	payload.Cursor = query.Get("cursor")
+	if query.Has("withTotal") {
+		withTotal, err := strconv.ParseBool(query.Get("withTotal"))
+		if err != nil {
+			httpHelper.ResponseJSONHTTP(w, http.StatusBadRequest, "", nil, nil, errors.Wrap(errorer.ErrBadRequest, "invalid withTotal"))
+			return
+		}
+		payload.WithTotal = withTotal
+	}
+
 	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
 	if !ok {
 		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
 		return
 	}
 	payload.UserID = user.ID
-	balances, code, err := api.service.GetBalancesHistory(r.Context(), payload)
-	httpHelper.ResponseJSONHTTP(w, code, "", balances, nil, err)
+	balances, meta, code, err := api.service.GetBalancesHistory(r.Context(), payload)
+	httpHelper.ResponseJSONHTTP(w, code, "", balances, meta, err)
 	api.debugError(err)
 }
@@ -0,0 +1,13 @@
+package restapi
+
+import (
+	"net/http"
+
+	httpHelper "github.com/ovrrtd/openidea-bank/internal/helper/http"
+)
+
+func (api *Restapi) GetBanks(w http.ResponseWriter, r *http.Request) {
+	banks, code, err := api.service.GetBanks(r.Context())
+	httpHelper.ResponseJSONHTTP(w, code, "", banks, nil, err)
+	api.debugError(err)
+}
@@ -0,0 +1,94 @@
+package restapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	httpHelper "github.com/ovrrtd/openidea-bank/internal/helper/http"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/ovrrtd/openidea-bank/internal/model/response"
+)
+
+func (api *Restapi) CreateBeneficiary(w http.ResponseWriter, r *http.Request) {
+	var payload request.CreateBeneficiary
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		httpHelper.ResponseJSONHTTP(w, http.StatusBadRequest, "", nil, nil, errorer.ErrInputRequest(err))
+		return
+	}
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	payload.UserID = user.ID
+
+	beneficiary, code, err := api.service.CreateBeneficiary(r.Context(), payload)
+	httpHelper.ResponseJSONHTTP(w, code, "", beneficiary, nil, err)
+	api.debugError(err)
+}
+
+func (api *Restapi) GetBeneficiaries(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	beneficiaries, code, err := api.service.GetBeneficiaries(r.Context(), user.ID)
+	httpHelper.ResponseJSONHTTP(w, code, "", beneficiaries, nil, err)
+	api.debugError(err)
+}
+
+func (api *Restapi) GetBeneficiary(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	beneficiary, code, err := api.service.GetBeneficiary(r.Context(), request.GetBeneficiary{
+		BeneficiaryID: mux.Vars(r)["id"],
+		UserID:        user.ID,
+	})
+	httpHelper.ResponseJSONHTTP(w, code, "", beneficiary, nil, err)
+	api.debugError(err)
+}
+
+func (api *Restapi) UpdateBeneficiary(w http.ResponseWriter, r *http.Request) {
+	var payload request.UpdateBeneficiary
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		httpHelper.ResponseJSONHTTP(w, http.StatusBadRequest, "", nil, nil, errorer.ErrInputRequest(err))
+		return
+	}
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	payload.BeneficiaryID = mux.Vars(r)["id"]
+	payload.UserID = user.ID
+
+	beneficiary, code, err := api.service.UpdateBeneficiary(r.Context(), payload)
+	httpHelper.ResponseJSONHTTP(w, code, "", beneficiary, nil, err)
+	api.debugError(err)
+}
+
+func (api *Restapi) DeleteBeneficiary(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	code, err := api.service.DeleteBeneficiary(r.Context(), request.GetBeneficiary{
+		BeneficiaryID: mux.Vars(r)["id"],
+		UserID:        user.ID,
+	})
+	httpHelper.ResponseJSONHTTP(w, code, "", nil, nil, err)
+	api.debugError(err)
+}
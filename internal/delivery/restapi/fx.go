@@ -0,0 +1,68 @@
+package restapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	httpHelper "github.com/ovrrtd/openidea-bank/internal/helper/http"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/ovrrtd/openidea-bank/internal/model/response"
+)
+
+func (api *Restapi) UpsertFxRates(w http.ResponseWriter, r *http.Request) {
+	var payload request.UpsertFxRates
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		httpHelper.ResponseJSONHTTP(w, http.StatusBadRequest, "", nil, nil, errorer.ErrInputRequest(err))
+		return
+	}
+
+	code, err := api.service.UpsertFxRates(r.Context(), payload)
+	httpHelper.ResponseJSONHTTP(w, code, "", nil, nil, err)
+	api.debugError(err)
+}
+
+func (api *Restapi) GetFxRates(w http.ResponseWriter, r *http.Request) {
+	rates, code, err := api.service.GetFxRates(r.Context())
+	httpHelper.ResponseJSONHTTP(w, code, "", rates, nil, err)
+	api.debugError(err)
+}
+
+func (api *Restapi) CreateFxQuote(w http.ResponseWriter, r *http.Request) {
+	var payload request.CreateFxQuote
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		httpHelper.ResponseJSONHTTP(w, http.StatusBadRequest, "", nil, nil, errorer.ErrInputRequest(err))
+		return
+	}
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	payload.UserID = user.ID
+
+	quote, code, err := api.service.CreateFxQuote(r.Context(), payload)
+	httpHelper.ResponseJSONHTTP(w, code, "", quote, nil, err)
+	api.debugError(err)
+}
+
+func (api *Restapi) ConvertFx(w http.ResponseWriter, r *http.Request) {
+	var payload request.ConvertFx
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		httpHelper.ResponseJSONHTTP(w, http.StatusBadRequest, "", nil, nil, errorer.ErrInputRequest(err))
+		return
+	}
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	payload.UserID = user.ID
+
+	conversion, code, err := api.service.ConvertFx(r.Context(), payload)
+	httpHelper.ResponseJSONHTTP(w, code, "", conversion, nil, err)
+	api.debugError(err)
+}
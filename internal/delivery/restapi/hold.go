@@ -0,0 +1,74 @@
+package restapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	httpHelper "github.com/ovrrtd/openidea-bank/internal/helper/http"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/ovrrtd/openidea-bank/internal/model/response"
+)
+
+func (api *Restapi) CreateHold(w http.ResponseWriter, r *http.Request) {
+	var payload request.CreateHold
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		httpHelper.ResponseJSONHTTP(w, http.StatusBadRequest, "", nil, nil, errorer.ErrInputRequest(err))
+		return
+	}
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	payload.UserID = user.ID
+
+	hold, code, err := api.service.CreateHold(r.Context(), payload)
+	httpHelper.ResponseJSONHTTP(w, code, "", hold, nil, err)
+	api.debugError(err)
+}
+
+func (api *Restapi) GetHolds(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	holds, code, err := api.service.GetHolds(r.Context(), user.ID)
+	httpHelper.ResponseJSONHTTP(w, code, "", holds, nil, err)
+	api.debugError(err)
+}
+
+func (api *Restapi) CaptureHold(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	hold, code, err := api.service.CaptureHold(r.Context(), request.SettleHold{
+		HoldID: mux.Vars(r)["id"],
+		UserID: user.ID,
+	})
+	httpHelper.ResponseJSONHTTP(w, code, "", hold, nil, err)
+	api.debugError(err)
+}
+
+func (api *Restapi) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	hold, code, err := api.service.ReleaseHold(r.Context(), request.SettleHold{
+		HoldID: mux.Vars(r)["id"],
+		UserID: user.ID,
+	})
+	httpHelper.ResponseJSONHTTP(w, code, "", hold, nil, err)
+	api.debugError(err)
+}
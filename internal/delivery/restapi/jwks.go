@@ -0,0 +1,27 @@
+package restapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	httpHelper "github.com/ovrrtd/openidea-bank/internal/helper/http"
+)
+
+// GetJWKS serves the key set bare rather than in the usual envelope, JWKS clients expect {"keys": [...]}
+func (api *Restapi) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	jwks, code, err := api.service.GetJWKS(r.Context())
+	if err != nil {
+		httpHelper.ResponseJSONHTTP(w, code, "", nil, nil, err)
+		api.debugError(err)
+		return
+	}
+
+	rJson, _ := json.Marshal(jwks)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(rJson)))
+	// short enough for verifiers to see a new key well within the rotation grace period
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(code)
+	w.Write(rJson)
+}
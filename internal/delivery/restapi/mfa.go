@@ -0,0 +1,74 @@
+package restapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	httpHelper "github.com/ovrrtd/openidea-bank/internal/helper/http"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/ovrrtd/openidea-bank/internal/model/response"
+)
+
+func (api *Restapi) EnrollMfa(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	enrollment, code, err := api.service.EnrollMfa(r.Context(), user.ID)
+	httpHelper.ResponseJSONHTTP(w, code, "", enrollment, nil, err)
+	api.debugError(err)
+}
+
+func (api *Restapi) VerifyMfa(w http.ResponseWriter, r *http.Request) {
+	payload, ok := api.decodeMfaCode(w, r)
+	if !ok {
+		return
+	}
+
+	codes, code, err := api.service.VerifyMfa(r.Context(), payload)
+	httpHelper.ResponseJSONHTTP(w, code, "", codes, nil, err)
+	api.debugError(err)
+}
+
+func (api *Restapi) DisableMfa(w http.ResponseWriter, r *http.Request) {
+	payload, ok := api.decodeMfaCode(w, r)
+	if !ok {
+		return
+	}
+
+	code, err := api.service.DisableMfa(r.Context(), payload)
+	httpHelper.ResponseJSONHTTP(w, code, "", nil, nil, err)
+	api.debugError(err)
+}
+
+func (api *Restapi) RegenerateMfaRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	payload, ok := api.decodeMfaCode(w, r)
+	if !ok {
+		return
+	}
+
+	codes, code, err := api.service.RegenerateMfaRecoveryCodes(r.Context(), payload)
+	httpHelper.ResponseJSONHTTP(w, code, "", codes, nil, err)
+	api.debugError(err)
+}
+
+// decodeMfaCode reads the code of a 2FA change, the response is already written when it fails
+func (api *Restapi) decodeMfaCode(w http.ResponseWriter, r *http.Request) (request.MfaCode, bool) {
+	var payload request.MfaCode
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		httpHelper.ResponseJSONHTTP(w, http.StatusBadRequest, "", nil, nil, errorer.ErrInputRequest(err))
+		return payload, false
+	}
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return payload, false
+	}
+	payload.UserID = user.ID
+
+	return payload, true
+}
@@ -1,6 +1,8 @@
 package restapi
 
 import (
+	"net"
+
 	"github.com/ovrrtd/openidea-bank/internal/delivery/middleware"
 	"github.com/ovrrtd/openidea-bank/internal/service"
 
@@ -11,17 +13,21 @@ type Restapi struct {
 	log        zerolog.Logger
 	middleware middleware.Middleware
 	service    service.Service
+	// trustedProxies are the reverse proxies whose X-Forwarded-For is believed
+	trustedProxies []*net.IPNet
 }
 
 func New(
 	log zerolog.Logger,
 	middleware middleware.Middleware,
 	s service.Service,
+	trustedProxies []*net.IPNet,
 ) *Restapi {
 	return &Restapi{
-		log:        log,
-		middleware: middleware,
-		service:    s,
+		log:            log,
+		middleware:     middleware,
+		service:        s,
+		trustedProxies: trustedProxies,
 	}
 }
 
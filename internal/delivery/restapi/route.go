@@ -21,15 +21,66 @@ func (api *Restapi) MakeRoute(mr *mux.Router) {
 		w.Header().Add("content-length", strconv.Itoa(len(rJson)))
 		w.Write([]byte(rJson))
 	})
+	// jwks
+	api.middleware.NewRoute(mr, http.MethodGet, "/.well-known/jwks.json", api.GetJWKS)
 	// user
 	api.middleware.NewRoute(mr, http.MethodPost, "/v1/user/register", api.Register)
 	api.middleware.NewRoute(mr, http.MethodPost, "/v1/user/login", api.Login)
+	api.middleware.NewRoute(mr, http.MethodPost, "/v1/user/login/mfa", api.LoginMfa)
+	api.middleware.NewRoute(mr, http.MethodPost, "/v1/user/refresh", api.RefreshToken)
+	api.middleware.NewRoute(mr, http.MethodPost, "/v1/user/logout", api.middleware.Authentication(true)(http.HandlerFunc(api.Logout)))
+	api.middleware.NewRoute(mr, http.MethodPut, "/v1/user/pin", api.middleware.Authentication(true)(http.HandlerFunc(api.SetTransactionPin)))
+	api.middleware.NewRoute(mr, http.MethodPost, "/v1/user/mfa/enroll", api.middleware.Authentication(true)(http.HandlerFunc(api.EnrollMfa)))
+	api.middleware.NewRoute(mr, http.MethodPost, "/v1/user/mfa/verify", api.middleware.Authentication(true)(http.HandlerFunc(api.VerifyMfa)))
+	api.middleware.NewRoute(mr, http.MethodPost, "/v1/user/mfa/disable", api.middleware.Authentication(true)(http.HandlerFunc(api.DisableMfa)))
+	api.middleware.NewRoute(mr, http.MethodPost, "/v1/user/mfa/recovery-codes", api.middleware.Authentication(true)(http.HandlerFunc(api.RegenerateMfaRecoveryCodes)))
 	// image
 	api.middleware.NewRoute(mr, http.MethodPost, "/v1/image", api.middleware.Authentication(true)(http.HandlerFunc(api.UploadImage)))
 	// balance
 	api.middleware.NewRoute(mr, http.MethodGet, "/v1/balance", api.middleware.Authentication(true)(http.HandlerFunc(api.GetBalances)))
-	api.middleware.NewRoute(mr, http.MethodPost, "/v1/balance", api.middleware.Authentication(true)(http.HandlerFunc(api.AddBalance)))
+	api.middleware.NewRoute(mr, http.MethodPost, "/v1/balance", api.middleware.Authentication(true)(api.middleware.Idempotency(api.AddBalance)))
 	api.middleware.NewRoute(mr, http.MethodGet, "/v1/balance/history", api.middleware.Authentication(true)(http.HandlerFunc(api.GetBalancesHistory)))
+	api.middleware.NewRoute(mr, http.MethodGet, "/v1/balance/statement", api.middleware.Authentication(true)(http.HandlerFunc(api.GetStatement)))
 	// transaction
-	api.middleware.NewRoute(mr, http.MethodPost, "/v1/transaction", api.middleware.Authentication(true)(http.HandlerFunc(api.CreateTransaction)))
+	api.middleware.NewRoute(mr, http.MethodPost, "/v1/transaction", api.middleware.Authentication(true)(api.middleware.TransactionPin(api.middleware.Idempotency(api.CreateTransaction))))
+	api.middleware.NewRoute(mr, http.MethodGet, "/v1/transaction/{id}", api.middleware.Authentication(true)(http.HandlerFunc(api.GetTransaction)))
+	api.middleware.NewRoute(mr, http.MethodGet, "/v1/transaction/{id}/receipt.pdf", api.middleware.Authentication(true)(http.HandlerFunc(api.GetTransactionReceipt)))
+	api.middleware.NewRoute(mr, http.MethodGet, "/v1/receipts/verify", api.VerifyReceipt)
+	// transfer
+	api.middleware.NewRoute(mr, http.MethodPost, "/v1/transfer", api.middleware.Authentication(true)(api.middleware.TransactionPin(api.middleware.Idempotency(api.CreateTransfer))))
+	// bank directory
+	api.middleware.NewRoute(mr, http.MethodGet, "/v1/banks", api.GetBanks)
+	// transfer limits
+	api.middleware.NewRoute(mr, http.MethodGet, "/v1/limits", api.middleware.Authentication(true)(http.HandlerFunc(api.GetTransferLimits)))
+	// beneficiary
+	api.middleware.NewRoute(mr, http.MethodGet, "/v1/beneficiaries", api.middleware.Authentication(true)(http.HandlerFunc(api.GetBeneficiaries)))
+	api.middleware.NewRoute(mr, http.MethodPost, "/v1/beneficiaries", api.middleware.Authentication(true)(api.middleware.Idempotency(api.CreateBeneficiary)))
+	api.middleware.NewRoute(mr, http.MethodGet, "/v1/beneficiaries/{id}", api.middleware.Authentication(true)(http.HandlerFunc(api.GetBeneficiary)))
+	api.middleware.NewRoute(mr, http.MethodPut, "/v1/beneficiaries/{id}", api.middleware.Authentication(true)(http.HandlerFunc(api.UpdateBeneficiary)))
+	api.middleware.NewRoute(mr, http.MethodDelete, "/v1/beneficiaries/{id}", api.middleware.Authentication(true)(http.HandlerFunc(api.DeleteBeneficiary)))
+	// standing order
+	api.middleware.NewRoute(mr, http.MethodGet, "/v1/standing-orders", api.middleware.Authentication(true)(http.HandlerFunc(api.GetStandingOrders)))
+	api.middleware.NewRoute(mr, http.MethodPost, "/v1/standing-orders", api.middleware.Authentication(true)(api.middleware.TransactionPin(api.middleware.Idempotency(api.CreateStandingOrder))))
+	api.middleware.NewRoute(mr, http.MethodGet, "/v1/standing-orders/{id}", api.middleware.Authentication(true)(http.HandlerFunc(api.GetStandingOrder)))
+	api.middleware.NewRoute(mr, http.MethodPut, "/v1/standing-orders/{id}", api.middleware.Authentication(true)(api.middleware.TransactionPin(api.UpdateStandingOrder)))
+	api.middleware.NewRoute(mr, http.MethodDelete, "/v1/standing-orders/{id}", api.middleware.Authentication(true)(http.HandlerFunc(api.CancelStandingOrder)))
+	// hold
+	api.middleware.NewRoute(mr, http.MethodGet, "/v1/holds", api.middleware.Authentication(true)(http.HandlerFunc(api.GetHolds)))
+	api.middleware.NewRoute(mr, http.MethodPost, "/v1/holds", api.middleware.Authentication(true)(api.middleware.Idempotency(api.CreateHold)))
+	api.middleware.NewRoute(mr, http.MethodPost, "/v1/holds/{id}/capture", api.middleware.Authentication(true)(api.middleware.TransactionPin(api.middleware.Idempotency(api.CaptureHold))))
+	api.middleware.NewRoute(mr, http.MethodPost, "/v1/holds/{id}/release", api.middleware.Authentication(true)(http.HandlerFunc(api.ReleaseHold)))
+	// fx
+	api.middleware.NewRoute(mr, http.MethodGet, "/v1/fx/rates", api.middleware.Authentication(true)(http.HandlerFunc(api.GetFxRates)))
+	api.middleware.NewRoute(mr, http.MethodPost, "/v1/fx/quote", api.middleware.Authentication(true)(http.HandlerFunc(api.CreateFxQuote)))
+	api.middleware.NewRoute(mr, http.MethodPost, "/v1/fx/convert", api.middleware.Authentication(true)(api.middleware.Idempotency(api.ConvertFx)))
+	// admin
+	api.middleware.NewRoute(mr, http.MethodPut, "/v1/admin/fx/rates", api.middleware.Authentication(true)(api.middleware.Admin(api.UpsertFxRates)))
+	api.middleware.NewRoute(mr, http.MethodGet, "/v1/admin/users/{id}/balance", api.middleware.Authentication(true)(api.middleware.Admin(api.GetUserBalancesAt)))
+	api.middleware.NewRoute(mr, http.MethodPut, "/v1/admin/users/{id}/limits", api.middleware.Authentication(true)(api.middleware.Admin(api.SetUserTransferLimits)))
+	api.middleware.NewRoute(mr, http.MethodDelete, "/v1/admin/users/{id}/limits/{currency}", api.middleware.Authentication(true)(api.middleware.Admin(api.ResetUserTransferLimit)))
+	api.middleware.NewRoute(mr, http.MethodPost, "/v1/admin/users/{id}/unlock", api.middleware.Authentication(true)(api.middleware.Admin(api.UnlockUser)))
+	api.middleware.NewRoute(mr, http.MethodGet, "/v1/admin/topups", api.middleware.Authentication(true)(api.middleware.Admin(api.GetTopUps)))
+	api.middleware.NewRoute(mr, http.MethodPost, "/v1/admin/topups/{id}/approve", api.middleware.Authentication(true)(api.middleware.Admin(api.middleware.Idempotency(api.ApproveTopUp))))
+	api.middleware.NewRoute(mr, http.MethodPost, "/v1/admin/topups/{id}/reject", api.middleware.Authentication(true)(api.middleware.Admin(api.middleware.Idempotency(api.RejectTopUp))))
+	api.middleware.NewRoute(mr, http.MethodPost, "/v1/admin/transactions/{id}/reverse", api.middleware.Authentication(true)(api.middleware.Admin(api.middleware.Idempotency(api.ReverseTransaction))))
 }
@@ -0,0 +1,94 @@
+package restapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	httpHelper "github.com/ovrrtd/openidea-bank/internal/helper/http"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/ovrrtd/openidea-bank/internal/model/response"
+)
+
+func (api *Restapi) CreateStandingOrder(w http.ResponseWriter, r *http.Request) {
+	var payload request.CreateStandingOrder
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		httpHelper.ResponseJSONHTTP(w, http.StatusBadRequest, "", nil, nil, errorer.ErrInputRequest(err))
+		return
+	}
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	payload.UserID = user.ID
+
+	order, code, err := api.service.CreateStandingOrder(r.Context(), payload)
+	httpHelper.ResponseJSONHTTP(w, code, "", order, nil, err)
+	api.debugError(err)
+}
+
+func (api *Restapi) GetStandingOrders(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	orders, code, err := api.service.GetStandingOrders(r.Context(), user.ID)
+	httpHelper.ResponseJSONHTTP(w, code, "", orders, nil, err)
+	api.debugError(err)
+}
+
+func (api *Restapi) GetStandingOrder(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	order, code, err := api.service.GetStandingOrder(r.Context(), request.GetStandingOrder{
+		StandingOrderID: mux.Vars(r)["id"],
+		UserID:          user.ID,
+	})
+	httpHelper.ResponseJSONHTTP(w, code, "", order, nil, err)
+	api.debugError(err)
+}
+
+func (api *Restapi) UpdateStandingOrder(w http.ResponseWriter, r *http.Request) {
+	var payload request.UpdateStandingOrder
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		httpHelper.ResponseJSONHTTP(w, http.StatusBadRequest, "", nil, nil, errorer.ErrInputRequest(err))
+		return
+	}
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	payload.StandingOrderID = mux.Vars(r)["id"]
+	payload.UserID = user.ID
+
+	order, code, err := api.service.UpdateStandingOrder(r.Context(), payload)
+	httpHelper.ResponseJSONHTTP(w, code, "", order, nil, err)
+	api.debugError(err)
+}
+
+func (api *Restapi) CancelStandingOrder(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	order, code, err := api.service.CancelStandingOrder(r.Context(), request.GetStandingOrder{
+		StandingOrderID: mux.Vars(r)["id"],
+		UserID:          user.ID,
+	})
+	httpHelper.ResponseJSONHTTP(w, code, "", order, nil, err)
+	api.debugError(err)
+}
@@ -0,0 +1,71 @@
+package restapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	httpHelper "github.com/ovrrtd/openidea-bank/internal/helper/http"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/ovrrtd/openidea-bank/internal/model/response"
+)
+
+func (api *Restapi) GetTopUps(w http.ResponseWriter, r *http.Request) {
+	payload := request.GetTopUps{Limit: 10}
+	query := r.URL.Query()
+	payload.Status = query.Get("status")
+	if query.Has("limit") {
+		limit, err := strconv.Atoi(query.Get("limit"))
+		if err != nil {
+			httpHelper.ResponseJSONHTTP(w, http.StatusBadRequest, "", nil, nil, errorer.ErrBadRequest)
+			return
+		}
+		payload.Limit = limit
+	}
+	if query.Has("offset") {
+		offset, err := strconv.Atoi(query.Get("offset"))
+		if err != nil {
+			httpHelper.ResponseJSONHTTP(w, http.StatusBadRequest, "", nil, nil, errorer.ErrBadRequest)
+			return
+		}
+		payload.Offset = offset
+	}
+
+	topUps, code, err := api.service.GetTopUps(r.Context(), payload)
+	httpHelper.ResponseJSONHTTP(w, code, "", topUps, nil, err)
+	api.debugError(err)
+}
+
+func (api *Restapi) ApproveTopUp(w http.ResponseWriter, r *http.Request) {
+	api.reviewTopUp(w, r, api.service.ApproveTopUp)
+}
+
+func (api *Restapi) RejectTopUp(w http.ResponseWriter, r *http.Request) {
+	api.reviewTopUp(w, r, api.service.RejectTopUp)
+}
+
+func (api *Restapi) reviewTopUp(w http.ResponseWriter, r *http.Request, review func(ctx context.Context, payload request.ReviewTopUp) (*response.TopUp, int, error)) {
+	var payload request.ReviewTopUp
+	// the body is optional when approving
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && err != io.EOF {
+		httpHelper.ResponseJSONHTTP(w, http.StatusBadRequest, "", nil, nil, errorer.ErrInputRequest(err))
+		return
+	}
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	payload.TopUpID = mux.Vars(r)["id"]
+	payload.ReviewerID = user.ID
+
+	topUp, code, err := review(r.Context(), payload)
+	httpHelper.ResponseJSONHTTP(w, code, "", topUp, nil, err)
+	api.debugError(err)
+}
@@ -0,0 +1,62 @@
+package restapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	httpHelper "github.com/ovrrtd/openidea-bank/internal/helper/http"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/ovrrtd/openidea-bank/internal/model/response"
+)
+
+func (api *Restapi) GetTransferLimits(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	limits, code, err := api.service.GetTransferLimits(r.Context(), user.ID)
+	httpHelper.ResponseJSONHTTP(w, code, "", limits, nil, err)
+	api.debugError(err)
+}
+
+func (api *Restapi) SetUserTransferLimits(w http.ResponseWriter, r *http.Request) {
+	var payload request.SetTransferLimits
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		httpHelper.ResponseJSONHTTP(w, http.StatusBadRequest, "", nil, nil, errorer.ErrInputRequest(err))
+		return
+	}
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	payload.UserID = mux.Vars(r)["id"]
+	payload.AdminID = user.ID
+
+	limits, code, err := api.service.SetUserTransferLimits(r.Context(), payload)
+	httpHelper.ResponseJSONHTTP(w, code, "", limits, nil, err)
+	api.debugError(err)
+}
+
+func (api *Restapi) ResetUserTransferLimit(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	limits, code, err := api.service.ResetUserTransferLimit(r.Context(), request.ResetTransferLimit{
+		Currency: strings.ToUpper(mux.Vars(r)["currency"]),
+		UserID:   mux.Vars(r)["id"],
+		AdminID:  user.ID,
+	})
+	httpHelper.ResponseJSONHTTP(w, code, "", limits, nil, err)
+	api.debugError(err)
+}
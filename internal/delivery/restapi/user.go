@@ -4,8 +4,13 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
 	httpHelper "github.com/ovrrtd/openidea-bank/internal/helper/http"
 	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/ovrrtd/openidea-bank/internal/model/response"
+
+	"github.com/gorilla/mux"
 )
 
 func (api *Restapi) Register(w http.ResponseWriter, r *http.Request) {
@@ -28,8 +33,99 @@ func (api *Restapi) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	request.IP = httpHelper.ClientIP(r, api.trustedProxies)
+
 	ret, code, err := api.service.Login(r.Context(), request)
 	api.debugError(err)
 	httpHelper.ResponseJSONHTTP(w, code, "User logged successfully", ret, nil, err)
 
 }
+
+func (api *Restapi) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var request request.RefreshToken
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httpHelper.ResponseJSONHTTP(w, http.StatusBadRequest, "Error parsing request body", nil, nil, err)
+		return
+	}
+
+	ret, code, err := api.service.RefreshToken(r.Context(), request)
+	api.debugError(err)
+	httpHelper.ResponseJSONHTTP(w, code, "Token refreshed successfully", ret, nil, err)
+}
+
+func (api *Restapi) Logout(w http.ResponseWriter, r *http.Request) {
+	var request request.Logout
+	// the refresh token is optional, without it only the access token is revoked
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			httpHelper.ResponseJSONHTTP(w, http.StatusBadRequest, "Error parsing request body", nil, nil, err)
+			return
+		}
+	}
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+	claims, ok := r.Context().Value(common.JwtCtxKey).(*common.UserClaims)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	request.UserID = user.ID
+	request.JTI = claims.ID
+	if claims.ExpiresAt != nil {
+		request.ExpiresAt = claims.ExpiresAt.UnixMilli()
+	}
+
+	code, err := api.service.Logout(r.Context(), request)
+	api.debugError(err)
+	httpHelper.ResponseJSONHTTP(w, code, "User logged out successfully", nil, nil, err)
+}
+
+func (api *Restapi) LoginMfa(w http.ResponseWriter, r *http.Request) {
+	var request request.LoginMfa
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httpHelper.ResponseJSONHTTP(w, http.StatusBadRequest, "Error parsing request body", nil, nil, err)
+		return
+	}
+
+	ret, code, err := api.service.LoginMfa(r.Context(), request)
+	api.debugError(err)
+	httpHelper.ResponseJSONHTTP(w, code, "User logged successfully", ret, nil, err)
+}
+
+func (api *Restapi) SetTransactionPin(w http.ResponseWriter, r *http.Request) {
+	var request request.SetPin
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httpHelper.ResponseJSONHTTP(w, http.StatusBadRequest, "Error parsing request body", nil, nil, err)
+		return
+	}
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+	request.UserID = user.ID
+	request.IP = httpHelper.ClientIP(r, api.trustedProxies)
+
+	code, err := api.service.SetTransactionPin(r.Context(), request)
+	api.debugError(err)
+	httpHelper.ResponseJSONHTTP(w, code, "Transaction pin set successfully", nil, nil, err)
+}
+
+func (api *Restapi) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(common.EncodedUserJwtCtxKey).(*response.User)
+	if !ok {
+		httpHelper.ResponseJSONHTTP(w, http.StatusInternalServerError, "", nil, nil, errorer.ErrInternalServer)
+		return
+	}
+
+	code, err := api.service.UnlockUser(r.Context(), request.UnlockUser{
+		UserID:  mux.Vars(r)["id"],
+		AdminID: user.ID,
+	})
+	api.debugError(err)
+	httpHelper.ResponseJSONHTTP(w, code, "User unlocked successfully", nil, nil, err)
+}
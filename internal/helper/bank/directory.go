@@ -0,0 +1,175 @@
+package bank
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+const (
+	FormatDigits = "digits"
+	FormatIBAN   = "iban"
+
+	ChecksumLuhn = "luhn"
+
+	// MaxAccountNumberLength and MaxBankNameLength are the sizes of the columns accounts are stored in
+	MaxAccountNumberLength = 42
+	MaxBankNameLength      = 30
+)
+
+var (
+	ErrUnknownBank            = errors.New("unknown bank code")
+	ErrInvalidAccountNumber   = errors.New("account number must only contain digits")
+	ErrInvalidAccountLength   = errors.New("account number has the wrong length for this bank")
+	ErrInvalidAccountChecksum = errors.New("account number check digit does not match")
+)
+
+//go:embed banks.json
+var defaultBanks []byte
+
+// Bank is a directory entry, Code is what clients send to pick it
+type Bank struct {
+	Code          string      `json:"code"`
+	Name          string      `json:"name"`
+	SwiftBIC      string      `json:"swiftBic"`
+	Country       string      `json:"country"`
+	AccountNumber AccountRule `json:"accountNumber"`
+}
+
+// AccountRule describes valid account numbers at a bank. Digit accounts may list the lengths they come in
+// and a checksum, IBAN accounts are checked with mod-97, must be from the bank's country and list the
+// IBAN length of that country.
+type AccountRule struct {
+	Format   string `json:"format"`
+	Lengths  []int  `json:"lengths,omitempty"`
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// Directory is the set of banks money can be sent to or received from
+type Directory struct {
+	banks []Bank
+	codes map[string]Bank
+}
+
+// Default returns the directory embedded in the binary
+func Default() (*Directory, error) {
+	var banks []Bank
+	if err := json.Unmarshal(defaultBanks, &banks); err != nil {
+		return nil, err
+	}
+	return NewDirectory(banks)
+}
+
+// Load reads a directory from a JSON array shaped like the embedded one
+func Load(r io.Reader) (*Directory, error) {
+	var banks []Bank
+	if err := json.NewDecoder(r).Decode(&banks); err != nil {
+		return nil, err
+	}
+	return NewDirectory(banks)
+}
+
+func NewDirectory(banks []Bank) (*Directory, error) {
+	d := &Directory{codes: make(map[string]Bank, len(banks))}
+	for _, b := range banks {
+		if b.Code == "" || b.Name == "" {
+			return nil, fmt.Errorf("bank %q needs a code and a name", b.Code)
+		}
+		if len(b.Name) > MaxBankNameLength {
+			return nil, fmt.Errorf("bank %q has a name longer than %d characters", b.Code, MaxBankNameLength)
+		}
+		if _, ok := d.codes[b.Code]; ok {
+			return nil, fmt.Errorf("bank code %q is listed twice", b.Code)
+		}
+		switch b.AccountNumber.Format {
+		case FormatDigits:
+			if b.AccountNumber.Checksum != "" && b.AccountNumber.Checksum != ChecksumLuhn {
+				return nil, fmt.Errorf("bank %q has unknown checksum %q", b.Code, b.AccountNumber.Checksum)
+			}
+		case FormatIBAN:
+			if len(b.AccountNumber.Lengths) != 1 {
+				return nil, fmt.Errorf("bank %q needs the IBAN length of %q", b.Code, b.Country)
+			}
+		default:
+			return nil, fmt.Errorf("bank %q has unknown account number format %q", b.Code, b.AccountNumber.Format)
+		}
+		for _, l := range b.AccountNumber.Lengths {
+			if l > MaxAccountNumberLength {
+				return nil, fmt.Errorf("bank %q allows account numbers longer than %d characters", b.Code, MaxAccountNumberLength)
+			}
+		}
+		d.codes[b.Code] = b
+		d.banks = append(d.banks, b)
+	}
+	sort.Slice(d.banks, func(i, j int) bool { return d.banks[i].Name < d.banks[j].Name })
+
+	return d, nil
+}
+
+// Banks lists the directory sorted by name
+func (d *Directory) Banks() []Bank {
+	return d.banks
+}
+
+func (d *Directory) Lookup(code string) (Bank, bool) {
+	b, ok := d.codes[code]
+	return b, ok
+}
+
+// ValidateAccount checks accountNumber against the bank's rules and returns it normalized
+func (b Bank) ValidateAccount(accountNumber string) (string, error) {
+	if b.AccountNumber.Format == FormatIBAN {
+		iban := NormalizeIBAN(accountNumber)
+		if err := ValidateIBAN(iban); err != nil {
+			return "", err
+		}
+		if iban[:2] != b.Country || len(iban) != b.AccountNumber.Lengths[0] {
+			return "", ErrInvalidIBAN
+		}
+		return iban, nil
+	}
+
+	for _, c := range accountNumber {
+		if c < '0' || c > '9' {
+			return "", ErrInvalidAccountNumber
+		}
+	}
+	if len(b.AccountNumber.Lengths) > 0 && !containsInt(b.AccountNumber.Lengths, len(accountNumber)) {
+		return "", ErrInvalidAccountLength
+	}
+	if b.AccountNumber.Checksum == ChecksumLuhn && !luhn(accountNumber) {
+		return "", ErrInvalidAccountChecksum
+	}
+
+	return accountNumber, nil
+}
+
+// luhn checks the trailing check digit of number with the Luhn mod 10 algorithm
+func luhn(number string) bool {
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return len(number) > 0 && sum%10 == 0
+}
+
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package bank
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidIBAN = errors.New("invalid IBAN")
+
+// NormalizeIBAN drops the spaces IBANs are usually printed with and upper cases it
+func NormalizeIBAN(iban string) string {
+	return strings.ToUpper(strings.ReplaceAll(iban, " ", ""))
+}
+
+// ValidateIBAN checks the ISO 13616 mod-97 check digits of a normalized IBAN, its length depends on the
+// country and is up to the directory entry of the bank
+func ValidateIBAN(iban string) error {
+	if len(iban) < 5 {
+		return ErrInvalidIBAN
+	}
+
+	// the country code and check digits move to the end, letters count as 10 to 35
+	rearranged := iban[4:] + iban[:4]
+	remainder := 0
+	for _, c := range rearranged {
+		switch {
+		case c >= '0' && c <= '9':
+			remainder = (remainder*10 + int(c-'0')) % 97
+		case c >= 'A' && c <= 'Z':
+			remainder = (remainder*100 + int(c-'A') + 10) % 97
+		default:
+			return ErrInvalidIBAN
+		}
+	}
+	if remainder != 1 {
+		return ErrInvalidIBAN
+	}
+
+	return nil
+}
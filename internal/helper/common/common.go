@@ -19,9 +19,12 @@ type UserClaims struct {
 }
 
 type Meta struct {
-	Limit  int
-	Offset int
-	Total  int
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	// Total is left out when counting was skipped, as on later cursor pages
+	Total *int `json:"total,omitempty"`
+	// NextCursor continues keyset paginated lists, empty on the last page
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
 // regex
@@ -0,0 +1,33 @@
+package common
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// EncodeCursor turns a (created at, id) position into an opaque token for keyset pagination
+func EncodeCursor(createdAt int64, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(createdAt, 10) + ":" + id))
+}
+
+// DecodeCursor reads a token made by EncodeCursor back into its position
+func DecodeCursor(cursor string) (int64, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, "", ErrInvalidCursor
+	}
+	createdAtStr, id, ok := strings.Cut(string(raw), ":")
+	if !ok || id == "" {
+		return 0, "", ErrInvalidCursor
+	}
+	createdAt, err := strconv.ParseInt(createdAtStr, 10, 64)
+	if err != nil {
+		return 0, "", ErrInvalidCursor
+	}
+
+	return createdAt, id, nil
+}
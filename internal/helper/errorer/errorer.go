@@ -20,8 +20,104 @@ var (
 	ErrInvalidPhone     = errors.New("invalid phone number")
 	ErrInvalidImageUrl  = errors.New("invalid image url")
 	ErrAlreadyFriend    = errors.New("already friend")
+	ErrUnbalancedLedger = errors.New("unbalanced ledger journal")
+	ErrAmountOverflow   = errors.New("amount is too large")
+	ErrInvalidAmount    = errors.New("amount must be a decimal in the major unit of its currency, with no more decimals than the currency has")
+	ErrDuplicate        = errors.New("a record with the same key already exists")
+
+	ErrRecipientNotFound = errors.New("recipient not found")
+	ErrSelfTransfer      = errors.New("cannot transfer to yourself")
+
+	ErrFxRateNotFound  = errors.New("fx rate not found")
+	ErrFxQuoteNotFound = errors.New("fx quote not found")
+	ErrFxQuoteExpired  = errors.New("fx quote expired")
+	ErrFxQuoteExecuted = errors.New("fx quote already executed")
+
+	ErrTransactionNotFound      = errors.New("transaction not found")
+	ErrTransactionReversed      = errors.New("transaction already fully reversed")
+	ErrReversalExceedsOriginal  = errors.New("reversal amount exceeds what is left of the original transaction")
+	ErrTransactionNotSettled    = errors.New("transaction is not settled yet")
+	ErrReversalCurrency         = errors.New("currency does not match the original transaction")
+	ErrTransactionStatusChanged = errors.New("transaction status changed concurrently")
+
+	ErrHoldNotFound  = errors.New("hold not found")
+	ErrHoldNotActive = errors.New("hold is no longer active")
+
+	ErrReceiptInvalid = errors.New("receipt signature is not valid")
+
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrLoginThrottled     = &CodedError{Code: "LOGIN_THROTTLED", Message: "too many failed login attempts, try again later"}
+
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used, all sessions from this login are signed out")
+	ErrTokenRevoked        = errors.New("token has been revoked")
+
+	ErrMfaNotEnrolled      = errors.New("two-factor authentication enrollment not found, enroll first")
+	ErrMfaAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrMfaNotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrInvalidMfaCode      = errors.New("invalid two-factor authentication code")
+	ErrInvalidMfaChallenge = errors.New("invalid or expired mfa challenge, log in again")
+	ErrMfaLocked           = errors.New("too many wrong two-factor authentication codes, try again later")
+
+	ErrBeneficiaryNotFound   = errors.New("beneficiary not found")
+	ErrBeneficiaryExists     = errors.New("beneficiary with this account already exists")
+	ErrBeneficiaryCoolingOff = errors.New("amount exceeds the limit for a new recipient")
+
+	ErrTransferLimitNotFound       = errors.New("no transfer limit override for this currency")
+	ErrTransferLimitPerTransaction = &CodedError{Code: "TRANSFER_LIMIT_PER_TRANSACTION", Message: "amount exceeds the per transaction limit"}
+	ErrTransferLimitDaily          = &CodedError{Code: "TRANSFER_LIMIT_DAILY", Message: "amount exceeds what is left of the daily transfer limit"}
+	ErrTransferLimitMonthly        = &CodedError{Code: "TRANSFER_LIMIT_MONTHLY", Message: "amount exceeds what is left of the monthly transfer limit"}
+
+	ErrPinRequired   = &CodedError{Code: "PIN_REQUIRED", Message: "transaction pin is required"}
+	ErrPinNotSet     = &CodedError{Code: "PIN_NOT_SET", Message: "set a transaction pin before moving money"}
+	ErrInvalidPin    = &CodedError{Code: "PIN_INVALID", Message: "invalid transaction pin"}
+	ErrPinLocked     = &CodedError{Code: "PIN_LOCKED", Message: "too many wrong transaction pins, try again later"}
+	ErrWeakPin       = errors.New("transaction pin is too easy to guess")
+	ErrWrongPassword = errors.New("wrong password")
+
+	ErrStandingOrderNotFound = errors.New("standing order not found")
+	ErrStandingOrderClosed   = errors.New("standing order is completed or cancelled")
+	ErrStandingOrderChanged  = errors.New("standing order changed while it was running")
+	ErrInvalidSchedule       = errors.New("invalid schedule")
+
+	ErrTopUpNotFound   = errors.New("top up not found")
+	ErrTopUpReviewed   = errors.New("top up already reviewed")
+	ErrTopUpSelfReview = errors.New("cannot review your own top up")
+
+	ErrIdempotencyKeyReused     = errors.New("idempotency key already used with a different request")
+	ErrIdempotencyKeyInProgress = errors.New("request with this idempotency key is still in progress")
 )
 
+// CodedError is an error clients are expected to act on, Code stays stable while Message may change
+type CodedError struct {
+	Code    string
+	Message string
+}
+
+func (e *CodedError) Error() string {
+	return e.Message
+}
+
+// notExecutedError marks an error raised before the request changed anything
+type notExecutedError struct {
+	error
+}
+
+func (e *notExecutedError) Cause() error  { return e.error }
+func (e *notExecutedError) Unwrap() error { return e.error }
+
+// NotExecuted marks err as raised before anything was written, e.g. by a rolled back transaction,
+// so running the request again cannot apply it twice
+func NotExecuted(err error) error {
+	return &notExecutedError{err}
+}
+
+// IsNotExecuted reports whether err was marked by NotExecuted
+func IsNotExecuted(err error) bool {
+	var notExecuted *notExecutedError
+	return errors.As(err, &notExecuted)
+}
+
 func ErrInputRequest(err error) error {
 	return fmt.Errorf("input request error: %s", err.Error())
 }
@@ -2,22 +2,36 @@ package util
 
 import (
 	"encoding/json"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
 
 	"github.com/pkg/errors"
 )
 
+// NotExecutedRecorder is implemented by response writers that need to know a request failed before it changed anything
+type NotExecutedRecorder interface {
+	MarkNotExecuted()
+}
+
 func ResponseJSONHTTP(w http.ResponseWriter, code int, msg string, data interface{}, meta *common.Meta, err error) {
+	if recorder, ok := w.(NotExecutedRecorder); ok && errorer.IsNotExecuted(err) {
+		recorder.MarkNotExecuted()
+	}
+
 	res := map[string]interface{}{
 		"data":    data,
 		"message": strings.ToLower(http.StatusText(code)),
 	}
 	if err != nil {
 		res["message"] = errors.Cause(err).Error()
+		if coded, ok := errors.Cause(err).(*errorer.CodedError); ok {
+			res["code"] = coded.Code
+		}
 	} else {
 		if msg != "" {
 			res["message"] = msg
@@ -56,3 +70,42 @@ func GetJWTFromRequest(r *http.Request) string {
 
 	return cookie.Value
 }
+
+// ClientIP returns the address of the client, X-Forwarded-For is only believed as far back as it was
+// appended by trustedProxies, anything a client put in it ahead of them is ignored
+func ClientIP(r *http.Request, trustedProxies []*net.IPNet) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !isTrustedProxy(host, trustedProxies) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		host = hop
+		if !isTrustedProxy(hop, trustedProxies) {
+			break
+		}
+	}
+
+	return host
+}
+
+func isTrustedProxy(addr string, trustedProxies []*net.IPNet) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, v := range trustedProxies {
+		if v.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -6,20 +6,28 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func GenerateJwt(payload jwt.Claims, secret string) (string, error) {
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
-	tokenString, err := token.SignedString([]byte(secret))
+// GenerateJwt signs payload with the current signing key of keys, its kid goes in the header
+func GenerateJwt(payload jwt.Claims, keys *KeySet) (string, error) {
+	signer := keys.Signer()
+	token := jwt.NewWithClaims(signer.Method, payload)
+	token.Header["kid"] = signer.ID
+	tokenString, err := token.SignedString(signer.Private)
 	if err != nil {
 		return "", err
 	}
 	return tokenString, nil
 }
 
-func VerifyJwt(tokenString string, claims jwt.Claims, secret string) error {
+// VerifyJwt accepts tokens signed by a key of keys with the algorithm that key is meant for
+func VerifyJwt(tokenString string, claims jwt.Claims, keys *KeySet) error {
 	tkn, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
-		return []byte(secret), nil
-	})
+		kid, _ := token.Header["kid"].(string)
+		key, ok := keys.Lookup(kid)
+		if !ok || token.Method.Alg() != key.Method.Alg() {
+			return nil, errorer.ErrUnauthorized
+		}
+		return key.Public, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}))
 	if err != nil {
 		return err
 	}
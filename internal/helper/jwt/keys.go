@@ -0,0 +1,249 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrNoSigningKey       = errors.New("no private key to sign tokens with")
+	ErrUnsupportedKeyType = errors.New("only RSA and P-256 EC keys are supported")
+)
+
+// Key is one entry of a key set, Private is nil for keys that are only kept around to verify with
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	Public  crypto.PublicKey
+	// AddedAt orders the keys, the newest private key added by now signs
+	AddedAt time.Time
+}
+
+// KeySet holds the key tokens are signed with and the keys they are accepted from. A directory backed
+// set is rotated by adding a new PEM file named after the current time, the keys it replaces keep verifying
+// for the grace period after that time so tokens signed just before the rotation stay good until they expire.
+// A file named after a later time is published right away and only signs from then on, which gives clients
+// caching the key set time to pick it up first.
+type KeySet struct {
+	dir   string
+	grace time.Duration
+
+	mu     sync.RWMutex
+	signer *Key
+	keys   map[string]*Key
+}
+
+// KeyIDTimeLayout is how the file name of a key starts, with the UTC time the key was added at,
+// e.g. 20261018T120000Z.pem or 20261018T120000Z-primary.pem
+const KeyIDTimeLayout = "20060102T150405Z"
+
+// LoadKeySet reads every .pem file in dir, the kid of a key is its file name without the extension
+// and keys are ordered by the time their kid starts with, see KeyIDTimeLayout
+func LoadKeySet(dir string, grace time.Duration) (*KeySet, error) {
+	ks := &KeySet{dir: dir, grace: grace}
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// NewEphemeralKeySet signs with a P-256 key that only lives as long as the process, for local runs
+// without a key directory
+func NewEphemeralKeySet() (*KeySet, error) {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	key := &Key{
+		ID:      "ephemeral",
+		Method:  jwt.SigningMethodES256,
+		Private: private,
+		Public:  private.Public(),
+		AddedAt: time.Now(),
+	}
+	return &KeySet{signer: key, keys: map[string]*Key{key.ID: key}}, nil
+}
+
+// Reload reads the key directory again, the keys in use are kept when it fails
+func (ks *KeySet) Reload() error {
+	if ks.dir == "" {
+		return nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(ks.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	var keys []*Key
+	for _, path := range paths {
+		key, err := readKey(path)
+		if err != nil {
+			return fmt.Errorf("jwt key %s: %w", filepath.Base(path), err)
+		}
+		keys = append(keys, key)
+	}
+	signer, active, err := activeKeys(keys, ks.grace, time.Now())
+	if err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.signer = signer
+	ks.keys = active
+	return nil
+}
+
+// Signer returns the key new tokens are signed with
+func (ks *KeySet) Signer() *Key {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.signer
+}
+
+// Lookup returns the key with kid if tokens signed with it are still accepted
+func (ks *KeySet) Lookup(kid string) (*Key, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+// Keys returns the keys tokens are accepted from, newest first
+func (ks *KeySet) Keys() []*Key {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	keys := make([]*Key, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		keys = append(keys, key)
+	}
+	sortKeys(keys)
+	return keys
+}
+
+// activeKeys picks the newest private key added by now to sign with and drops keys replaced more than grace
+// before now. Keys added after now are kept without replacing anything yet.
+func activeKeys(keys []*Key, grace time.Duration, now time.Time) (*Key, map[string]*Key, error) {
+	sortKeys(keys)
+
+	var signer *Key
+	active := make(map[string]*Key, len(keys))
+	// replacedAt is when the newer key next to the one looked at was added
+	var replacedAt time.Time
+	for _, key := range keys {
+		if _, ok := active[key.ID]; ok {
+			return nil, nil, fmt.Errorf("jwt key id %q is used twice", key.ID)
+		}
+		if signer == nil && key.Private != nil && !key.AddedAt.After(now) {
+			signer = key
+		}
+		if replacedAt.IsZero() || now.Before(replacedAt.Add(grace)) {
+			active[key.ID] = key
+		}
+		if signer != nil {
+			replacedAt = key.AddedAt
+		}
+	}
+	if signer == nil {
+		return nil, nil, ErrNoSigningKey
+	}
+
+	return signer, active, nil
+}
+
+func sortKeys(keys []*Key) {
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].AddedAt.Equal(keys[j].AddedAt) {
+			return keys[i].AddedAt.After(keys[j].AddedAt)
+		}
+		return keys[i].ID > keys[j].ID
+	})
+}
+
+// keyAddedAt reads the time a key was added at from the start of its kid
+func keyAddedAt(id string) (time.Time, error) {
+	stamp, _, _ := strings.Cut(id, "-")
+	addedAt, err := time.Parse(KeyIDTimeLayout, stamp)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("key file name must start with the time it was added at as %s", KeyIDTimeLayout)
+	}
+	return addedAt, nil
+}
+
+func readKey(path string) (*Key, error) {
+	id := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	addedAt, err := keyAddedAt(id)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	key := &Key{
+		ID:      id,
+		AddedAt: addedAt,
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		key.Public, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key.Private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key.Private, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		var parsed any
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		if signer, ok := parsed.(crypto.Signer); ok {
+			key.Private = signer
+		} else if err == nil {
+			err = ErrUnsupportedKeyType
+		}
+	default:
+		err = fmt.Errorf("unexpected PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if key.Private != nil {
+		key.Public = key.Private.Public()
+	}
+
+	switch public := key.Public.(type) {
+	case *rsa.PublicKey:
+		if public.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		key.Method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		if public.Curve != elliptic.P256() {
+			return nil, ErrUnsupportedKeyType
+		}
+		key.Method = jwt.SigningMethodES256
+	default:
+		return nil, ErrUnsupportedKeyType
+	}
+
+	return key, nil
+}
@@ -0,0 +1,109 @@
+package receipt
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/skip2/go-qrcode"
+)
+
+// Receipt holds what is printed on a transaction receipt, amounts already formatted
+type Receipt struct {
+	TransactionID     string
+	Status            string
+	Amount            string
+	Currency          string
+	BankName          string
+	BankAccountNumber string
+	CreatedAt         time.Time
+	// VerifyURL is encoded in the QR code so anyone holding the receipt can check it
+	VerifyURL string
+}
+
+// Sign returns the hex HMAC-SHA256 of the transaction details a receipt vouches for
+func Sign(secret []byte, transactionID string, amount int64, currency string, createdAt int64) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s|%d|%s|%d", transactionID, amount, currency, createdAt)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature made by Sign in constant time
+func Verify(secret []byte, signature string, transactionID string, amount int64, currency string, createdAt int64) bool {
+	expected := Sign(secret, transactionID, amount, currency, createdAt)
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
+
+// VerifyURL builds the public verification link for a signed transaction
+func VerifyURL(baseURL string, transactionID string, signature string) string {
+	query := url.Values{}
+	query.Set("transactionId", transactionID)
+	query.Set("signature", signature)
+	return strings.TrimRight(baseURL, "/") + "/v1/receipts/verify?" + query.Encode()
+}
+
+// MaskAccountNumber keeps only the last four digits of an account number visible
+func MaskAccountNumber(accountNumber string) string {
+	const visible = 4
+	if len(accountNumber) <= visible {
+		return accountNumber
+	}
+	return strings.Repeat("*", len(accountNumber)-visible) + accountNumber[len(accountNumber)-visible:]
+}
+
+// Render writes r as a single page A5 PDF
+func Render(w io.Writer, r Receipt) error {
+	qr, err := qrcode.Encode(r.VerifyURL, qrcode.Medium, 256)
+	if err != nil {
+		return err
+	}
+
+	pdf := fpdf.New("P", "mm", "A5", "")
+	pdf.SetTitle("Transaction receipt "+r.TransactionID, true)
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 10, "Transaction Receipt", "", 1, "C", false, 0, "")
+	pdf.Ln(4)
+
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.CellFormat(0, 12, r.Amount+" "+r.Currency, "", 1, "C", false, 0, "")
+	pdf.Ln(4)
+
+	rows := [][2]string{
+		{"Transaction ID", r.TransactionID},
+		{"Status", r.Status},
+		{"Recipient bank", r.BankName},
+		{"Recipient account", MaskAccountNumber(r.BankAccountNumber)},
+		{"Date", r.CreatedAt.UTC().Format("2006-01-02 15:04:05") + " UTC"},
+	}
+	for _, row := range rows {
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.CellFormat(45, 8, row[0], "B", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(0, 8, row[1], "B", 1, "R", false, 0, "")
+	}
+	pdf.Ln(6)
+
+	name := "qr-" + r.TransactionID
+	pdf.RegisterImageOptionsReader(name, fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(qr))
+	pageWidth, _ := pdf.GetPageSize()
+	const qrSize = 40.0
+	pdf.ImageOptions(name, (pageWidth-qrSize)/2, pdf.GetY(), qrSize, qrSize, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.SetY(pdf.GetY() + qrSize + 2)
+	pdf.CellFormat(0, 4, "Scan the code to verify this receipt", "", 1, "C", false, 0, "")
+
+	if err := pdf.Error(); err != nil {
+		return err
+	}
+	return pdf.Output(w)
+}
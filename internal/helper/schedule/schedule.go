@@ -0,0 +1,116 @@
+package schedule
+
+import (
+	"errors"
+	"time"
+	// keeps time zones working on hosts without a zoneinfo database
+	_ "time/tzdata"
+
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	TypeOnce    = "once"
+	TypeDaily   = "daily"
+	TypeWeekly  = "weekly"
+	TypeMonthly = "monthly"
+	TypeCron    = "cron"
+)
+
+var (
+	ErrUnknownType = errors.New("unknown schedule type")
+	ErrInvalidCron = errors.New("invalid cron expression")
+)
+
+// Spec describes when something recurs. Daily, weekly and monthly schedules repeat the wall clock time of Start
+// in its location, monthly ones fall back to the last day of shorter months. Cron schedules do not run before Start.
+type Spec struct {
+	Type  string
+	Cron  string
+	Start time.Time
+}
+
+// Validate checks that the spec can produce run times
+func Validate(spec Spec) error {
+	switch spec.Type {
+	case TypeOnce, TypeDaily, TypeWeekly, TypeMonthly:
+		return nil
+	case TypeCron:
+		if _, err := cron.ParseStandard(spec.Cron); err != nil {
+			return ErrInvalidCron
+		}
+		return nil
+	default:
+		return ErrUnknownType
+	}
+}
+
+// Next returns the first run strictly after after, false when the schedule has no more runs
+func Next(spec Spec, after time.Time) (time.Time, bool) {
+	start := spec.Start
+	after = after.In(start.Location())
+
+	switch spec.Type {
+	case TypeOnce:
+		return start, start.After(after)
+	case TypeDaily:
+		return nextEvery(start, after, 1), true
+	case TypeWeekly:
+		return nextEvery(start, after, 7), true
+	case TypeMonthly:
+		return nextMonthly(start, after), true
+	case TypeCron:
+		sched, err := cron.ParseStandard(spec.Cron)
+		if err != nil {
+			return time.Time{}, false
+		}
+		if after.Before(start) {
+			after = start.Add(-time.Nanosecond)
+		}
+		next := sched.Next(after)
+		return next, !next.IsZero()
+	default:
+		return time.Time{}, false
+	}
+}
+
+// nextEvery steps from start in whole days so runs keep their wall clock time across DST changes
+func nextEvery(start time.Time, after time.Time, days int) time.Time {
+	if after.Before(start) {
+		return start
+	}
+	n := int(after.Sub(start) / (time.Duration(days) * 24 * time.Hour))
+	next := start.AddDate(0, 0, n*days)
+	for !next.After(after) {
+		n++
+		next = start.AddDate(0, 0, n*days)
+	}
+	return next
+}
+
+func nextMonthly(start time.Time, after time.Time) time.Time {
+	if after.Before(start) {
+		return start
+	}
+	n := (after.Year()-start.Year())*12 + int(after.Month()-start.Month()) - 1
+	if n < 0 {
+		n = 0
+	}
+	next := addMonths(start, n)
+	for !next.After(after) {
+		n++
+		next = addMonths(start, n)
+	}
+	return next
+}
+
+// addMonths moves t n months ahead, keeping its day unless the month is too short for it
+func addMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(first.Year(), first.Month(), day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
@@ -0,0 +1,246 @@
+package statement
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ovrrtd/openidea-bank/internal/model/money"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatOFX     = "ofx"
+	FormatCAMT053 = "camt053"
+)
+
+// BankID identifies this bank in OFX and CAMT.053 documents
+const BankID = "OPENIDEA"
+
+// Header describes the statement as a whole, balances are in minor units of Currency
+type Header struct {
+	StatementID    string
+	AccountID      string
+	AccountName    string
+	Currency       string
+	From           time.Time
+	To             time.Time
+	OpeningBalance int64
+	ClosingBalance int64
+	GeneratedAt    time.Time
+}
+
+// Entry is one booked line of the statement
+type Entry struct {
+	ID                string
+	TransactionID     string
+	Type              string
+	Amount            int64
+	BankName          string
+	BankAccountNumber string
+	CreatedAt         time.Time
+}
+
+// Writer renders a statement as its entries arrive so it never has to hold all of them
+type Writer interface {
+	WriteHeader(h Header) error
+	WriteEntry(e Entry) error
+	// Close writes what follows the entries and flushes the output
+	Close() error
+}
+
+// NewWriter returns a writer for format, which must be one of the Format constants
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case FormatOFX:
+		return &ofxWriter{w: bufio.NewWriter(w)}, nil
+	case FormatCAMT053:
+		return &camtWriter{w: bufio.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("unknown statement format %q", format)
+	}
+}
+
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatOFX:
+		return "application/x-ofx"
+	default:
+		return "application/xml"
+	}
+}
+
+// Filename names the download after the account currency and period, e.g. statement-IDR-20260601-20260630.csv
+func Filename(format string, h Header) string {
+	ext := format
+	if format == FormatCAMT053 {
+		ext = "xml"
+	}
+	return fmt.Sprintf("statement-%s-%s-%s.%s", h.Currency, h.From.Format("20060102"), h.To.Format("20060102"), ext)
+}
+
+type csvWriter struct {
+	w       *csv.Writer
+	header  Header
+	balance int64
+}
+
+func (c *csvWriter) WriteHeader(h Header) error {
+	c.header = h
+	c.balance = h.OpeningBalance
+	c.w.Write([]string{"date", "transaction_id", "type", "bank_name", "bank_account_number", "amount", "balance", "currency"})
+	return c.w.Write([]string{h.From.Format(time.RFC3339), "", "opening_balance", "", "", "", money.Format(h.OpeningBalance, h.Currency), h.Currency})
+}
+
+func (c *csvWriter) WriteEntry(e Entry) error {
+	balance, err := money.New(c.balance, c.header.Currency).Add(money.New(e.Amount, c.header.Currency))
+	if err != nil {
+		return err
+	}
+	c.balance = balance.Amount
+
+	return c.w.Write([]string{
+		e.CreatedAt.Format(time.RFC3339),
+		e.TransactionID,
+		e.Type,
+		e.BankName,
+		e.BankAccountNumber,
+		money.Format(e.Amount, c.header.Currency),
+		money.Format(c.balance, c.header.Currency),
+		c.header.Currency,
+	})
+}
+
+func (c *csvWriter) Close() error {
+	h := c.header
+	c.w.Write([]string{h.To.Format(time.RFC3339), "", "closing_balance", "", "", "", money.Format(h.ClosingBalance, h.Currency), h.Currency})
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// ofxWriter writes an OFX 2.2 bank statement response
+type ofxWriter struct {
+	w      *bufio.Writer
+	header Header
+}
+
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405.000") + "[0:UTC]"
+}
+
+func (o *ofxWriter) WriteHeader(h Header) error {
+	o.header = h
+	fmt.Fprint(o.w, `<?xml version="1.0" encoding="UTF-8" standalone="no"?>`+"\n")
+	fmt.Fprint(o.w, `<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>`+"\n")
+	fmt.Fprint(o.w, "<OFX>\n")
+	fmt.Fprintf(o.w, "<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS><DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE></SONRS></SIGNONMSGSRSV1>\n", ofxTime(h.GeneratedAt))
+	fmt.Fprintf(o.w, "<BANKMSGSRSV1><STMTTRNRS><TRNUID>%s</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n", escape(h.StatementID))
+	fmt.Fprintf(o.w, "<STMTRS><CURDEF>%s</CURDEF>\n", escape(h.Currency))
+	fmt.Fprintf(o.w, "<BANKACCTFROM><BANKID>%s</BANKID><ACCTID>%s</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>\n", BankID, escape(h.AccountID))
+	_, err := fmt.Fprintf(o.w, "<BANKTRANLIST><DTSTART>%s</DTSTART><DTEND>%s</DTEND>\n", ofxTime(h.From), ofxTime(h.To))
+	return err
+}
+
+func (o *ofxWriter) WriteEntry(e Entry) error {
+	trnType := "CREDIT"
+	if e.Amount < 0 {
+		trnType = "DEBIT"
+	}
+	_, err := fmt.Fprintf(o.w, "<STMTTRN><TRNTYPE>%s</TRNTYPE><DTPOSTED>%s</DTPOSTED><TRNAMT>%s</TRNAMT><FITID>%s</FITID><NAME>%s</NAME><MEMO>%s</MEMO></STMTTRN>\n",
+		trnType, ofxTime(e.CreatedAt), money.Format(e.Amount, o.header.Currency), escape(e.ID), escape(e.Type), escape(memo(e)))
+	return err
+}
+
+func (o *ofxWriter) Close() error {
+	h := o.header
+	fmt.Fprint(o.w, "</BANKTRANLIST>\n")
+	fmt.Fprintf(o.w, "<LEDGERBAL><BALAMT>%s</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>\n", money.Format(h.ClosingBalance, h.Currency), ofxTime(h.To))
+	// OFX has no opening balance element, it goes in the balance list instead
+	fmt.Fprintf(o.w, "<BALLIST><BAL><NAME>Opening balance</NAME><DESC>Balance at the start of the statement</DESC><BALTYPE>DOLLAR</BALTYPE><VALUE>%s</VALUE><DTASOF>%s</DTASOF></BAL></BALLIST>\n",
+		money.Format(h.OpeningBalance, h.Currency), ofxTime(h.From))
+	fmt.Fprint(o.w, "</STMTRS></STMTTRNRS></BANKMSGSRSV1>\n</OFX>\n")
+	return o.w.Flush()
+}
+
+// camtWriter writes an ISO 20022 camt.053.001.02 bank to customer statement
+type camtWriter struct {
+	w      *bufio.Writer
+	header Header
+}
+
+func camtTime(t time.Time) string {
+	return t.Format("2006-01-02T15:04:05.000Z07:00")
+}
+
+// camtAmount splits a signed amount into the unsigned amount and credit/debit indicator CAMT expects
+func camtAmount(amount int64, currency string) (string, string) {
+	indicator := "CRDT"
+	formatted := money.Format(amount, currency)
+	if amount < 0 {
+		indicator = "DBIT"
+		formatted = formatted[1:]
+	}
+	return formatted, indicator
+}
+
+func (c *camtWriter) balance(code string, amount int64, at time.Time) {
+	amt, indicator := camtAmount(amount, c.header.Currency)
+	fmt.Fprintf(c.w, `<Bal><Tp><CdOrPrtry><Cd>%s</Cd></CdOrPrtry></Tp><Amt Ccy="%s">%s</Amt><CdtDbtInd>%s</CdtDbtInd><Dt><DtTm>%s</DtTm></Dt></Bal>`+"\n",
+		code, escape(c.header.Currency), amt, indicator, camtTime(at))
+}
+
+func (c *camtWriter) WriteHeader(h Header) error {
+	c.header = h
+	fmt.Fprint(c.w, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprint(c.w, `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">`+"\n<BkToCstmrStmt>\n")
+	fmt.Fprintf(c.w, "<GrpHdr><MsgId>%s</MsgId><CreDtTm>%s</CreDtTm></GrpHdr>\n", escape(h.StatementID), camtTime(h.GeneratedAt))
+	fmt.Fprintf(c.w, "<Stmt><Id>%s</Id><CreDtTm>%s</CreDtTm><FrToDt><FrDtTm>%s</FrDtTm><ToDtTm>%s</ToDtTm></FrToDt>\n",
+		escape(h.StatementID), camtTime(h.GeneratedAt), camtTime(h.From), camtTime(h.To))
+	fmt.Fprintf(c.w, "<Acct><Id><Othr><Id>%s</Id></Othr></Id><Ccy>%s</Ccy><Ownr><Nm>%s</Nm></Ownr><Svcr><FinInstnId><Othr><Id>%s</Id></Othr></FinInstnId></Svcr></Acct>\n",
+		escape(h.AccountID), escape(h.Currency), escape(h.AccountName), BankID)
+	c.balance("OPBD", h.OpeningBalance, h.From)
+	c.balance("CLBD", h.ClosingBalance, h.To)
+	return nil
+}
+
+func (c *camtWriter) WriteEntry(e Entry) error {
+	amt, indicator := camtAmount(e.Amount, c.header.Currency)
+	_, err := fmt.Fprintf(c.w, `<Ntry><NtryRef>%s</NtryRef><Amt Ccy="%s">%s</Amt><CdtDbtInd>%s</CdtDbtInd><Sts>BOOK</Sts><BookgDt><DtTm>%s</DtTm></BookgDt><ValDt><DtTm>%s</DtTm></ValDt><AcctSvcrRef>%s</AcctSvcrRef><BkTxCd><Prtry><Cd>%s</Cd><Issr>%s</Issr></Prtry></BkTxCd><NtryDtls><TxDtls><Refs><EndToEndId>%s</EndToEndId></Refs><AddtlTxInf>%s</AddtlTxInf></TxDtls></NtryDtls></Ntry>`+"\n",
+		escape(e.ID), escape(c.header.Currency), amt, indicator, camtTime(e.CreatedAt), camtTime(e.CreatedAt),
+		escape(e.TransactionID), escape(e.Type), BankID, escape(endToEndID(e.TransactionID)), escape(memo(e)))
+	return err
+}
+
+func (c *camtWriter) Close() error {
+	fmt.Fprint(c.w, "</Stmt>\n</BkToCstmrStmt>\n</Document>\n")
+	return c.w.Flush()
+}
+
+// endToEndID falls back to the ISO 20022 placeholder for entries without a transaction id
+func endToEndID(transactionID string) string {
+	if transactionID == "" {
+		return "NOTPROVIDED"
+	}
+	return transactionID
+}
+
+func memo(e Entry) string {
+	if e.BankAccountNumber == "" {
+		return e.BankName
+	}
+	return e.BankName + " " + e.BankAccountNumber
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,34 @@
+package entity
+
+const (
+	AuditActionTransactionReversed = "transaction.reversed"
+	AuditActionTopUpApproved       = "topup.approved"
+	AuditActionTopUpRejected       = "topup.rejected"
+	AuditActionTransferLimitSet    = "transfer_limit.set"
+	AuditActionTransferLimitReset  = "transfer_limit.reset"
+	AuditActionMfaEnabled          = "mfa.enabled"
+	AuditActionMfaDisabled         = "mfa.disabled"
+	AuditActionMfaRecoveryReissued = "mfa.recovery_codes_reissued"
+	AuditActionMfaLocked           = "mfa.locked"
+	AuditActionPinSet              = "pin.set"
+	AuditActionPinLocked           = "pin.locked"
+	AuditActionLoginUnlocked       = "login.unlocked"
+	AuditActionPasswordFailed      = "password.failed"
+)
+
+const (
+	AuditTargetTransaction = "transaction"
+	AuditTargetTopUp       = "topup"
+	AuditTargetUser        = "user"
+)
+
+type AuditLog struct {
+	ID         string
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	Reason     string
+	Metadata   map[string]interface{}
+	CreatedAt  int64
+}
@@ -1,36 +1,116 @@
 package entity
 
+const (
+	BalanceHistoryTypeTopUp       = "topup"
+	BalanceHistoryTypeTransaction = "transaction"
+	BalanceHistoryTypeTransferIn  = "transfer_in"
+	BalanceHistoryTypeTransferOut = "transfer_out"
+	BalanceHistoryTypeFxIn        = "fx_in"
+	BalanceHistoryTypeFxOut       = "fx_out"
+	BalanceHistoryTypeReversal    = "reversal"
+	BalanceHistoryTypeHoldCapture = "hold_capture"
+	BalanceHistoryTypeRefund      = "refund"
+	// a rejected top up is recorded with a zero balance change
+	BalanceHistoryTypeTopUpRejected = "topup_rejected"
+)
+
+const (
+	ReversalStatusPartial = "partially_reversed"
+	ReversalStatusFull    = "reversed"
+)
+
+// bank name recorded on history entries of transfers between our own users
+const InternalBankName = "internal"
+
 type Balance struct {
 	ID       string
 	UserID   string
-	Balance  int
+	Balance  int64 // total, including held funds
+	Held     int64
 	Currency string
 }
 
+// Available is the part of the balance that is not reserved by holds
+func (b Balance) Available() int64 {
+	return b.Balance - b.Held
+}
+
 type BalanceHistory struct {
 	ID                      string
 	TransactionID           string // nullable
 	UserID                  string
-	Balance                 int
+	Balance                 int64
 	Currency                string
 	ProofImageURL           string
 	CreatedAt               int64
 	SourceBankAccountNumber string
 	SourceBankName          string
+	Type                    string
+	CounterpartyUserID      string // nullable
+	FxRate                  string // nullable, rate applied on currency conversions
+	ReversedAmount          int64  // how much of a debit has been refunded so far
+	ReversalOf              string // nullable, transaction id a reversal entry compensates
+	BalanceBefore           *int64 // nullable until backfilled on entries older than the column
+	BalanceAfter            *int64
+	StandingOrderID         string // nullable, set on transactions a standing order made
 }
 
-type UpsertBalance struct {
-	SenderBankAccountNumber string
-	SenderBankName          string
-	Balance                 int
-	Currency                string
-	ProofImageURL           string
-	UserID                  string
-	TransactionID           string
+// ReversalStatus tells whether a debit entry has been refunded, partially or fully
+func (h BalanceHistory) ReversalStatus() string {
+	switch {
+	case h.ReversedAmount == 0:
+		return ""
+	case h.ReversedAmount < -h.Balance:
+		return ReversalStatusPartial
+	default:
+		return ReversalStatusFull
+	}
+}
+
+type Transfer struct {
+	TransactionID string
+	SenderID      string
+	RecipientID   string
+	Currency      string
+	Amount        int64
+	TransferLimit TransferLimitCheck // the sender's limit in the currency
 }
 
+type Reversal struct {
+	TransactionID         string
+	OriginalTransactionID string
+	UserID                string
+	Amount                int64 // zero reverses whatever is left of the original
+	Currency              string
+	ReversedAmount        int64 // total refunded on the original including this reversal
+	OriginalAmount        int64
+	Reason                string
+	ActorID               string
+	CreatedAt             int64
+}
+
+const (
+	BalanceHistoryDirectionCredit = "credit"
+	BalanceHistoryDirectionDebit  = "debit"
+)
+
 type GetBalancesHistory struct {
 	Limit  int
 	Offset int
 	UserID string
+	// AfterCreatedAt and AfterID continue the listing after that entry, the offset is ignored then
+	AfterCreatedAt int64
+	AfterID        string
+	// CountTotal also counts all matches, which costs a scan of them
+	CountTotal bool
+
+	// filters, zero values match everything
+	Currency      string
+	Direction     string
+	From          int64 // created at, inclusive
+	To            int64 // created at, inclusive
+	MinAmount     int64 // absolute amount in minor units
+	MaxAmount     int64
+	BankName      string
+	TransactionID string
 }
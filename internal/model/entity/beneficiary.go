@@ -0,0 +1,20 @@
+package entity
+
+// Beneficiary is a saved recipient account, its account details never change once added
+type Beneficiary struct {
+	ID                string
+	UserID            string
+	Nickname          string
+	BankAccountNumber string
+	BankName          string
+	IsFavorite        bool
+	CreatedAt         int64
+	UpdatedAt         int64
+}
+
+// BeneficiaryCoolingOff caps the total sent since Since, per currency, to a recipient account that was
+// neither saved as a beneficiary nor sent to before Since
+type BeneficiaryCoolingOff struct {
+	Since int64
+	Limit int64
+}
@@ -0,0 +1,29 @@
+package entity
+
+type FxRate struct {
+	BaseCurrency  string
+	QuoteCurrency string
+	Rate          string // decimal, units of quote currency per unit of base currency
+	UpdatedAt     int64
+}
+
+type FxQuote struct {
+	ID            string
+	UserID        string
+	FromCurrency  string
+	ToCurrency    string
+	FromAmount    int64
+	ToAmount      int64
+	Rate          string
+	CreatedAt     int64
+	ExpiresAt     int64
+	ExecutedAt    int64 // nullable
+	TransactionID string
+}
+
+type FxConversion struct {
+	QuoteID       string
+	UserID        string
+	TransactionID string
+	ExecutedAt    int64
+}
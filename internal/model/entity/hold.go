@@ -0,0 +1,30 @@
+package entity
+
+const (
+	HoldStatusActive   = "active"
+	HoldStatusCaptured = "captured"
+	HoldStatusReleased = "released"
+	HoldStatusExpired  = "expired"
+)
+
+type Hold struct {
+	ID            string
+	UserID        string
+	BalanceID     string
+	Amount        int64
+	Currency      string
+	Status        string
+	Description   string
+	TransactionID string // nullable, set once captured
+	ExpiresAt     int64
+	CreatedAt     int64
+	UpdatedAt     int64
+}
+
+type SettleHold struct {
+	HoldID        string
+	UserID        string
+	TransactionID string
+	SettledAt     int64
+	TransferLimit TransferLimitCheck // only checked on capture
+}
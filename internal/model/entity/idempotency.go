@@ -0,0 +1,11 @@
+package entity
+
+type IdempotencyKey struct {
+	UserID      string
+	Key         string
+	RequestHash string
+	StatusCode  int // zero while the first request is still in flight
+	Response    []byte
+	CreatedAt   int64
+	ExpiresAt   int64
+}
@@ -0,0 +1,91 @@
+package entity
+
+const (
+	PostingDirectionDebit  = "debit"
+	PostingDirectionCredit = "credit"
+)
+
+const (
+	LedgerAccountTypeAsset     = "asset"
+	LedgerAccountTypeLiability = "liability"
+	LedgerAccountTypeEquity    = "equity"
+	LedgerAccountTypeRevenue   = "revenue"
+)
+
+// system ledger accounts, one per currency
+const (
+	LedgerAccountExternalBankInflow  = "system:external_bank_inflow"
+	LedgerAccountExternalBankOutflow = "system:external_bank_outflow"
+	LedgerAccountFees                = "system:fees"
+	LedgerAccountOpeningBalance      = "system:opening_balance"
+	LedgerAccountFxPosition          = "system:fx_position"
+	LedgerAccountPayoutClearing      = "system:payout_clearing"
+)
+
+type LedgerAccount struct {
+	ID        string
+	Code      string
+	UserID    string // nullable, only set for wallet accounts
+	Currency  string
+	Type      string
+	CreatedAt int64
+}
+
+type Journal struct {
+	ID            string
+	TransactionID string
+	ReferenceID   string
+	Description   string
+	CreatedAt     int64
+	Postings      []Posting
+}
+
+type Posting struct {
+	ID        string
+	JournalID string
+	// AccountCode and UserID identify the ledger account, it is opened on first use
+	AccountCode string
+	AccountType string
+	UserID      string
+	Direction   string
+	Amount      int64
+	Currency    string
+}
+
+// WalletAccountCode returns the ledger account code backing a user's balance
+func WalletAccountCode(userID string) string {
+	return "wallet:" + userID
+}
+
+// WalletPosting books amount against the user's wallet, a positive amount credits it
+func WalletPosting(userID string, currency string, amount int64) Posting {
+	p := Posting{
+		AccountCode: WalletAccountCode(userID),
+		AccountType: LedgerAccountTypeLiability,
+		UserID:      userID,
+		Direction:   PostingDirectionCredit,
+		Amount:      amount,
+		Currency:    currency,
+	}
+	if amount < 0 {
+		p.Direction = PostingDirectionDebit
+		p.Amount = -amount
+	}
+	return p
+}
+
+// SystemPosting books amount against a system account, a positive amount debits it
+func SystemPosting(code string, accountType string, currency string, amount int64) Posting {
+	p := Posting{
+		AccountCode: code,
+		AccountType: accountType,
+		Direction:   PostingDirectionDebit,
+		Amount:      amount,
+		Currency:    currency,
+	}
+	if amount < 0 {
+		p.Direction = PostingDirectionCredit
+		p.Amount = -amount
+	}
+	return p
+}
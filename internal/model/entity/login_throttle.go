@@ -0,0 +1,65 @@
+package entity
+
+import "time"
+
+const (
+	LoginThrottleScopeAccount = "account"
+	LoginThrottleScopeIP      = "ip"
+)
+
+// LoginThrottle lets FreeAttempts failed logins through, then blocks for BackoffBase doubling with
+// every further failure, and locks out for Lockout once MaxAttempts is reached
+type LoginThrottle struct {
+	FreeAttempts int
+	MaxAttempts  int
+	BackoffBase  time.Duration
+	Lockout      time.Duration
+}
+
+// Delay returns how long logins are blocked after the given number of failures
+func (t LoginThrottle) Delay(failures int) time.Duration {
+	if failures >= t.MaxAttempts {
+		return t.Lockout
+	}
+	if failures <= t.FreeAttempts {
+		return 0
+	}
+	exp := failures - t.FreeAttempts - 1
+	if exp >= 30 {
+		return t.Lockout
+	}
+	if delay := t.BackoffBase << exp; delay < t.Lockout {
+		return delay
+	}
+	return t.Lockout
+}
+
+// LoginThrottleKey names a failed login counter, Key is the normalized email or the client ip
+type LoginThrottleKey struct {
+	Scope string
+	Key   string
+}
+
+// LoginThrottleCounter is a counter after an attempt was claimed on it, BlockedUntil is the block
+// the claim put in place in case the attempt fails, 0 when it did not need one
+type LoginThrottleCounter struct {
+	Key            LoginThrottleKey
+	FailedAttempts int
+	BlockedUntil   int64
+}
+
+// ClaimLoginAttempt counts a login as failed on each key before the password is checked, Throttles
+// holds the throttle of each scope. Failures before WindowStart are forgotten.
+type ClaimLoginAttempt struct {
+	Keys        []LoginThrottleKey
+	Throttles   map[string]LoginThrottle
+	Now         int64
+	WindowStart int64
+}
+
+type UnlockLogin struct {
+	UserID     string
+	AdminID    string
+	Email      string
+	UnlockedAt int64
+}
@@ -0,0 +1,53 @@
+package entity
+
+// UserMfa is the TOTP enrollment of a user, Secret is sealed and EnabledAt is 0 until it is confirmed.
+// LockedUntil is set once too many wrong codes were entered.
+type UserMfa struct {
+	UserID         string
+	Secret         string
+	EnabledAt      int64 // nullable
+	LastUsedStep   int64
+	FailedAttempts int
+	LockedUntil    int64 // nullable
+	CreatedAt      int64
+	UpdatedAt      int64
+}
+
+// ClaimMfaAttempt takes one of the MaxAttempts a user has to enter a code of their enabled 2FA
+type ClaimMfaAttempt struct {
+	UserID      string
+	MaxAttempts int
+	Now         int64
+}
+
+type MfaRecoveryCode struct {
+	ID        string
+	UserID    string
+	CodeHash  string
+	CreatedAt int64
+	UsedAt    int64 // nullable
+}
+
+// MfaChallenge stands in for the access token between a correct password and a correct second factor
+type MfaChallenge struct {
+	ID        string
+	UserID    string
+	TokenHash string
+	Attempts  int
+	ExpiresAt int64
+	CreatedAt int64
+}
+
+// EnableMfa confirms an enrollment with the code of Step and hands out the first recovery codes
+type EnableMfa struct {
+	UserID        string
+	Step          int64
+	RecoveryCodes []MfaRecoveryCode
+	EnabledAt     int64
+}
+
+type ReplaceMfaRecoveryCodes struct {
+	UserID        string
+	RecoveryCodes []MfaRecoveryCode
+	CreatedAt     int64
+}
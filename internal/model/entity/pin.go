@@ -0,0 +1,19 @@
+package entity
+
+// UserPin is the transaction PIN of a user, LockedUntil is set once too many wrong PINs were entered
+type UserPin struct {
+	UserID         string
+	PinHash        string
+	FailedAttempts int
+	LockedUntil    int64 // nullable
+	CreatedAt      int64
+	UpdatedAt      int64
+}
+
+// ClaimPinAttempt takes one of the MaxAttempts a user has to enter their PIN before it locks until LockUntil
+type ClaimPinAttempt struct {
+	UserID      string
+	MaxAttempts int
+	Now         int64
+	LockUntil   int64
+}
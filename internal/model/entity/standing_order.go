@@ -0,0 +1,63 @@
+package entity
+
+const (
+	StandingOrderStatusActive    = "active"
+	StandingOrderStatusPaused    = "paused"
+	StandingOrderStatusCompleted = "completed"
+	StandingOrderStatusFailed    = "failed"
+	StandingOrderStatusCancelled = "cancelled"
+)
+
+// StandingOrder repeats an outbound transaction on a schedule
+type StandingOrder struct {
+	ID                string
+	UserID            string
+	Description       string
+	ScheduleType      string
+	CronExpression    string
+	Timezone          string
+	StartAt           int64
+	Amount            int64
+	Currency          string
+	BankAccountNumber string
+	BankName          string
+	BeneficiaryID     string // nullable
+	Status            string
+	NextRunAt         int64 // nullable, unset once there is nothing left to run
+	Attempts          int
+	LastRunAt         int64  // nullable
+	LastTransactionID string // nullable
+	LastError         string
+	LastFailedAt      int64 // nullable
+	CreatedAt         int64
+	UpdatedAt         int64
+}
+
+type ClaimStandingOrders struct {
+	Now int64
+	// LeaseUntil hides claimed orders from other schedulers until then
+	LeaseUntil int64
+	Limit      int
+}
+
+// ExecuteStandingOrder books the transaction of a due run and moves the order on to its next run
+type ExecuteStandingOrder struct {
+	OrderID string
+	// LeasedUntil is the next run at set when claiming, the run only goes ahead while the lease is still ours
+	LeasedUntil int64
+	Transaction Transaction
+	Checks      OutboundChecks
+	NextRunAt   int64
+	Status      string
+}
+
+// FailStandingOrder records a failed run, it is retried at NextRunAt
+type FailStandingOrder struct {
+	OrderID     string
+	LeasedUntil int64
+	Attempts    int
+	NextRunAt   int64
+	Status      string
+	Error       string
+	FailedAt    int64
+}
@@ -0,0 +1,30 @@
+package entity
+
+// RefreshToken is stored by the hash of its value, the value itself only ever reaches the client
+type RefreshToken struct {
+	ID        string
+	FamilyID  string
+	UserID    string
+	TokenHash string
+	ExpiresAt int64
+	CreatedAt int64
+	UsedAt    int64 // nullable
+	RevokedAt int64 // nullable
+}
+
+// RotateRefreshToken trades the token with TokenHash for Next, which joins its family
+type RotateRefreshToken struct {
+	TokenHash string
+	Next      RefreshToken
+}
+
+type RevokeRefreshToken struct {
+	TokenHash string
+	UserID    string
+	RevokedAt int64
+}
+
+type RevokeAccessToken struct {
+	JTI       string
+	ExpiresAt int64
+}
@@ -0,0 +1,36 @@
+package entity
+
+const (
+	TopUpStatusPendingReview = "pending_review"
+	TopUpStatusApproved      = "approved"
+	TopUpStatusRejected      = "rejected"
+)
+
+type TopUp struct {
+	ID                      string
+	UserID                  string
+	Amount                  int64
+	Currency                string
+	ProofImageURL           string
+	SenderBankAccountNumber string
+	SenderBankName          string
+	Status                  string
+	ReviewerID              string // nullable
+	RejectionReason         string
+	ReviewedAt              int64 // nullable
+	CreatedAt               int64
+}
+
+type ReviewTopUp struct {
+	TopUpID    string
+	ReviewerID string
+	Approve    bool
+	Reason     string
+	ReviewedAt int64
+}
+
+type GetTopUps struct {
+	Status string
+	Limit  int
+	Offset int
+}
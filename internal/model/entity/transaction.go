@@ -0,0 +1,65 @@
+package entity
+
+const (
+	TransactionStatusPending    = "pending"
+	TransactionStatusProcessing = "processing"
+	TransactionStatusCompleted  = "completed"
+	TransactionStatusFailed     = "failed"
+	TransactionStatusReturned   = "returned"
+	// the bank stopped answering about a transfer in flight, it has to be looked into by hand
+	TransactionStatusUnresolved = "unresolved"
+)
+
+// Transaction is a transfer out to an external bank account
+type Transaction struct {
+	ID                string
+	UserID            string
+	Amount            int64
+	Currency          string
+	BankAccountNumber string
+	BankName          string
+	Status            string
+	GatewayReference  string // nullable, set once the bank accepted the transfer
+	FailureReason     string
+	Attempts          int
+	PollErrors        int // status checks in a row the bank did not answer
+	NextAttemptAt     int64
+	StandingOrderID   string // nullable
+	BeneficiaryID     string // nullable
+	SettledAt         int64  // nullable, when the bank reported it completed
+	CreatedAt         int64
+	UpdatedAt         int64
+}
+
+// Refunded reports whether the wallet got the money back because the bank did not deliver it
+func (t Transaction) Refunded() bool {
+	return t.Status == TransactionStatusFailed || t.Status == TransactionStatusReturned
+}
+
+type ClaimTransactions struct {
+	Status string
+	Now    int64
+	// LeaseUntil hides claimed transactions from other workers until then
+	LeaseUntil int64
+	Limit      int
+	// SettledAfter only claims transactions settled since then, zero claims regardless
+	SettledAfter int64
+}
+
+type UpdateTransactionStatus struct {
+	ID               string
+	FromStatus       string
+	ToStatus         string
+	GatewayReference string
+	FailureReason    string
+	Attempts         int
+	PollErrors       int
+	NextAttemptAt    int64
+	UpdatedAt        int64
+}
+
+// GatewayResult is what a bank reports about a submitted transfer
+type GatewayResult struct {
+	Status        string
+	FailureReason string
+}
@@ -0,0 +1,46 @@
+package entity
+
+// TransferLimit caps what a user sends out of their wallet in one currency, a zero amount leaves that cap off.
+// Bank transactions, transfers to other users and captured holds all count against it.
+type TransferLimit struct {
+	UserID         string
+	Currency       string
+	PerTransaction int64
+	Daily          int64
+	Monthly        int64
+	UpdatedAt      int64
+}
+
+// TransferUsage is what a user sent out in one currency since the start of the day and of the month
+type TransferUsage struct {
+	Currency string
+	Daily    int64
+	Monthly  int64
+}
+
+// TransferLimitCheck is the limit a debit has to stay within, with the periods it counts over
+type TransferLimitCheck struct {
+	Limit      TransferLimit
+	DayStart   int64
+	MonthStart int64
+}
+
+// OutboundChecks are the caps enforced when debiting the wallet for an outbound transaction
+type OutboundChecks struct {
+	CoolingOff    BeneficiaryCoolingOff
+	TransferLimit TransferLimitCheck
+}
+
+type UpsertTransferLimits struct {
+	UserID    string
+	AdminID   string
+	Limits    []TransferLimit
+	UpdatedAt int64
+}
+
+type DeleteTransferLimit struct {
+	UserID    string
+	AdminID   string
+	Currency  string
+	DeletedAt int64
+}
@@ -1,8 +1,15 @@
 package entity
 
+const (
+	UserRoleUser  = "user"
+	UserRoleAdmin = "admin"
+)
+
 type User struct {
 	ID       string
 	Name     string
 	Email    string
+	Phone    string // nullable
 	Password string
+	Role     string
 }
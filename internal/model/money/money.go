@@ -0,0 +1,159 @@
+package money
+
+import (
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrOverflow         = errors.New("amount overflow")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrInvalidAmount    = errors.New("invalid amount")
+)
+
+// exponents lists ISO 4217 currencies whose minor unit is not 2 decimals
+var exponents = map[string]int{
+	// no minor unit
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	// funds and precious metals have no minor unit defined
+	"XAG": 0, "XAU": 0, "XBA": 0, "XBB": 0, "XBC": 0, "XBD": 0, "XDR": 0, "XPD": 0,
+	"XPT": 0, "XSU": 0, "XTS": 0, "XUA": 0, "XXX": 0,
+	// three decimals
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	// four decimals
+	"CLF": 4, "UYW": 4,
+}
+
+// Exponent returns the number of decimals of currency's minor unit
+func Exponent(currency string) int {
+	if e, ok := exponents[strings.ToUpper(currency)]; ok {
+		return e
+	}
+	return 2
+}
+
+// Money is an amount in the minor unit of its currency, e.g. cents for USD
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Add returns m + o, failing on different currencies or when the sum does not fit in int64
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	sum := m.Amount + o.Amount
+	if (o.Amount > 0 && sum < m.Amount) || (o.Amount < 0 && sum > m.Amount) {
+		return Money{}, ErrOverflow
+	}
+	return Money{Amount: sum, Currency: m.Currency}, nil
+}
+
+// Sub returns m - o with the same checks as Add
+func (m Money) Sub(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	diff := m.Amount - o.Amount
+	if (o.Amount > 0 && diff > m.Amount) || (o.Amount < 0 && diff < m.Amount) {
+		return Money{}, ErrOverflow
+	}
+	return Money{Amount: diff, Currency: m.Currency}, nil
+}
+
+// Convert turns m into currency at rate units of currency per major unit of m, rounding down
+func (m Money) Convert(currency string, rate *big.Rat) (Money, error) {
+	r := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount), rate)
+	shift := Exponent(currency) - Exponent(m.Currency)
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(shift))), nil))
+	if shift >= 0 {
+		r.Mul(r, scale)
+	} else {
+		r.Quo(r, scale)
+	}
+
+	amount := new(big.Int).Quo(r.Num(), r.Denom())
+	if !amount.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+	return Money{Amount: amount.Int64(), Currency: currency}, nil
+}
+
+// Format renders a minor unit amount of currency as a decimal string
+func Format(amount int64, currency string) string {
+	exp := Exponent(currency)
+	neg := amount < 0
+	magnitude := uint64(amount)
+	if neg {
+		// works for math.MinInt64 too, its two's complement is its magnitude
+		magnitude = uint64(-amount)
+	}
+	digits := strconv.FormatUint(magnitude, 10)
+
+	if exp > 0 {
+		if len(digits) <= exp {
+			digits = strings.Repeat("0", exp-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+	}
+	if neg {
+		return "-" + digits
+	}
+	return digits
+}
+
+// Parse reads a decimal string in major units of currency, with at most one leading sign, into minor units
+func Parse(s string, currency string) (Money, error) {
+	exp := Exponent(currency)
+	s = strings.TrimSpace(s)
+	neg := false
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if !isDigits(whole) || (frac != "" && !isDigits(frac)) || len(frac) > exp {
+		return Money{}, ErrInvalidAmount
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+
+	amount, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return Money{}, ErrOverflow
+		}
+		return Money{}, ErrInvalidAmount
+	}
+	if neg {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,22 @@
+package request
+
+import "encoding/json"
+
+// Amount is a money amount in the major unit of the currency sent along with it, e.g. "12.50" for USD.
+// Both JSON strings and numbers are read so clients sending whole numbers keep meaning major units.
+type Amount string
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*a = Amount(s)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*a = Amount(n.String())
+	return nil
+}
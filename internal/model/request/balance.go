@@ -1,24 +1,86 @@
 package request
 
 type AddBalance struct {
-	BankAccountNumber string `json:"senderBankAccountNumber" validate:"required,min=5,max=30"`
-	BankName          string `json:"senderBankName" validate:"required,min=3,max=30"`
-	Balance           int    `json:"addedBalance" validate:"required,min=0"`
+	// BankAccountNumber is checked against the rules of the bank BankCode picks from the bank directory,
+	// IBANs may be written with spaces
+	BankAccountNumber string `json:"senderBankAccountNumber" validate:"required,min=5,max=42"`
+	BankCode          string `json:"senderBankCode" validate:"required,max=11"`
+	Balance           Amount `json:"addedBalance" validate:"required,max=32"`
 	Currency          string `json:"currency" validate:"required,iso4217"`
 	ProofImageURL     string `json:"transferProofImg" validate:"required,url"`
 	UserID            string
 }
 
 type GetBalancesHistory struct {
-	Limit  int
-	Offset int
-	UserID string
+	Limit     int    `validate:"min=0"`
+	Offset    int    `validate:"min=0"`
+	Currency  string `validate:"required_with=MinAmount MaxAmount,omitempty,iso4217"`
+	Direction string `validate:"omitempty,oneof=credit debit"`
+	From      int64  `validate:"min=0"`
+	To        int64  `validate:"omitempty,gtefield=From"`
+	// MinAmount and MaxAmount are absolute amounts in Currency
+	MinAmount     Amount `validate:"max=32"`
+	MaxAmount     Amount `validate:"max=32"`
+	BankName      string `validate:"max=30"`
+	TransactionID string
+	// Cursor is the nextCursor of a previous page, it replaces Offset
+	Cursor string
+	// WithTotal counts the matches on cursor pages too, the first page always counts them
+	WithTotal bool
+	UserID    string
 }
 
 type CreateTransaction struct {
-	BankAccountNumber string `json:"recipientBankAccountNumber" validate:"required,min=5,max=30"`
-	BankName          string `json:"recipientBankName" validate:"required,min=3,max=30"`
-	Currency          string `json:"fromCurrency" validate:"required,iso4217"`
-	Balance           int    `json:"balances" validate:"required"`
-	UserID            string
+	// BeneficiaryID sends to a saved beneficiary instead of the recipient account given inline
+	BeneficiaryID     string `json:"beneficiaryId"`
+	BankAccountNumber string `json:"recipientBankAccountNumber" validate:"required_without=BeneficiaryID,excluded_with=BeneficiaryID,omitempty,min=5,max=42"`
+	BankCode          string `json:"recipientBankCode" validate:"required_without=BeneficiaryID,excluded_with=BeneficiaryID,omitempty,max=11"`
+	// BankName is filled in from the bank directory or the beneficiary
+	BankName string `json:"-"`
+	Currency string `json:"fromCurrency" validate:"required,iso4217"`
+	Balance  Amount `json:"balances" validate:"required,max=32"`
+	UserID   string
+}
+
+type CreateTransfer struct {
+	RecipientUserID string `json:"recipientUserId"`
+	RecipientEmail  string `json:"recipientEmail" validate:"omitempty,email"`
+	RecipientPhone  string `json:"recipientPhone"`
+	Currency        string `json:"currency" validate:"required,iso4217"`
+	Balance         Amount `json:"balances" validate:"required,max=32"`
+	UserID          string
+}
+
+type ReverseTransaction struct {
+	TransactionID string `validate:"required"`
+	// Amount is in Currency, which has to be the currency of the original, empty reverses whatever is left
+	Amount   Amount `json:"amount" validate:"max=32"`
+	Currency string `json:"currency" validate:"required_with=Amount,omitempty,iso4217"`
+	Reason   string `json:"reason" validate:"required,min=5,max=255"`
+	ActorID  string
+}
+
+type GetTransaction struct {
+	TransactionID string `validate:"required"`
+	UserID        string
+}
+
+type GetBalancesAt struct {
+	UserID string `validate:"required"`
+	// At is an RFC3339 instant, e.g. 2026-06-30T23:59:00+07:00
+	At string `validate:"required"`
+}
+
+type GetStatement struct {
+	UserID string `validate:"required"`
+	// From and To are RFC3339 instants, both inclusive
+	From     string `validate:"required"`
+	To       string `validate:"required"`
+	Currency string `validate:"required,iso4217"`
+	Format   string `validate:"required,oneof=csv ofx camt053"`
+}
+
+type VerifyReceipt struct {
+	TransactionID string `validate:"required"`
+	Signature     string `validate:"required,hexadecimal"`
 }
@@ -0,0 +1,21 @@
+package request
+
+type CreateBeneficiary struct {
+	Nickname          string `json:"nickname" validate:"max=50"`
+	BankAccountNumber string `json:"bankAccountNumber" validate:"required,min=5,max=42"`
+	BankCode          string `json:"bankCode" validate:"required,max=11"`
+	IsFavorite        bool   `json:"isFavorite"`
+	UserID            string
+}
+
+type UpdateBeneficiary struct {
+	BeneficiaryID string `validate:"required"`
+	Nickname      string `json:"nickname" validate:"max=50"`
+	IsFavorite    bool   `json:"isFavorite"`
+	UserID        string
+}
+
+type GetBeneficiary struct {
+	BeneficiaryID string `validate:"required"`
+	UserID        string
+}
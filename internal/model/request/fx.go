@@ -0,0 +1,23 @@
+package request
+
+type FxRate struct {
+	BaseCurrency  string `json:"base" validate:"required,iso4217"`
+	QuoteCurrency string `json:"quote" validate:"required,iso4217,nefield=BaseCurrency"`
+	Rate          string `json:"rate" validate:"required,numeric"`
+}
+
+type UpsertFxRates struct {
+	Rates []FxRate `json:"rates" validate:"required,min=1,dive"`
+}
+
+type CreateFxQuote struct {
+	FromCurrency string `json:"fromCurrency" validate:"required,iso4217"`
+	ToCurrency   string `json:"toCurrency" validate:"required,iso4217,nefield=FromCurrency"`
+	Amount       Amount `json:"amount" validate:"required,max=32"`
+	UserID       string
+}
+
+type ConvertFx struct {
+	QuoteID string `json:"quoteId" validate:"required"`
+	UserID  string
+}
@@ -0,0 +1,14 @@
+package request
+
+type CreateHold struct {
+	Currency         string `json:"currency" validate:"required,iso4217"`
+	Amount           Amount `json:"amount" validate:"required,max=32"`
+	Description      string `json:"description" validate:"max=255"`
+	ExpiresInSeconds int64  `json:"expiresInSeconds" validate:"min=0,max=2592000"`
+	UserID           string
+}
+
+type SettleHold struct {
+	HoldID string `validate:"required"`
+	UserID string
+}
@@ -0,0 +1,16 @@
+package request
+
+type ReserveIdempotencyKey struct {
+	UserID      string `validate:"required"`
+	Key         string `validate:"required,max=255"`
+	RequestHash string `validate:"required"`
+}
+
+type CompleteIdempotencyKey struct {
+	UserID     string
+	Key        string
+	StatusCode int
+	Response   []byte
+	// NotExecuted is set when the handler failed before changing anything
+	NotExecuted bool
+}
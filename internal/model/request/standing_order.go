@@ -0,0 +1,31 @@
+package request
+
+type StandingOrderSchedule struct {
+	Type string `json:"type" validate:"required,oneof=once daily weekly monthly cron"`
+	// StartAt is an RFC3339 time, the one-off date or the first run, optional for cron schedules
+	StartAt  string `json:"startAt" validate:"required_unless=Type cron"`
+	Cron     string `json:"cron" validate:"required_if=Type cron"`
+	Timezone string `json:"timezone" validate:"omitempty,timezone"`
+}
+
+type CreateStandingOrder struct {
+	Description string                `json:"description" validate:"max=255"`
+	Schedule    StandingOrderSchedule `json:"schedule"`
+	Transaction CreateTransaction     `json:"transaction"`
+	UserID      string
+}
+
+type UpdateStandingOrder struct {
+	StandingOrderID string                `validate:"required"`
+	Description     string                `json:"description" validate:"max=255"`
+	Schedule        StandingOrderSchedule `json:"schedule"`
+	Transaction     CreateTransaction     `json:"transaction"`
+	// Status pauses or resumes the order, it stays as it is when empty
+	Status string `json:"status" validate:"omitempty,oneof=active paused"`
+	UserID string
+}
+
+type GetStandingOrder struct {
+	StandingOrderID string `validate:"required"`
+	UserID          string
+}
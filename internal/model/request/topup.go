@@ -0,0 +1,13 @@
+package request
+
+type GetTopUps struct {
+	Status string `validate:"omitempty,oneof=pending_review approved rejected"`
+	Limit  int    `validate:"min=0"`
+	Offset int    `validate:"min=0"`
+}
+
+type ReviewTopUp struct {
+	TopUpID    string `validate:"required"`
+	Reason     string `json:"reason"`
+	ReviewerID string
+}
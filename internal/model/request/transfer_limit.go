@@ -0,0 +1,21 @@
+package request
+
+type TransferLimit struct {
+	Currency       string `json:"currency" validate:"required,iso4217"`
+	PerTransaction Amount `json:"perTransaction" validate:"max=32"`
+	Daily          Amount `json:"daily" validate:"max=32"`
+	Monthly        Amount `json:"monthly" validate:"max=32"`
+}
+
+// SetTransferLimits overrides the default limits of a user, an empty or zero amount leaves that cap off
+type SetTransferLimits struct {
+	Limits  []TransferLimit `json:"limits" validate:"required,min=1,dive"`
+	UserID  string          `validate:"required"`
+	AdminID string
+}
+
+type ResetTransferLimit struct {
+	Currency string `validate:"required,iso4217"`
+	UserID   string `validate:"required"`
+	AdminID  string
+}
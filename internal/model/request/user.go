@@ -4,10 +4,54 @@ type Register struct {
 	Email    string `json:"email" validate:"required,email"`
 	Name     string `json:"name" validate:"required,min=5,max=50"`
 	Password string `json:"password" validate:"required,min=5,max=15"`
+	Phone    string `json:"phone" validate:"omitempty,min=7,max=20"`
 }
 
 type Login struct {
 	Email    string `json:"email" validate:"required,email"`
 	Name     string `json:"name" validate:"required,min=5,max=50"`
 	Password string `json:"password" validate:"required,min=5,max=15"`
+	// IP is the client address failed logins are also counted against
+	IP string `json:"-"`
+}
+
+type RefreshToken struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+type Logout struct {
+	RefreshToken string `json:"refreshToken"`
+	UserID       string `json:"-" validate:"required"`
+	// JTI and ExpiresAt, in unix millis, identify the access token the request was made with
+	JTI       string `json:"-" validate:"required"`
+	ExpiresAt int64  `json:"-"`
+}
+
+// MfaCode is a TOTP code, or a recovery code where one is accepted, confirming a 2FA change
+type MfaCode struct {
+	UserID string `json:"-" validate:"required"`
+	Code   string `json:"code" validate:"required,max=20"`
+}
+
+type LoginMfa struct {
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+	Code           string `json:"code" validate:"required,max=20"`
+}
+
+type SetPin struct {
+	UserID   string `json:"-" validate:"required"`
+	Password string `json:"password" validate:"required"`
+	Pin      string `json:"pin" validate:"required,len=6,numeric"`
+	// IP is the client address wrong passwords are also counted against, like failed logins
+	IP string `json:"-"`
+}
+
+type VerifyPin struct {
+	UserID string
+	Pin    string
+}
+
+type UnlockUser struct {
+	UserID  string `json:"-" validate:"required"`
+	AdminID string `json:"-" validate:"required"`
 }
@@ -1,18 +1,79 @@
 package response
 
 type Balance struct {
-	Balance  int    `json:"balance"`
+	Available string `json:"available"`
+	Held      string `json:"held"`
+	Total     string `json:"total"`
+	Currency  string `json:"currency"`
+}
+
+type BalanceAt struct {
+	Balance  string `json:"balance"`
 	Currency string `json:"currency"`
+	At       string `json:"at"`
 }
 
 type GetBalancesHistory struct {
-	TransactionID    string `json:"transactionId"`
-	Balance          int    `json:"balance"`
-	Currency         string `json:"currency"`
-	TransferProofImg string `json:"transferProofImg"`
-	CreatedAt        int64  `json:"createdAt"`
-	Source           struct {
+	TransactionID      string `json:"transactionId"`
+	Balance            string `json:"balance"`
+	Currency           string `json:"currency"`
+	TransferProofImg   string `json:"transferProofImg"`
+	CreatedAt          int64  `json:"createdAt"`
+	Type               string `json:"type"`
+	ReversedAmount     string `json:"reversedAmount,omitempty"`
+	ReversalStatus     string `json:"reversalStatus,omitempty"`
+	ReversalOf         string `json:"reversalOf,omitempty"`
+	BalanceBefore      string `json:"balanceBefore,omitempty"`
+	BalanceAfter       string `json:"balanceAfter,omitempty"`
+	StandingOrderID    string `json:"standingOrderId,omitempty"`
+	FxRate             string `json:"fxRate,omitempty"`
+	CounterpartyUserID string `json:"counterpartyUserId,omitempty"`
+	Source             struct {
 		BankAccountNumber string `json:"bankAccountNumber"`
 		BankName          string `json:"bankName"`
 	} `json:"source"`
 }
+
+type Transfer struct {
+	TransactionID string `json:"transactionId"`
+	Recipient     User   `json:"recipient"`
+	Balance       string `json:"balances"`
+	Currency      string `json:"currency"`
+}
+
+type Reversal struct {
+	TransactionID         string `json:"transactionId"`
+	OriginalTransactionID string `json:"originalTransactionId"`
+	Amount                string `json:"amount"`
+	Currency              string `json:"currency"`
+	ReversedAmount        string `json:"reversedAmount"`
+	RemainingAmount       string `json:"remainingAmount"`
+}
+
+type Transaction struct {
+	TransactionID string `json:"transactionId"`
+	Status        string `json:"status"`
+	Balance       string `json:"balances"`
+	Currency      string `json:"currency"`
+	Recipient     struct {
+		BankAccountNumber string `json:"bankAccountNumber"`
+		BankName          string `json:"bankName"`
+	} `json:"recipient"`
+	BeneficiaryID string `json:"beneficiaryId,omitempty"`
+	FailureReason string `json:"failureReason,omitempty"`
+	CreatedAt     int64  `json:"createdAt"`
+	UpdatedAt     int64  `json:"updatedAt"`
+}
+
+type ReceiptVerification struct {
+	Valid         bool   `json:"valid"`
+	TransactionID string `json:"transactionId"`
+	Status        string `json:"status"`
+	Balance       string `json:"balances"`
+	Currency      string `json:"currency"`
+	Recipient     struct {
+		BankAccountNumber string `json:"bankAccountNumber"`
+		BankName          string `json:"bankName"`
+	} `json:"recipient"`
+	CreatedAt int64 `json:"createdAt"`
+}
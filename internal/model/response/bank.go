@@ -0,0 +1,12 @@
+package response
+
+type Bank struct {
+	Code          string `json:"code"`
+	Name          string `json:"name"`
+	SwiftBIC      string `json:"swiftBic"`
+	Country       string `json:"country"`
+	AccountNumber struct {
+		Format  string `json:"format"`
+		Lengths []int  `json:"lengths,omitempty"`
+	} `json:"accountNumber"`
+}
@@ -0,0 +1,13 @@
+package response
+
+type Beneficiary struct {
+	BeneficiaryID     string `json:"beneficiaryId"`
+	Nickname          string `json:"nickname"`
+	BankAccountNumber string `json:"bankAccountNumber"`
+	BankName          string `json:"bankName"`
+	IsFavorite        bool   `json:"isFavorite"`
+	// CoolingOffUntil is set while transactions to the beneficiary are still capped
+	CoolingOffUntil int64 `json:"coolingOffUntil,omitempty"`
+	CreatedAt       int64 `json:"createdAt"`
+	UpdatedAt       int64 `json:"updatedAt"`
+}
@@ -0,0 +1,27 @@
+package response
+
+type FxRate struct {
+	BaseCurrency  string `json:"base"`
+	QuoteCurrency string `json:"quote"`
+	Rate          string `json:"rate"`
+	UpdatedAt     int64  `json:"updatedAt"`
+}
+
+type FxQuote struct {
+	QuoteID      string `json:"quoteId"`
+	FromCurrency string `json:"fromCurrency"`
+	ToCurrency   string `json:"toCurrency"`
+	FromAmount   string `json:"fromAmount"`
+	ToAmount     string `json:"toAmount"`
+	Rate         string `json:"rate"`
+	ExpiresAt    int64  `json:"expiresAt"`
+}
+
+type FxConversion struct {
+	TransactionID string `json:"transactionId"`
+	FromCurrency  string `json:"fromCurrency"`
+	ToCurrency    string `json:"toCurrency"`
+	FromAmount    string `json:"fromAmount"`
+	ToAmount      string `json:"toAmount"`
+	Rate          string `json:"rate"`
+}
@@ -0,0 +1,12 @@
+package response
+
+type Hold struct {
+	HoldID        string `json:"holdId"`
+	Amount        string `json:"amount"`
+	Currency      string `json:"currency"`
+	Status        string `json:"status"`
+	Description   string `json:"description"`
+	TransactionID string `json:"transactionId,omitempty"`
+	ExpiresAt     int64  `json:"expiresAt"`
+	CreatedAt     int64  `json:"createdAt"`
+}
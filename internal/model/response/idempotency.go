@@ -0,0 +1,6 @@
+package response
+
+type IdempotentReplay struct {
+	StatusCode int
+	Response   []byte
+}
@@ -0,0 +1,28 @@
+package response
+
+type StandingOrder struct {
+	StandingOrderID string `json:"standingOrderId"`
+	Description     string `json:"description"`
+	Status          string `json:"status"`
+	Schedule        struct {
+		Type     string `json:"type"`
+		StartAt  string `json:"startAt"`
+		Cron     string `json:"cron,omitempty"`
+		Timezone string `json:"timezone"`
+	} `json:"schedule"`
+	Transaction struct {
+		BankAccountNumber string `json:"recipientBankAccountNumber"`
+		BankName          string `json:"recipientBankName"`
+		BeneficiaryID     string `json:"beneficiaryId,omitempty"`
+		Currency          string `json:"fromCurrency"`
+		Balance           string `json:"balances"`
+	} `json:"transaction"`
+	NextRunAt         int64  `json:"nextRunAt,omitempty"`
+	LastRunAt         int64  `json:"lastRunAt,omitempty"`
+	LastTransactionID string `json:"lastTransactionId,omitempty"`
+	Attempts          int    `json:"attempts"`
+	LastError         string `json:"lastError,omitempty"`
+	LastFailedAt      int64  `json:"lastFailedAt,omitempty"`
+	CreatedAt         int64  `json:"createdAt"`
+	UpdatedAt         int64  `json:"updatedAt"`
+}
@@ -0,0 +1,18 @@
+package response
+
+type TopUp struct {
+	TopUpID          string `json:"topUpId"`
+	UserID           string `json:"userId"`
+	Balance          string `json:"addedBalance"`
+	Currency         string `json:"currency"`
+	TransferProofImg string `json:"transferProofImg"`
+	Status           string `json:"status"`
+	Source           struct {
+		BankAccountNumber string `json:"bankAccountNumber"`
+		BankName          string `json:"bankName"`
+	} `json:"source"`
+	ReviewerID      string `json:"reviewerId,omitempty"`
+	RejectionReason string `json:"rejectionReason,omitempty"`
+	ReviewedAt      int64  `json:"reviewedAt,omitempty"`
+	CreatedAt       int64  `json:"createdAt"`
+}
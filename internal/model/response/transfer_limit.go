@@ -0,0 +1,19 @@
+package response
+
+type TransferLimit struct {
+	Currency string `json:"currency"`
+	// PerTransaction is empty when single transactions are not capped
+	PerTransaction string              `json:"perTransaction,omitempty"`
+	Daily          TransferLimitPeriod `json:"daily"`
+	Monthly        TransferLimitPeriod `json:"monthly"`
+	// Overridden is true when the user has limits of their own instead of the defaults
+	Overridden bool `json:"overridden"`
+}
+
+// TransferLimitPeriod leaves Limit and Remaining empty when the period is not capped
+type TransferLimitPeriod struct {
+	Limit     string `json:"limit,omitempty"`
+	Used      string `json:"used"`
+	Remaining string `json:"remaining,omitempty"`
+	ResetsAt  int64  `json:"resetsAt"`
+}
@@ -4,16 +4,47 @@ type User struct {
 	ID    string `json:"userId"`
 	Email string `json:"email,omitempty"`
 	Name  string `json:"name"`
+	Role  string `json:"role,omitempty"`
 }
 
 type Register struct {
-	Email       string `json:"email,omitempty"`
-	Name        string `json:"name"`
-	AccessToken string `json:"accessToken"`
+	Email        string `json:"email,omitempty"`
+	Name         string `json:"name"`
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int64  `json:"expiresIn"`
 }
 
+const LoginStatusMfaRequired = "mfa_required"
+
+// Login carries either the tokens or, for users with 2FA enabled, a challenge to complete with a code
 type Login struct {
-	Email       string `json:"email"`
-	Name        string `json:"name"`
-	AccessToken string `json:"accessToken"`
+	Email        string `json:"email"`
+	Name         string `json:"name"`
+	AccessToken  string `json:"accessToken,omitempty"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	ExpiresIn    int64  `json:"expiresIn,omitempty"`
+
+	Status             string `json:"status,omitempty"`
+	ChallengeToken     string `json:"challengeToken,omitempty"`
+	ChallengeExpiresIn int64  `json:"challengeExpiresIn,omitempty"`
+}
+
+// Token is an access token good for ExpiresIn seconds and the refresh token to renew it with
+type Token struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int64  `json:"expiresIn"`
+}
+
+type MfaEnrollment struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauthUri"`
+	// QrCode is a base64 PNG of OtpauthURI for authenticator apps to scan
+	QrCode string `json:"qrCode"`
+}
+
+// MfaRecoveryCodes are only ever shown once, each can stand in for a TOTP code one time
+type MfaRecoveryCodes struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
 }
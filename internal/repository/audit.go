@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+)
+
+// insertAuditLog records an audit entry within the transaction of the action it describes
+func insertAuditLog(ctx context.Context, tx *sql.Tx, log entity.AuditLog) error {
+	if log.ID == "" {
+		log.ID = common.GenerateULID()
+	}
+	metadata, err := json.Marshal(log.Metadata)
+	if err != nil {
+		return err
+	}
+	if log.Metadata == nil {
+		metadata = []byte("{}")
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_logs (id, actor_id, action, target_type, target_id, reason, metadata, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`,
+		log.ID,
+		log.ActorID,
+		log.Action,
+		log.TargetType,
+		log.TargetID,
+		log.Reason,
+		string(metadata),
+		log.CreatedAt,
+	)
+	if err != nil {
+		return wrapDBError(err)
+	}
+
+	return nil
+}
+
+// CreateAuditLog records an event that isn't part of a change made in a transaction of its own, e.g. a failed check
+func (r *UserRepositoryImpl) CreateAuditLog(ctx context.Context, log entity.AuditLog) (int, error) {
+	return withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		if err := insertAuditLog(ctx, tx, log); err != nil {
+			return http.StatusInternalServerError, err
+		}
+		return http.StatusCreated, nil
+	})
+}
@@ -3,20 +3,77 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/ovrrtd/openidea-bank/internal/helper/common"
 	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
 	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/ovrrtd/openidea-bank/internal/model/money"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
 
 type BalanceRepository interface {
-	UpsertBalance(ctx context.Context, payload entity.UpsertBalance) (int, error)
 	GetBalances(ctx context.Context, userId string) ([]entity.Balance, int, error)
-	GetBalancesHistory(ctx context.Context, payload entity.GetBalancesHistory) ([]entity.BalanceHistory, int, error)
+	GetBalancesHistory(ctx context.Context, payload entity.GetBalancesHistory) ([]entity.BalanceHistory, int, int, error)
+	Transfer(ctx context.Context, payload entity.Transfer) (int, error)
+	Convert(ctx context.Context, payload entity.FxConversion) (*entity.FxQuote, int, error)
+	ReverseTransaction(ctx context.Context, payload entity.Reversal) (*entity.Reversal, int, error)
+
+	// top ups
+	CreateTopUp(ctx context.Context, topUp entity.TopUp) (int, error)
+	GetTopUps(ctx context.Context, payload entity.GetTopUps) ([]entity.TopUp, int, error)
+	ReviewTopUp(ctx context.Context, payload entity.ReviewTopUp) (*entity.TopUp, int, error)
+
+	// outbound transactions
+	CreateOutboundTransaction(ctx context.Context, transaction entity.Transaction, checks entity.OutboundChecks) (int, error)
+	GetTransaction(ctx context.Context, id string, userID string) (*entity.Transaction, int, error)
+	FindTransactionByID(ctx context.Context, id string) (*entity.Transaction, int, error)
+	ClaimTransactions(ctx context.Context, payload entity.ClaimTransactions) ([]entity.Transaction, int, error)
+	UpdateTransactionStatus(ctx context.Context, payload entity.UpdateTransactionStatus) (int, error)
+
+	// standing orders
+	CreateStandingOrder(ctx context.Context, order entity.StandingOrder) (int, error)
+	GetStandingOrders(ctx context.Context, userID string) ([]entity.StandingOrder, int, error)
+	GetStandingOrder(ctx context.Context, id string, userID string) (*entity.StandingOrder, int, error)
+	UpdateStandingOrder(ctx context.Context, order entity.StandingOrder) (int, error)
+	ClaimStandingOrders(ctx context.Context, payload entity.ClaimStandingOrders) ([]entity.StandingOrder, int, error)
+	ExecuteStandingOrder(ctx context.Context, payload entity.ExecuteStandingOrder) (int, error)
+	FailStandingOrder(ctx context.Context, payload entity.FailStandingOrder) (int, error)
+
+	// transfer limits, GetTransferLimits only returns the user's overrides
+	GetTransferLimits(ctx context.Context, userID string) ([]entity.TransferLimit, int, error)
+	UpsertTransferLimits(ctx context.Context, payload entity.UpsertTransferLimits) (int, error)
+	DeleteTransferLimit(ctx context.Context, payload entity.DeleteTransferLimit) (int, error)
+	GetTransferUsage(ctx context.Context, userID string, dayStart int64, monthStart int64) ([]entity.TransferUsage, int, error)
+
+	// beneficiaries
+	CreateBeneficiary(ctx context.Context, beneficiary entity.Beneficiary) (int, error)
+	GetBeneficiaries(ctx context.Context, userID string) ([]entity.Beneficiary, int, error)
+	GetBeneficiary(ctx context.Context, id string, userID string) (*entity.Beneficiary, int, error)
+	UpdateBeneficiary(ctx context.Context, beneficiary entity.Beneficiary) (int, error)
+	DeleteBeneficiary(ctx context.Context, id string, userID string) (int, error)
+
+	// holds
+	CreateHold(ctx context.Context, hold entity.Hold) (*entity.Hold, int, error)
+	GetHolds(ctx context.Context, userID string) ([]entity.Hold, int, error)
+	GetHold(ctx context.Context, id string, userID string) (*entity.Hold, int, error)
+	CaptureHold(ctx context.Context, payload entity.SettleHold) (*entity.Hold, int, error)
+	ReleaseHold(ctx context.Context, payload entity.SettleHold) (*entity.Hold, int, error)
+	ExpireHolds(ctx context.Context, now int64) (int64, int, error)
+
+	// StreamBalancesHistory calls fn for each entry matching the filters, oldest first, limit and offset are ignored
+	StreamBalancesHistory(ctx context.Context, payload entity.GetBalancesHistory, fn func(entity.BalanceHistory) error) (int, error)
+
+	// point in time balances
+	CreateBalanceSnapshots(ctx context.Context, until int64) (int, int, error)
+	GetBalancesAt(ctx context.Context, userID string, at int64) ([]entity.Balance, int, error)
+
+	// BackfillBalanceHistory fills balance before and after on entries written before they were recorded
+	BackfillBalanceHistory(ctx context.Context) (int64, int, error)
 }
 
 func NewBalanceRepository(logger zerolog.Logger, db *sql.DB) BalanceRepository {
@@ -31,80 +88,373 @@ type BalanceRepositoryImpl struct {
 	db     *sql.DB
 }
 
-func (r *BalanceRepositoryImpl) UpsertBalance(ctx context.Context, payload entity.UpsertBalance) (int, error) {
-	tx, err := r.db.BeginTx(ctx, nil)
+// applyBalanceDelta adds delta to the user's balance in currency and returns the updated row.
+// Credits open the balance on first deposit, debits only apply when the available balance covers them,
+// both in a single statement so concurrent updates cannot overdraw the wallet.
+func (r *BalanceRepositoryImpl) applyBalanceDelta(ctx context.Context, tx *sql.Tx, userID string, currency string, delta int64) (*entity.Balance, int, error) {
+	balance := entity.Balance{UserID: userID, Currency: currency}
+
+	var err error
+	if delta >= 0 {
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO balances (id, balance, user_id, currency) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id, currency) DO UPDATE SET balance = balances.balance + EXCLUDED.balance
+			RETURNING id, balance
+		`, common.GenerateULID(), delta, userID, currency).Scan(&balance.ID, &balance.Balance)
+	} else {
+		err = tx.QueryRowContext(ctx, `
+			UPDATE balances SET balance = balance + $1
+			WHERE user_id = $2 AND currency = $3 AND balance + $1 >= held
+			RETURNING id, balance
+		`, delta, userID, currency).Scan(&balance.ID, &balance.Balance)
+	}
 
+	if err == sql.ErrNoRows {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrBadRequest, "balance is not enough")
+	}
+	if isOutOfRange(err) {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrAmountOverflow, errorer.ErrAmountOverflow.Error())
+	}
 	if err != nil {
-		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+		return nil, http.StatusInternalServerError, wrapDBError(err)
 	}
-	defer tx.Rollback()
 
-	balance := entity.Balance{}
-	err = tx.QueryRowContext(ctx, `SELECT id, balance FROM balances WHERE user_id = $1 AND currency = $2`, payload.UserID, payload.Currency).Scan(&balance.ID, &balance.Balance)
+	return &balance, http.StatusOK, nil
+}
 
-	if err != nil && err != sql.ErrNoRows {
-		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+// insertHistory records an entry with the balance it left behind, balanceAfter must come from the row locked by the same transaction
+func (r *BalanceRepositoryImpl) insertHistory(ctx context.Context, tx *sql.Tx, history entity.BalanceHistory, balanceAfter int64) error {
+	var counterpartyUserID sql.NullString
+	if history.CounterpartyUserID != "" {
+		counterpartyUserID = sql.NullString{String: history.CounterpartyUserID, Valid: true}
 	}
 
-	if err == sql.ErrNoRows {
-		query := `
-		INSERT INTO balances (id, balance, user_id, currency) VALUES ($1, $2, $3, $4)
-	`
+	var fxRate sql.NullString
+	if history.FxRate != "" {
+		fxRate = sql.NullString{String: history.FxRate, Valid: true}
+	}
+
+	var reversalOf sql.NullString
+	if history.ReversalOf != "" {
+		reversalOf = sql.NullString{String: history.ReversalOf, Valid: true}
+	}
+
+	var standingOrderID sql.NullString
+	if history.StandingOrderID != "" {
+		standingOrderID = sql.NullString{String: history.StandingOrderID, Valid: true}
+	}
 
-		_, err = tx.ExecContext(ctx, query,
-			common.GenerateULID(),
-			payload.Balance,
-			payload.UserID,
-			payload.Currency,
+	query := `INSERT INTO BALANCES_HISTORY (id, transaction_id, user_id, balance, currency, proof_image_url,source_bank_account_number, source_bank_name, created_at, type, counterparty_user_id, fx_rate, reversal_of, balance_before, balance_after, standing_order_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`
+	_, err := tx.ExecContext(ctx, query,
+		history.ID,
+		history.TransactionID,
+		history.UserID,
+		history.Balance,
+		history.Currency,
+		history.ProofImageURL,
+		history.SourceBankAccountNumber,
+		history.SourceBankName,
+		history.CreatedAt,
+		history.Type,
+		counterpartyUserID,
+		fxRate,
+		reversalOf,
+		balanceAfter-history.Balance,
+		balanceAfter,
+		standingOrderID,
+	)
+	if err != nil {
+		return wrapDBError(err)
+	}
+
+	return nil
+}
+
+// Transfer moves funds between two users' wallets, both sides share the transaction id.
+// The amount counts against the sender's transfer limits.
+func (r *BalanceRepositoryImpl) Transfer(ctx context.Context, payload entity.Transfer) (int, error) {
+	return withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		// touch the rows in a fixed order so opposite transfers cannot deadlock each other
+		legs := []struct {
+			userID       string
+			counterparty string
+			delta        int64
+			historyType  string
+		}{
+			{payload.SenderID, payload.RecipientID, -payload.Amount, entity.BalanceHistoryTypeTransferOut},
+			{payload.RecipientID, payload.SenderID, payload.Amount, entity.BalanceHistoryTypeTransferIn},
+		}
+		if payload.RecipientID < payload.SenderID {
+			legs[0], legs[1] = legs[1], legs[0]
+		}
+
+		now := time.Now().UnixMilli()
+		for _, leg := range legs {
+			balance, code, err := r.applyBalanceDelta(ctx, tx, leg.userID, payload.Currency, leg.delta)
+			if err != nil {
+				return code, err
+			}
+			if leg.userID == payload.SenderID {
+				code, err := r.checkTransferLimit(ctx, tx, payload.SenderID, payload.Currency, payload.Amount, payload.TransferLimit)
+				if err != nil {
+					return code, err
+				}
+			}
+
+			err = r.insertHistory(ctx, tx, entity.BalanceHistory{
+				ID:                 common.GenerateULID(),
+				TransactionID:      payload.TransactionID,
+				UserID:             leg.userID,
+				Balance:            leg.delta,
+				Currency:           payload.Currency,
+				CreatedAt:          now,
+				SourceBankName:     entity.InternalBankName,
+				Type:               leg.historyType,
+				CounterpartyUserID: leg.counterparty,
+			}, balance.Balance)
+			if err != nil {
+				return http.StatusInternalServerError, err
+			}
+		}
+
+		err := r.postJournal(ctx, tx, entity.Journal{
+			TransactionID: payload.TransactionID,
+			Description:   "transfer",
+			CreatedAt:     now,
+			Postings: []entity.Posting{
+				entity.WalletPosting(payload.SenderID, payload.Currency, -payload.Amount),
+				entity.WalletPosting(payload.RecipientID, payload.Currency, payload.Amount),
+			},
+		})
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		return http.StatusOK, nil
+	})
+}
+
+// Convert executes a locked fx quote, debiting one currency wallet and crediting the other
+func (r *BalanceRepositoryImpl) Convert(ctx context.Context, payload entity.FxConversion) (*entity.FxQuote, int, error) {
+	quote := entity.FxQuote{}
+	code, err := withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		var executedAt sql.NullInt64
+		err := tx.QueryRowContext(ctx, `
+			SELECT id, user_id, from_currency, to_currency, from_amount, to_amount, rate, created_at, expires_at, executed_at
+			FROM fx_quotes WHERE id = $1 AND user_id = $2 FOR UPDATE
+		`, payload.QuoteID, payload.UserID).Scan(
+			&quote.ID, &quote.UserID, &quote.FromCurrency, &quote.ToCurrency, &quote.FromAmount, &quote.ToAmount,
+			&quote.Rate, &quote.CreatedAt, &quote.ExpiresAt, &executedAt,
 		)
 		if err != nil {
-			return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+			if err == sql.ErrNoRows {
+				return http.StatusNotFound, errors.Wrap(errorer.ErrFxQuoteNotFound, errorer.ErrFxQuoteNotFound.Error())
+			}
+			return http.StatusInternalServerError, wrapDBError(err)
 		}
-	} else {
-		if balance.Balance+payload.Balance < 0 {
-			return http.StatusBadRequest, errors.Wrap(errorer.ErrBadRequest, "balance is not enough")
+		if executedAt.Valid {
+			return http.StatusConflict, errors.Wrap(errorer.ErrFxQuoteExecuted, errorer.ErrFxQuoteExecuted.Error())
+		}
+		if quote.ExpiresAt <= payload.ExecutedAt {
+			return http.StatusBadRequest, errors.Wrap(errorer.ErrFxQuoteExpired, errorer.ErrFxQuoteExpired.Error())
 		}
 
-		query := `
-		UPDATE balances SET balance=balance + $1 WHERE id = $2
-	`
+		legs := []struct {
+			currency    string
+			delta       int64
+			historyType string
+		}{
+			{quote.FromCurrency, -quote.FromAmount, entity.BalanceHistoryTypeFxOut},
+			{quote.ToCurrency, quote.ToAmount, entity.BalanceHistoryTypeFxIn},
+		}
+		for _, leg := range legs {
+			balance, code, err := r.applyBalanceDelta(ctx, tx, quote.UserID, leg.currency, leg.delta)
+			if err != nil {
+				return code, err
+			}
 
-		_, err = tx.ExecContext(ctx, query,
-			payload.Balance,
-			balance.ID,
-		)
+			err = r.insertHistory(ctx, tx, entity.BalanceHistory{
+				ID:             common.GenerateULID(),
+				TransactionID:  payload.TransactionID,
+				UserID:         quote.UserID,
+				Balance:        leg.delta,
+				Currency:       leg.currency,
+				CreatedAt:      payload.ExecutedAt,
+				SourceBankName: entity.InternalBankName,
+				Type:           leg.historyType,
+				FxRate:         quote.Rate,
+			}, balance.Balance)
+			if err != nil {
+				return http.StatusInternalServerError, err
+			}
+		}
 
+		// the fx position account takes the source currency in and pays the target currency out
+		err = r.postJournal(ctx, tx, entity.Journal{
+			TransactionID: payload.TransactionID,
+			ReferenceID:   quote.ID,
+			Description:   "fx conversion",
+			CreatedAt:     payload.ExecutedAt,
+			Postings: []entity.Posting{
+				entity.WalletPosting(quote.UserID, quote.FromCurrency, -quote.FromAmount),
+				entity.SystemPosting(entity.LedgerAccountFxPosition, entity.LedgerAccountTypeAsset, quote.FromCurrency, quote.FromAmount),
+				entity.SystemPosting(entity.LedgerAccountFxPosition, entity.LedgerAccountTypeAsset, quote.ToCurrency, -quote.ToAmount),
+				entity.WalletPosting(quote.UserID, quote.ToCurrency, quote.ToAmount),
+			},
+		})
 		if err != nil {
-			return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+			return http.StatusInternalServerError, err
 		}
-	}
 
-	query := `INSERT INTO BALANCES_HISTORY (id, transaction_id, user_id, balance, currency, proof_image_url,source_bank_account_number, source_bank_name, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
-	_, err = tx.ExecContext(ctx, query,
-		common.GenerateULID(),
-		payload.TransactionID,
-		payload.UserID,
-		payload.Balance,
-		payload.Currency,
-		payload.ProofImageURL,
-		payload.SenderBankAccountNumber,
-		payload.SenderBankName,
-		time.Now().UnixMilli(),
-	)
+		_, err = tx.ExecContext(ctx, `UPDATE fx_quotes SET executed_at = $1, transaction_id = $2 WHERE id = $3`,
+			payload.ExecutedAt, payload.TransactionID, quote.ID)
+		if err != nil {
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
 
+		return http.StatusOK, nil
+	})
 	if err != nil {
-		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+		return nil, code, err
 	}
-	if err := tx.Commit(); err != nil {
-		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+
+	quote.ExecutedAt = payload.ExecutedAt
+	quote.TransactionID = payload.TransactionID
+	return &quote, code, nil
+}
+
+// ReverseTransaction refunds an outgoing transaction fully or partially, never past its original amount
+func (r *BalanceRepositoryImpl) ReverseTransaction(ctx context.Context, payload entity.Reversal) (*entity.Reversal, int, error) {
+	reversal := payload
+	code, err := withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		original := entity.BalanceHistory{}
+		var status string
+		err := tx.QueryRowContext(ctx, `
+			SELECT h.id, h.user_id, h.balance, h.currency, h.reversed_amount, COALESCE(t.status, $3)
+			FROM balances_history h
+			LEFT JOIN transactions t ON t.id = h.transaction_id
+			WHERE h.transaction_id = $1 AND h.type = $2 FOR UPDATE OF h
+		`, payload.OriginalTransactionID, entity.BalanceHistoryTypeTransaction, entity.TransactionStatusCompleted).Scan(
+			&original.ID, &original.UserID, &original.Balance, &original.Currency, &original.ReversedAmount, &status,
+		)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return http.StatusNotFound, errors.Wrap(errorer.ErrTransactionNotFound, errorer.ErrTransactionNotFound.Error())
+			}
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+		// money still on its way to the bank is refunded by the transfer worker if it fails
+		if status != entity.TransactionStatusCompleted {
+			return http.StatusConflict, errors.Wrap(errorer.ErrTransactionNotSettled, errorer.ErrTransactionNotSettled.Error())
+		}
+
+		if reversal.Currency != "" && reversal.Currency != original.Currency {
+			return http.StatusBadRequest, errors.Wrap(errorer.ErrReversalCurrency, errorer.ErrReversalCurrency.Error())
+		}
+		originalAmount := money.New(-original.Balance, original.Currency)
+		remaining, err := originalAmount.Sub(money.New(original.ReversedAmount, original.Currency))
+		if err != nil {
+			return http.StatusInternalServerError, errors.Wrap(errorer.ErrAmountOverflow, err.Error())
+		}
+		if remaining.Amount <= 0 {
+			return http.StatusConflict, errors.Wrap(errorer.ErrTransactionReversed, errorer.ErrTransactionReversed.Error())
+		}
+		if reversal.Amount == 0 {
+			reversal.Amount = remaining.Amount
+		}
+		if reversal.Amount > remaining.Amount {
+			return http.StatusBadRequest, errors.Wrap(errorer.ErrReversalExceedsOriginal, errorer.ErrReversalExceedsOriginal.Error())
+		}
+		reversed, err := money.New(original.ReversedAmount, original.Currency).Add(money.New(reversal.Amount, original.Currency))
+		if err != nil {
+			return http.StatusBadRequest, errors.Wrap(errorer.ErrAmountOverflow, err.Error())
+		}
+
+		reversal.UserID = original.UserID
+		reversal.Currency = original.Currency
+		reversal.OriginalAmount = originalAmount.Amount
+		reversal.ReversedAmount = reversed.Amount
+
+		balance, code, err := r.applyBalanceDelta(ctx, tx, original.UserID, original.Currency, reversal.Amount)
+		if err != nil {
+			return code, err
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE balances_history SET reversed_amount = $1 WHERE id = $2`, reversal.ReversedAmount, original.ID)
+		if err != nil {
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+
+		err = r.insertHistory(ctx, tx, entity.BalanceHistory{
+			ID:             common.GenerateULID(),
+			TransactionID:  reversal.TransactionID,
+			UserID:         original.UserID,
+			Balance:        reversal.Amount,
+			Currency:       original.Currency,
+			CreatedAt:      reversal.CreatedAt,
+			SourceBankName: entity.InternalBankName,
+			Type:           entity.BalanceHistoryTypeReversal,
+			ReversalOf:     payload.OriginalTransactionID,
+		}, balance.Balance)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		// the money comes back from where the original transaction sent it
+		err = r.postJournal(ctx, tx, entity.Journal{
+			TransactionID: reversal.TransactionID,
+			ReferenceID:   original.ID,
+			Description:   "reversal",
+			CreatedAt:     reversal.CreatedAt,
+			Postings: []entity.Posting{
+				entity.SystemPosting(entity.LedgerAccountExternalBankOutflow, entity.LedgerAccountTypeAsset, original.Currency, reversal.Amount),
+				entity.WalletPosting(original.UserID, original.Currency, reversal.Amount),
+			},
+		})
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		err = insertAuditLog(ctx, tx, entity.AuditLog{
+			ActorID:    reversal.ActorID,
+			Action:     entity.AuditActionTransactionReversed,
+			TargetType: entity.AuditTargetTransaction,
+			TargetID:   payload.OriginalTransactionID,
+			Reason:     reversal.Reason,
+			Metadata: map[string]interface{}{
+				"reversalTransactionId": reversal.TransactionID,
+				"amount":                reversal.Amount,
+				"currency":              reversal.Currency,
+				"reversedAmount":        reversal.ReversedAmount,
+			},
+			CreatedAt: reversal.CreatedAt,
+		})
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		return http.StatusOK, nil
+	})
+	if err != nil {
+		return nil, code, err
 	}
 
-	return http.StatusOK, nil
+	return &reversal, code, nil
 }
 
 func (r *BalanceRepositoryImpl) GetBalances(ctx context.Context, userId string) ([]entity.Balance, int, error) {
 	var balances []entity.Balance
-	query := `SELECT id, balance, currency FROM balances WHERE user_id = $1 ORDER BY balance DESC`
+	// the wallet ledger account is credit-normal, so its balance is credits minus debits
+	query := `
+		SELECT b.id, b.balance, b.held, b.currency,
+			COALESCE(SUM(CASE p.direction WHEN 'credit' THEN p.amount ELSE -p.amount END), 0)
+		FROM balances b
+		LEFT JOIN ledger_accounts a ON a.code = 'wallet:' || b.user_id AND a.currency = b.currency
+		LEFT JOIN ledger_postings p ON p.account_id = a.id
+		WHERE b.user_id = $1
+		GROUP BY b.id, b.balance, b.held, b.currency
+		ORDER BY b.balance DESC
+	`
 
 	rows, err := r.db.QueryContext(ctx, query, userId)
 	if err != nil {
@@ -113,9 +463,18 @@ func (r *BalanceRepositoryImpl) GetBalances(ctx context.Context, userId string)
 
 	for rows.Next() {
 		var balance entity.Balance
-		if err := rows.Scan(&balance.ID, &balance.Balance, &balance.Currency); err != nil {
+		var ledgerBalance int64
+		if err := rows.Scan(&balance.ID, &balance.Balance, &balance.Held, &balance.Currency, &ledgerBalance); err != nil {
 			return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
 		}
+		if balance.Balance != ledgerBalance {
+			r.logger.Error().
+				Str("balanceId", balance.ID).
+				Str("currency", balance.Currency).
+				Int64("balance", balance.Balance).
+				Int64("ledgerBalance", ledgerBalance).
+				Msg("balance does not match ledger")
+		}
 		balances = append(balances, balance)
 	}
 
@@ -126,22 +485,159 @@ func (r *BalanceRepositoryImpl) GetBalances(ctx context.Context, userId string)
 	return balances, http.StatusOK, nil
 }
 
-func (r *BalanceRepositoryImpl) GetBalancesHistory(ctx context.Context, payload entity.GetBalancesHistory) ([]entity.BalanceHistory, int, error) {
+// GetBalancesHistory returns a page of the user's history matching the filters, newest first,
+// with the total count of matches when payload.CountTotal is set
+func (r *BalanceRepositoryImpl) GetBalancesHistory(ctx context.Context, payload entity.GetBalancesHistory) ([]entity.BalanceHistory, int, int, error) {
 	var balances []entity.BalanceHistory
-	query := `SELECT id, transaction_id, user_id, balance, currency, proof_image_url,source_bank_account_number, source_bank_name, created_at FROM balances_history WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3`
+	where, args := balancesHistoryFilter(payload)
+
+	var total int
+	if payload.CountTotal {
+		err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM balances_history WHERE `+where, args...).Scan(&total)
+		if err != nil {
+			return nil, 0, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+		}
+	}
 
-	rows, err := r.db.QueryContext(ctx, query, payload.UserID, payload.Limit, payload.Offset)
+	// offset pagination is kept for older clients, a cursor seeks straight to its position instead
+	offset := payload.Offset
+	if payload.AfterID != "" {
+		args = append(args, payload.AfterCreatedAt, payload.AfterID)
+		where += fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", len(args)-1, len(args))
+		offset = 0
+	}
+	args = append(args, payload.Limit, offset)
+	query := fmt.Sprintf(`SELECT %s FROM balances_history WHERE %s ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d`, historyColumns, where, len(args)-1, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+		return nil, 0, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
 	}
+	defer rows.Close()
 
 	for rows.Next() {
-		bh := entity.BalanceHistory{}
-		if err := rows.Scan(&bh.ID, &bh.TransactionID, &bh.UserID, &bh.Balance, &bh.Currency, &bh.ProofImageURL, &bh.SourceBankAccountNumber, &bh.SourceBankName, &bh.CreatedAt); err != nil {
-			return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+		bh, err := scanHistory(rows)
+		if err != nil {
+			return nil, 0, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
 		}
-		balances = append(balances, bh)
+		balances = append(balances, *bh)
 	}
 
-	return balances, http.StatusOK, nil
+	if err := rows.Err(); err != nil {
+		return nil, 0, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return balances, total, http.StatusOK, nil
+}
+
+func (r *BalanceRepositoryImpl) StreamBalancesHistory(ctx context.Context, payload entity.GetBalancesHistory, fn func(entity.BalanceHistory) error) (int, error) {
+	where, args := balancesHistoryFilter(payload)
+	rows, err := r.db.QueryContext(ctx, `SELECT `+historyColumns+` FROM balances_history WHERE `+where+` ORDER BY created_at, id`, args...)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		bh, err := scanHistory(rows)
+		if err != nil {
+			return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+		}
+		if err := fn(*bh); err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return http.StatusOK, nil
+}
+
+const historyColumns = `id, transaction_id, user_id, balance, currency, proof_image_url, source_bank_account_number, source_bank_name, created_at, type, reversed_amount, COALESCE(reversal_of, ''), balance_before, balance_after, COALESCE(standing_order_id, ''), COALESCE(fx_rate::TEXT, ''), COALESCE(counterparty_user_id, '')`
+
+func scanHistory(row rowScanner) (*entity.BalanceHistory, error) {
+	bh := entity.BalanceHistory{}
+	err := row.Scan(
+		&bh.ID, &bh.TransactionID, &bh.UserID, &bh.Balance, &bh.Currency, &bh.ProofImageURL, &bh.SourceBankAccountNumber, &bh.SourceBankName,
+		&bh.CreatedAt, &bh.Type, &bh.ReversedAmount, &bh.ReversalOf, &bh.BalanceBefore, &bh.BalanceAfter, &bh.StandingOrderID, &bh.FxRate, &bh.CounterpartyUserID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bh, nil
+}
+
+// balancesHistoryFilter builds the WHERE clause for the filters set in payload and its positional arguments
+func balancesHistoryFilter(payload entity.GetBalancesHistory) (string, []interface{}) {
+	conditions := []string{"user_id = $1"}
+	args := []interface{}{payload.UserID}
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if payload.Currency != "" {
+		add("currency = $%d", payload.Currency)
+	}
+	switch payload.Direction {
+	case entity.BalanceHistoryDirectionCredit:
+		conditions = append(conditions, "balance > 0")
+	case entity.BalanceHistoryDirectionDebit:
+		conditions = append(conditions, "balance < 0")
+	}
+	if payload.From > 0 {
+		add("created_at >= $%d", payload.From)
+	}
+	if payload.To > 0 {
+		add("created_at <= $%d", payload.To)
+	}
+	if payload.MinAmount > 0 {
+		add("ABS(balance) >= $%d", payload.MinAmount)
+	}
+	if payload.MaxAmount > 0 {
+		add("ABS(balance) <= $%d", payload.MaxAmount)
+	}
+	if payload.BankName != "" {
+		add("LOWER(source_bank_name) = LOWER($%d)", payload.BankName)
+	}
+	if payload.TransactionID != "" {
+		add("transaction_id = $%d", payload.TransactionID)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// BackfillBalanceHistory reconstructs balance before and after for entries missing them by replaying each
+// wallet's history in order. Entries that already have them are left alone, so it is safe to run again.
+func (r *BalanceRepositoryImpl) BackfillBalanceHistory(ctx context.Context) (int64, int, error) {
+	var updated int64
+	code, err := withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		// keeps new entries from landing between the replay and the update
+		if _, err := tx.ExecContext(ctx, `LOCK TABLE balances_history IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+
+		res, err := tx.ExecContext(ctx, `
+			UPDATE balances_history h SET balance_before = running.balance_after - h.balance, balance_after = running.balance_after
+			FROM (
+				SELECT id, SUM(balance) OVER (PARTITION BY user_id, currency ORDER BY created_at, id) AS balance_after
+				FROM balances_history
+			) running
+			WHERE h.id = running.id AND h.balance_after IS NULL
+		`)
+		if err != nil {
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+		updated, err = res.RowsAffected()
+		if err != nil {
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+
+		return http.StatusOK, nil
+	})
+
+	return updated, code, err
 }
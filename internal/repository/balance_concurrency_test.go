@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/rs/zerolog"
+)
+
+// testDB connects to TEST_DATABASE_URL, a postgres database with the migrations applied.
+// Tests needing it are skipped when it is not set.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(20)
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func createTestUser(t *testing.T, repo UserRepository, name string) *entity.User {
+	t.Helper()
+
+	user, _, err := repo.Register(context.Background(), entity.User{
+		Name:     name,
+		Email:    common.GenerateULID() + "@example.com",
+		Password: "not used",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return user
+}
+
+// fundTestUser tops up the user's wallet through an approved top up
+func fundTestUser(t *testing.T, userRepo UserRepository, balanceRepo BalanceRepository, userID string, currency string, amount int64) {
+	t.Helper()
+
+	ctx := context.Background()
+	reviewer := createTestUser(t, userRepo, "concurrent reviewer")
+	topUp := entity.TopUp{
+		ID:                      common.GenerateULID(),
+		UserID:                  userID,
+		Amount:                  amount,
+		Currency:                currency,
+		ProofImageURL:           "https://example.com/proof.png",
+		SenderBankAccountNumber: "1234567890",
+		SenderBankName:          "test bank",
+		Status:                  entity.TopUpStatusPendingReview,
+		CreatedAt:               time.Now().UnixMilli(),
+	}
+	if _, err := balanceRepo.CreateTopUp(ctx, topUp); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := balanceRepo.ReviewTopUp(ctx, entity.ReviewTopUp{
+		TopUpID:    topUp.ID,
+		ReviewerID: reviewer.ID,
+		Approve:    true,
+		ReviewedAt: time.Now().UnixMilli(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// runConcurrentDebits runs debit n times at once and counts the ones that went through,
+// running out of balance is the only failure allowed
+func runConcurrentDebits(t *testing.T, n int, debit func() (int, error)) int64 {
+	t.Helper()
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int64
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			code, err := debit()
+			if err != nil && code != http.StatusBadRequest {
+				t.Errorf("debit failed with %d: %v", code, err)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				succeeded++
+			}
+		}()
+	}
+	wg.Wait()
+
+	return succeeded
+}
+
+// assertWalletConsistent checks the balance is want and that the user's history and ledger wallet agree with it
+func assertWalletConsistent(t *testing.T, db *sql.DB, userID string, currency string, want int64) {
+	t.Helper()
+
+	ctx := context.Background()
+	var balance int64
+	err := db.QueryRowContext(ctx, `SELECT balance FROM balances WHERE user_id = $1 AND currency = $2`, userID, currency).Scan(&balance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance != want || balance < 0 {
+		t.Errorf("balance of %s is %d, want %d", userID, balance, want)
+	}
+
+	var historySum, negative int64
+	err = db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(balance), 0), COUNT(*) FILTER (WHERE balance_after < 0)
+		FROM balances_history WHERE user_id = $1 AND currency = $2
+	`, userID, currency).Scan(&historySum, &negative)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if historySum != balance {
+		t.Errorf("history of %s sums to %d, balance is %d", userID, historySum, balance)
+	}
+	if negative > 0 {
+		t.Errorf("history of %s has %d entries leaving a negative balance", userID, negative)
+	}
+
+	var ledgerSum int64
+	err = db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(CASE p.direction WHEN 'credit' THEN p.amount ELSE -p.amount END), 0)
+		FROM ledger_postings p JOIN ledger_accounts a ON a.id = p.account_id
+		WHERE a.code = $1 AND a.currency = $2
+	`, entity.WalletAccountCode(userID), currency).Scan(&ledgerSum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ledgerSum != balance {
+		t.Errorf("ledger wallet of %s sums to %d, balance is %d", userID, ledgerSum, balance)
+	}
+}
+
+func TestConcurrentDebitsNeverOverdraw(t *testing.T) {
+	const (
+		currency = "USD"
+		deposit  = int64(10000)
+		amount   = int64(100)
+		debits   = 300
+	)
+
+	db := testDB(t)
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	userRepo := NewUserRepository(logger, db)
+	balanceRepo := NewBalanceRepository(logger, db)
+
+	sender := createTestUser(t, userRepo, "concurrent sender")
+	recipient := createTestUser(t, userRepo, "concurrent recipient")
+	fundTestUser(t, userRepo, balanceRepo, sender.ID, currency, deposit)
+
+	succeeded := runConcurrentDebits(t, debits, func() (int, error) {
+		return balanceRepo.Transfer(ctx, entity.Transfer{
+			TransactionID: common.GenerateULID(),
+			SenderID:      sender.ID,
+			RecipientID:   recipient.ID,
+			Currency:      currency,
+			Amount:        amount,
+		})
+	})
+
+	if want := deposit / amount; succeeded != want {
+		t.Errorf("succeeded %d debits, want %d", succeeded, want)
+	}
+	assertWalletConsistent(t, db, sender.ID, currency, deposit-succeeded*amount)
+	assertWalletConsistent(t, db, recipient.ID, currency, succeeded*amount)
+}
+
+func TestConcurrentOutboundTransactionsNeverOverdraw(t *testing.T) {
+	const (
+		currency = "USD"
+		deposit  = int64(10000)
+		amount   = int64(100)
+		debits   = 300
+	)
+
+	db := testDB(t)
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	userRepo := NewUserRepository(logger, db)
+	balanceRepo := NewBalanceRepository(logger, db)
+
+	sender := createTestUser(t, userRepo, "concurrent sender")
+	fundTestUser(t, userRepo, balanceRepo, sender.ID, currency, deposit)
+
+	succeeded := runConcurrentDebits(t, debits, func() (int, error) {
+		now := time.Now().UnixMilli()
+		return balanceRepo.CreateOutboundTransaction(ctx, entity.Transaction{
+			ID:                common.GenerateULID(),
+			UserID:            sender.ID,
+			Amount:            amount,
+			Currency:          currency,
+			BankAccountNumber: "1234567890",
+			BankName:          "test bank",
+			Status:            entity.TransactionStatusPending,
+			NextAttemptAt:     now,
+			CreatedAt:         now,
+			UpdatedAt:         now,
+		}, entity.OutboundChecks{
+			// every debit goes to the same new recipient account, keep its cooling off limit out of the way
+			CoolingOff: entity.BeneficiaryCoolingOff{Limit: 2 * deposit},
+		})
+	})
+
+	if want := deposit / amount; succeeded != want {
+		t.Errorf("succeeded %d debits, want %d", succeeded, want)
+	}
+	assertWalletConsistent(t, db, sender.ID, currency, deposit-succeeded*amount)
+
+	var queued int64
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM transactions WHERE user_id = $1`, sender.ID).Scan(&queued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queued != succeeded {
+		t.Errorf("%d transactions queued, %d debits succeeded", queued, succeeded)
+	}
+}
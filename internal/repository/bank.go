@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// BankGateway submits outbound transfers to a bank and reports how they went
+type BankGateway interface {
+	// Submit hands the transfer to the bank and returns the bank's reference for it. It must be idempotent
+	// on transaction.ID: a transaction is submitted again when recording the first submission failed, and
+	// the bank has to answer that with the reference it already gave out instead of paying out twice.
+	Submit(ctx context.Context, transaction entity.Transaction) (string, error)
+	// Status reports where a submitted transfer is. A transfer reported completed may later be reported
+	// returned, settled transfers are asked about again until the return window runs out.
+	Status(ctx context.Context, reference string) (*entity.GatewayResult, error)
+}
+
+type SimulatedBankConfig struct {
+	// Latency is how long the bank takes to settle a transfer
+	Latency time.Duration
+	// FailureRate and ReturnRate are the share of transfers, between 0 and 1, that fail or are returned
+	FailureRate float64
+	ReturnRate  float64
+}
+
+// NewSimulatedBankGateway returns a gateway for running the transfer flow locally.
+// References are derived from the transaction and outcomes from the reference alone, so submitting
+// the same transaction again gets the same reference and both survive restarts.
+func NewSimulatedBankGateway(logger zerolog.Logger, cfg SimulatedBankConfig) BankGateway {
+	return &SimulatedBankGateway{
+		logger: logger,
+		cfg:    cfg,
+	}
+}
+
+type SimulatedBankGateway struct {
+	logger zerolog.Logger
+	cfg    SimulatedBankConfig
+}
+
+const simulatedReferencePrefix = "SIM"
+
+func (g *SimulatedBankGateway) Submit(ctx context.Context, transaction entity.Transaction) (string, error) {
+	// settlement latency runs from when the transaction was created rather than submitted, which keeps
+	// the reference the same across submissions
+	reference := fmt.Sprintf("%s-%d-%s", simulatedReferencePrefix, transaction.CreatedAt, transaction.ID)
+	g.logger.Debug().Str("transactionId", transaction.ID).Str("reference", reference).Msg("simulated bank accepted transfer")
+
+	return reference, nil
+}
+
+func (g *SimulatedBankGateway) Status(ctx context.Context, reference string) (*entity.GatewayResult, error) {
+	parts := strings.SplitN(reference, "-", 3)
+	if len(parts) != 3 || parts[0] != simulatedReferencePrefix {
+		return nil, errors.New("unknown bank reference " + reference)
+	}
+	submittedAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid bank reference "+reference)
+	}
+
+	if time.Since(time.UnixMilli(submittedAt)) < g.cfg.Latency {
+		return &entity.GatewayResult{Status: entity.TransactionStatusProcessing}, nil
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(reference))
+	roll := float64(h.Sum64()%10000) / 10000
+
+	switch {
+	case roll < g.cfg.FailureRate:
+		return &entity.GatewayResult{Status: entity.TransactionStatusFailed, FailureReason: "rejected by simulated bank"}, nil
+	case roll < g.cfg.FailureRate+g.cfg.ReturnRate:
+		// returned transfers settle first and bounce back after another latency
+		if time.Since(time.UnixMilli(submittedAt)) < 2*g.cfg.Latency {
+			return &entity.GatewayResult{Status: entity.TransactionStatusCompleted}, nil
+		}
+		return &entity.GatewayResult{Status: entity.TransactionStatusReturned, FailureReason: "returned by simulated bank"}, nil
+	default:
+		return &entity.GatewayResult{Status: entity.TransactionStatusCompleted}, nil
+	}
+}
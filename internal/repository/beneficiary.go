@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/pkg/errors"
+)
+
+const beneficiaryColumns = `id, user_id, nickname, bank_account_number, bank_name, is_favorite, created_at, updated_at`
+
+func (r *BalanceRepositoryImpl) CreateBeneficiary(ctx context.Context, beneficiary entity.Beneficiary) (int, error) {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO beneficiaries (id, user_id, nickname, bank_account_number, bank_name, is_favorite, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`,
+		beneficiary.ID,
+		beneficiary.UserID,
+		beneficiary.Nickname,
+		beneficiary.BankAccountNumber,
+		beneficiary.BankName,
+		beneficiary.IsFavorite,
+		beneficiary.CreatedAt,
+		beneficiary.UpdatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return http.StatusConflict, errors.Wrap(errorer.ErrBeneficiaryExists, errorer.ErrBeneficiaryExists.Error())
+		}
+		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return http.StatusCreated, nil
+}
+
+// GetBeneficiaries lists favorites first, then by nickname
+func (r *BalanceRepositoryImpl) GetBeneficiaries(ctx context.Context, userID string) ([]entity.Beneficiary, int, error) {
+	var beneficiaries []entity.Beneficiary
+	rows, err := r.db.QueryContext(ctx, `SELECT `+beneficiaryColumns+` FROM beneficiaries WHERE user_id = $1 ORDER BY is_favorite DESC, nickname, created_at`, userID)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		beneficiary, err := scanBeneficiary(rows)
+		if err != nil {
+			return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+		}
+		beneficiaries = append(beneficiaries, *beneficiary)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return beneficiaries, http.StatusOK, nil
+}
+
+func (r *BalanceRepositoryImpl) GetBeneficiary(ctx context.Context, id string, userID string) (*entity.Beneficiary, int, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+beneficiaryColumns+` FROM beneficiaries WHERE id = $1 AND user_id = $2`, id, userID)
+	beneficiary, err := scanBeneficiary(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, http.StatusNotFound, errors.Wrap(errorer.ErrBeneficiaryNotFound, errorer.ErrBeneficiaryNotFound.Error())
+		}
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return beneficiary, http.StatusOK, nil
+}
+
+// UpdateBeneficiary only changes the nickname and favorite flag, the account a beneficiary points at is fixed
+// so an old trusted beneficiary cannot be turned into a new recipient without cooling off
+func (r *BalanceRepositoryImpl) UpdateBeneficiary(ctx context.Context, beneficiary entity.Beneficiary) (int, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE beneficiaries SET nickname = $1, is_favorite = $2, updated_at = $3 WHERE id = $4 AND user_id = $5`,
+		beneficiary.Nickname, beneficiary.IsFavorite, beneficiary.UpdatedAt, beneficiary.ID, beneficiary.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return http.StatusNotFound, errors.Wrap(errorer.ErrBeneficiaryNotFound, errorer.ErrBeneficiaryNotFound.Error())
+	}
+
+	return http.StatusOK, nil
+}
+
+func (r *BalanceRepositoryImpl) DeleteBeneficiary(ctx context.Context, id string, userID string) (int, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM beneficiaries WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return http.StatusNotFound, errors.Wrap(errorer.ErrBeneficiaryNotFound, errorer.ErrBeneficiaryNotFound.Error())
+	}
+
+	return http.StatusOK, nil
+}
+
+// checkRecipientCoolingOff rejects a transaction that would take what was sent to a recipient account in
+// cooling off past its limit. An account is past cooling off once it was saved as a beneficiary or sent
+// money to before the period started, deleting and adding it again doesn't change that. Unsaved accounts
+// count too, so the cap can't be sidestepped by typing the account in. The caller must hold the lock on
+// the user's balance row in the currency, so concurrent transactions add up.
+func (r *BalanceRepositoryImpl) checkRecipientCoolingOff(ctx context.Context, tx *sql.Tx, transaction entity.Transaction, coolingOff entity.BeneficiaryCoolingOff) (int, error) {
+	var established bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM beneficiaries
+			WHERE user_id = $1 AND bank_name = $2 AND bank_account_number = $3 AND created_at <= $4
+		) OR EXISTS (
+			SELECT 1 FROM transactions
+			WHERE user_id = $1 AND bank_name = $2 AND bank_account_number = $3 AND created_at <= $4 AND status NOT IN ($5, $6)
+		)
+	`, transaction.UserID, transaction.BankName, transaction.BankAccountNumber, coolingOff.Since,
+		entity.TransactionStatusFailed, entity.TransactionStatusReturned).Scan(&established)
+	if err != nil {
+		return http.StatusInternalServerError, wrapDBError(err)
+	}
+	if established {
+		return http.StatusOK, nil
+	}
+
+	var sent int64
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(amount), 0) FROM transactions
+		WHERE user_id = $1 AND bank_name = $2 AND bank_account_number = $3 AND currency = $4 AND created_at > $5 AND status NOT IN ($6, $7)
+	`, transaction.UserID, transaction.BankName, transaction.BankAccountNumber, transaction.Currency, coolingOff.Since,
+		entity.TransactionStatusFailed, entity.TransactionStatusReturned).Scan(&sent)
+	if err != nil {
+		return http.StatusInternalServerError, wrapDBError(err)
+	}
+	if sent+transaction.Amount > coolingOff.Limit {
+		return http.StatusForbidden, errors.Wrap(errorer.ErrBeneficiaryCoolingOff, errorer.ErrBeneficiaryCoolingOff.Error())
+	}
+
+	return http.StatusOK, nil
+}
+
+func scanBeneficiary(row rowScanner) (*entity.Beneficiary, error) {
+	beneficiary := entity.Beneficiary{}
+	err := row.Scan(
+		&beneficiary.ID, &beneficiary.UserID, &beneficiary.Nickname, &beneficiary.BankAccountNumber,
+		&beneficiary.BankName, &beneficiary.IsFavorite, &beneficiary.CreatedAt, &beneficiary.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &beneficiary, nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+type FxRepository interface {
+	UpsertRates(ctx context.Context, rates []entity.FxRate) (int, error)
+	GetRates(ctx context.Context) ([]entity.FxRate, int, error)
+	GetRate(ctx context.Context, base string, quote string) (*entity.FxRate, int, error)
+	CreateQuote(ctx context.Context, quote entity.FxQuote) (int, error)
+}
+
+func NewFxRepository(logger zerolog.Logger, db *sql.DB) FxRepository {
+	return &FxRepositoryImpl{
+		logger: logger,
+		db:     db,
+	}
+}
+
+type FxRepositoryImpl struct {
+	logger zerolog.Logger
+	db     *sql.DB
+}
+
+func (r *FxRepositoryImpl) UpsertRates(ctx context.Context, rates []entity.FxRate) (int, error) {
+	return withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		for _, rate := range rates {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO fx_rates (base_currency, quote_currency, rate, updated_at) VALUES ($1, $2, $3, $4)
+				ON CONFLICT (base_currency, quote_currency) DO UPDATE SET rate = EXCLUDED.rate, updated_at = EXCLUDED.updated_at
+			`, rate.BaseCurrency, rate.QuoteCurrency, rate.Rate, rate.UpdatedAt)
+			if err != nil {
+				return http.StatusInternalServerError, wrapDBError(err)
+			}
+		}
+
+		return http.StatusOK, nil
+	})
+}
+
+func (r *FxRepositoryImpl) GetRates(ctx context.Context) ([]entity.FxRate, int, error) {
+	var rates []entity.FxRate
+	rows, err := r.db.QueryContext(ctx, `SELECT base_currency, quote_currency, rate, updated_at FROM fx_rates ORDER BY base_currency, quote_currency`)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		rate := entity.FxRate{}
+		if err := rows.Scan(&rate.BaseCurrency, &rate.QuoteCurrency, &rate.Rate, &rate.UpdatedAt); err != nil {
+			return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+		}
+		rates = append(rates, rate)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return rates, http.StatusOK, nil
+}
+
+func (r *FxRepositoryImpl) GetRate(ctx context.Context, base string, quote string) (*entity.FxRate, int, error) {
+	rate := entity.FxRate{}
+	err := r.db.QueryRowContext(ctx, `SELECT base_currency, quote_currency, rate, updated_at FROM fx_rates WHERE base_currency = $1 AND quote_currency = $2`, base, quote).
+		Scan(&rate.BaseCurrency, &rate.QuoteCurrency, &rate.Rate, &rate.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, http.StatusNotFound, errors.Wrap(errorer.ErrFxRateNotFound, errorer.ErrFxRateNotFound.Error())
+		}
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return &rate, http.StatusOK, nil
+}
+
+func (r *FxRepositoryImpl) CreateQuote(ctx context.Context, quote entity.FxQuote) (int, error) {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO fx_quotes (id, user_id, from_currency, to_currency, from_amount, to_amount, rate, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`,
+		quote.ID,
+		quote.UserID,
+		quote.FromCurrency,
+		quote.ToCurrency,
+		quote.FromAmount,
+		quote.ToAmount,
+		quote.Rate,
+		quote.CreatedAt,
+		quote.ExpiresAt,
+	)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return http.StatusCreated, nil
+}
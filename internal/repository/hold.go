@@ -0,0 +1,280 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/pkg/errors"
+)
+
+const holdColumns = `id, user_id, balance_id, amount, currency, status, description, COALESCE(transaction_id, ''), expires_at, created_at, updated_at`
+
+// CreateHold moves amount from the available to the held part of the user's balance
+func (r *BalanceRepositoryImpl) CreateHold(ctx context.Context, hold entity.Hold) (*entity.Hold, int, error) {
+	code, err := withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		err := tx.QueryRowContext(ctx, `
+			UPDATE balances SET held = held + $1
+			WHERE user_id = $2 AND currency = $3 AND balance - held >= $1
+			RETURNING id
+		`, hold.Amount, hold.UserID, hold.Currency).Scan(&hold.BalanceID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return http.StatusBadRequest, errors.Wrap(errorer.ErrBadRequest, "balance is not enough")
+			}
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO holds (id, user_id, balance_id, amount, currency, status, description, expires_at, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		`,
+			hold.ID,
+			hold.UserID,
+			hold.BalanceID,
+			hold.Amount,
+			hold.Currency,
+			hold.Status,
+			hold.Description,
+			hold.ExpiresAt,
+			hold.CreatedAt,
+			hold.UpdatedAt,
+		)
+		if err != nil {
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+
+		return http.StatusCreated, nil
+	})
+	if err != nil {
+		return nil, code, err
+	}
+
+	return &hold, code, nil
+}
+
+func (r *BalanceRepositoryImpl) GetHolds(ctx context.Context, userID string) ([]entity.Hold, int, error) {
+	var holds []entity.Hold
+	rows, err := r.db.QueryContext(ctx, `SELECT `+holdColumns+` FROM holds WHERE user_id = $1 ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		hold, err := scanHold(rows)
+		if err != nil {
+			return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+		}
+		holds = append(holds, *hold)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return holds, http.StatusOK, nil
+}
+
+func (r *BalanceRepositoryImpl) GetHold(ctx context.Context, id string, userID string) (*entity.Hold, int, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+holdColumns+` FROM holds WHERE id = $1 AND user_id = $2`, id, userID)
+	hold, err := scanHold(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, http.StatusNotFound, errors.Wrap(errorer.ErrHoldNotFound, errorer.ErrHoldNotFound.Error())
+		}
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return hold, http.StatusOK, nil
+}
+
+// CaptureHold turns an active hold into a debit of the held amount, which counts against the user's transfer limits
+func (r *BalanceRepositoryImpl) CaptureHold(ctx context.Context, payload entity.SettleHold) (*entity.Hold, int, error) {
+	var hold *entity.Hold
+	code, err := withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		var (
+			code int
+			err  error
+		)
+		hold, code, err = lockActiveHold(ctx, tx, payload)
+		if err != nil {
+			return code, err
+		}
+
+		var balanceAfter int64
+		err = tx.QueryRowContext(ctx, `UPDATE balances SET held = held - $1, balance = balance - $1 WHERE id = $2 RETURNING balance`, hold.Amount, hold.BalanceID).Scan(&balanceAfter)
+		if err != nil {
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+		if code, err := r.checkTransferLimit(ctx, tx, hold.UserID, hold.Currency, hold.Amount, payload.TransferLimit); err != nil {
+			return code, err
+		}
+
+		history := entity.BalanceHistory{
+			ID:             common.GenerateULID(),
+			TransactionID:  payload.TransactionID,
+			UserID:         hold.UserID,
+			Balance:        -hold.Amount,
+			Currency:       hold.Currency,
+			CreatedAt:      payload.SettledAt,
+			SourceBankName: entity.InternalBankName,
+			Type:           entity.BalanceHistoryTypeHoldCapture,
+		}
+		if err := r.insertHistory(ctx, tx, history, balanceAfter); err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		err = r.postJournal(ctx, tx, entity.Journal{
+			TransactionID: payload.TransactionID,
+			ReferenceID:   hold.ID,
+			Description:   "hold capture",
+			CreatedAt:     payload.SettledAt,
+			Postings: []entity.Posting{
+				entity.WalletPosting(hold.UserID, hold.Currency, -hold.Amount),
+				entity.SystemPosting(entity.LedgerAccountExternalBankOutflow, entity.LedgerAccountTypeAsset, hold.Currency, -hold.Amount),
+			},
+		})
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		hold.Status = entity.HoldStatusCaptured
+		hold.TransactionID = payload.TransactionID
+		hold.UpdatedAt = payload.SettledAt
+		return updateHoldStatus(ctx, tx, *hold)
+	})
+	if err != nil {
+		return nil, code, err
+	}
+
+	return hold, code, nil
+}
+
+// ReleaseHold gives the held amount back to the available balance
+func (r *BalanceRepositoryImpl) ReleaseHold(ctx context.Context, payload entity.SettleHold) (*entity.Hold, int, error) {
+	var hold *entity.Hold
+	code, err := withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		var (
+			code int
+			err  error
+		)
+		hold, code, err = lockActiveHold(ctx, tx, payload)
+		if err != nil {
+			return code, err
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE balances SET held = held - $1 WHERE id = $2`, hold.Amount, hold.BalanceID)
+		if err != nil {
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+
+		hold.Status = entity.HoldStatusReleased
+		hold.UpdatedAt = payload.SettledAt
+		return updateHoldStatus(ctx, tx, *hold)
+	})
+	if err != nil {
+		return nil, code, err
+	}
+
+	return hold, code, nil
+}
+
+// ExpireHolds releases every active hold past its expiry
+func (r *BalanceRepositoryImpl) ExpireHolds(ctx context.Context, now int64) (int64, int, error) {
+	var expired int64
+	code, err := withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		expired = 0
+		rows, err := tx.QueryContext(ctx, `
+			UPDATE holds SET status = $1, updated_at = $2
+			WHERE id IN (
+				SELECT id FROM holds WHERE status = $3 AND expires_at <= $2
+				ORDER BY expires_at LIMIT 500 FOR UPDATE SKIP LOCKED
+			)
+			RETURNING balance_id, amount
+		`, entity.HoldStatusExpired, now, entity.HoldStatusActive)
+		if err != nil {
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+
+		released := map[string]int64{}
+		for rows.Next() {
+			var balanceID string
+			var amount int64
+			if err := rows.Scan(&balanceID, &amount); err != nil {
+				rows.Close()
+				return http.StatusInternalServerError, wrapDBError(err)
+			}
+			released[balanceID] += amount
+			expired++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+
+		for balanceID, amount := range released {
+			_, err := tx.ExecContext(ctx, `UPDATE balances SET held = held - $1 WHERE id = $2`, amount, balanceID)
+			if err != nil {
+				return http.StatusInternalServerError, wrapDBError(err)
+			}
+		}
+
+		return http.StatusOK, nil
+	})
+
+	return expired, code, err
+}
+
+func lockActiveHold(ctx context.Context, tx *sql.Tx, payload entity.SettleHold) (*entity.Hold, int, error) {
+	row := tx.QueryRowContext(ctx, `SELECT `+holdColumns+` FROM holds WHERE id = $1 AND user_id = $2 FOR UPDATE`, payload.HoldID, payload.UserID)
+	hold, err := scanHold(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, http.StatusNotFound, errors.Wrap(errorer.ErrHoldNotFound, errorer.ErrHoldNotFound.Error())
+		}
+		return nil, http.StatusInternalServerError, wrapDBError(err)
+	}
+
+	// an expired hold may still wait for the expiry worker, it must not be captured meanwhile
+	if hold.Status != entity.HoldStatusActive || hold.ExpiresAt <= payload.SettledAt {
+		return nil, http.StatusConflict, errors.Wrap(errorer.ErrHoldNotActive, errorer.ErrHoldNotActive.Error())
+	}
+
+	return hold, http.StatusOK, nil
+}
+
+func updateHoldStatus(ctx context.Context, tx *sql.Tx, hold entity.Hold) (int, error) {
+	var transactionID sql.NullString
+	if hold.TransactionID != "" {
+		transactionID = sql.NullString{String: hold.TransactionID, Valid: true}
+	}
+
+	_, err := tx.ExecContext(ctx, `UPDATE holds SET status = $1, transaction_id = $2, updated_at = $3 WHERE id = $4`,
+		hold.Status, transactionID, hold.UpdatedAt, hold.ID)
+	if err != nil {
+		return http.StatusInternalServerError, wrapDBError(err)
+	}
+
+	return http.StatusOK, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanHold(row rowScanner) (*entity.Hold, error) {
+	hold := entity.Hold{}
+	err := row.Scan(
+		&hold.ID, &hold.UserID, &hold.BalanceID, &hold.Amount, &hold.Currency, &hold.Status,
+		&hold.Description, &hold.TransactionID, &hold.ExpiresAt, &hold.CreatedAt, &hold.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hold, nil
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+type IdempotencyRepository interface {
+	// Reserve claims the key for the first request, returning nil, or the already stored key
+	Reserve(ctx context.Context, key entity.IdempotencyKey) (*entity.IdempotencyKey, int, error)
+	Complete(ctx context.Context, key entity.IdempotencyKey) (int, error)
+	Release(ctx context.Context, userID string, key string) (int, error)
+	DeleteExpired(ctx context.Context, now int64) (int64, int, error)
+}
+
+func NewIdempotencyRepository(logger zerolog.Logger, db *sql.DB) IdempotencyRepository {
+	return &IdempotencyRepositoryImpl{
+		logger: logger,
+		db:     db,
+	}
+}
+
+type IdempotencyRepositoryImpl struct {
+	logger zerolog.Logger
+	db     *sql.DB
+}
+
+func (r *IdempotencyRepositoryImpl) Reserve(ctx context.Context, key entity.IdempotencyKey) (*entity.IdempotencyKey, int, error) {
+	// an expired key is free to be claimed again
+	_, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE user_id = $1 AND idempotency_key = $2 AND expires_at <= $3`,
+		key.UserID, key.Key, key.CreatedAt)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (user_id, idempotency_key, request_hash, created_at, expires_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, idempotency_key) DO NOTHING
+	`, key.UserID, key.Key, key.RequestHash, key.CreatedAt, key.ExpiresAt)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	if n, _ := res.RowsAffected(); n == 1 {
+		return nil, http.StatusCreated, nil
+	}
+
+	existing := entity.IdempotencyKey{}
+	var statusCode sql.NullInt64
+	var body sql.NullString
+	err = r.db.QueryRowContext(ctx, `
+		SELECT user_id, idempotency_key, request_hash, status_code, response_body, created_at, expires_at
+		FROM idempotency_keys WHERE user_id = $1 AND idempotency_key = $2
+	`, key.UserID, key.Key).Scan(&existing.UserID, &existing.Key, &existing.RequestHash, &statusCode, &body, &existing.CreatedAt, &existing.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// released between our insert and select, let the client retry
+			return nil, http.StatusConflict, errors.Wrap(errorer.ErrIdempotencyKeyInProgress, errorer.ErrIdempotencyKeyInProgress.Error())
+		}
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	existing.StatusCode = int(statusCode.Int64)
+	existing.Response = []byte(body.String)
+
+	return &existing, http.StatusOK, nil
+}
+
+func (r *IdempotencyRepositoryImpl) Complete(ctx context.Context, key entity.IdempotencyKey) (int, error) {
+	_, err := r.db.ExecContext(ctx, `UPDATE idempotency_keys SET status_code = $1, response_body = $2 WHERE user_id = $3 AND idempotency_key = $4`,
+		key.StatusCode, string(key.Response), key.UserID, key.Key)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return http.StatusOK, nil
+}
+
+func (r *IdempotencyRepositoryImpl) Release(ctx context.Context, userID string, key string) (int, error) {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE user_id = $1 AND idempotency_key = $2 AND status_code IS NULL`, userID, key)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return http.StatusOK, nil
+}
+
+func (r *IdempotencyRepositoryImpl) DeleteExpired(ctx context.Context, now int64) (int64, int, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	n, _ := res.RowsAffected()
+
+	return n, http.StatusOK, nil
+}
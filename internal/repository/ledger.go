@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/ovrrtd/openidea-bank/internal/model/money"
+	"github.com/pkg/errors"
+)
+
+// postJournal writes a balanced journal and its postings within tx
+func (r *BalanceRepositoryImpl) postJournal(ctx context.Context, tx *sql.Tx, journal entity.Journal) error {
+	if len(journal.Postings) < 2 {
+		return errors.Wrap(errorer.ErrUnbalancedLedger, "journal needs at least two postings")
+	}
+
+	net := map[string]money.Money{}
+	for _, p := range journal.Postings {
+		if p.Amount <= 0 {
+			return errors.Wrap(errorer.ErrUnbalancedLedger, "posting amount must be positive")
+		}
+		sum, ok := net[p.Currency]
+		if !ok {
+			sum = money.New(0, p.Currency)
+		}
+		var err error
+		if p.Direction == entity.PostingDirectionCredit {
+			sum, err = sum.Sub(money.New(p.Amount, p.Currency))
+		} else {
+			sum, err = sum.Add(money.New(p.Amount, p.Currency))
+		}
+		if err != nil {
+			return errors.Wrap(errorer.ErrAmountOverflow, err.Error())
+		}
+		net[p.Currency] = sum
+	}
+	for currency, v := range net {
+		if v.Amount != 0 {
+			return errors.Wrap(errorer.ErrUnbalancedLedger, fmt.Sprintf("journal is off by %s %s", money.Format(v.Amount, currency), currency))
+		}
+	}
+
+	if journal.ID == "" {
+		journal.ID = common.GenerateULID()
+	}
+	if journal.CreatedAt == 0 {
+		journal.CreatedAt = time.Now().UnixMilli()
+	}
+
+	_, err := tx.ExecContext(ctx, `INSERT INTO ledger_journals (id, transaction_id, reference_id, description, created_at) VALUES ($1, $2, $3, $4, $5)`,
+		journal.ID,
+		journal.TransactionID,
+		journal.ReferenceID,
+		journal.Description,
+		journal.CreatedAt,
+	)
+	if err != nil {
+		return wrapDBError(err)
+	}
+
+	for _, p := range journal.Postings {
+		accountID, err := r.ledgerAccountID(ctx, tx, p)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO ledger_postings (id, journal_id, account_id, direction, amount, currency, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			common.GenerateULID(),
+			journal.ID,
+			accountID,
+			p.Direction,
+			p.Amount,
+			p.Currency,
+			journal.CreatedAt,
+		)
+		if err != nil {
+			return wrapDBError(err)
+		}
+	}
+
+	return nil
+}
+
+// ledgerAccountID returns the id of the account a posting belongs to, opening it if needed. Accounts are
+// looked up before inserting so postings to the shared system accounts don't write, and lock, their row.
+func (r *BalanceRepositoryImpl) ledgerAccountID(ctx context.Context, tx *sql.Tx, p entity.Posting) (string, error) {
+	id, err := findLedgerAccountID(ctx, tx, p)
+	if err != sql.ErrNoRows {
+		return id, err
+	}
+
+	var userID sql.NullString
+	if p.UserID != "" {
+		userID = sql.NullString{String: p.UserID, Valid: true}
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO ledger_accounts (id, code, user_id, currency, type, created_at) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (code, currency) DO NOTHING
+		RETURNING id
+	`,
+		common.GenerateULID(),
+		p.AccountCode,
+		userID,
+		p.Currency,
+		p.AccountType,
+		time.Now().UnixMilli(),
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		// opened by a concurrent transaction after the lookup above
+		id, err = findLedgerAccountID(ctx, tx, p)
+		if err == sql.ErrNoRows {
+			return "", wrapDBError(err)
+		}
+		return id, err
+	}
+	if err != nil {
+		return "", wrapDBError(err)
+	}
+
+	return id, nil
+}
+
+// findLedgerAccountID returns sql.ErrNoRows as is when the account is not opened yet
+func findLedgerAccountID(ctx context.Context, tx *sql.Tx, p entity.Posting) (string, error) {
+	var id string
+	err := tx.QueryRowContext(ctx, `SELECT id FROM ledger_accounts WHERE code = $1 AND currency = $2`, p.AccountCode, p.Currency).Scan(&id)
+	if err != nil && err != sql.ErrNoRows {
+		return "", wrapDBError(err)
+	}
+
+	return id, err
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/pkg/errors"
+)
+
+// ClaimLoginAttempt counts a login as failed on every key before its password is checked, and puts
+// the block that failure calls for in place right away, so concurrent guesses can't get past the
+// throttle. It is refused with nothing counted while any of the keys is blocked.
+func (r *UserRepositoryImpl) ClaimLoginAttempt(ctx context.Context, payload entity.ClaimLoginAttempt) ([]entity.LoginThrottleCounter, int, error) {
+	var counters []entity.LoginThrottleCounter
+	code, err := withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		counters = make([]entity.LoginThrottleCounter, 0, len(payload.Keys))
+		// keys come in the same order on every login, so concurrent claims cannot deadlock
+		for _, key := range payload.Keys {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO login_throttles (scope, throttle_key, failed_attempts, last_failed_at)
+				VALUES ($1, $2, 0, $3)
+				ON CONFLICT (scope, throttle_key) DO NOTHING
+			`, key.Scope, key.Key, payload.Now)
+			if err != nil {
+				return http.StatusInternalServerError, wrapDBError(err)
+			}
+
+			var (
+				failures     int
+				lastFailedAt int64
+				blockedUntil int64
+			)
+			err = tx.QueryRowContext(ctx, `
+				SELECT failed_attempts, last_failed_at, COALESCE(blocked_until, 0)
+				FROM login_throttles WHERE scope = $1 AND throttle_key = $2 FOR UPDATE
+			`, key.Scope, key.Key).Scan(&failures, &lastFailedAt, &blockedUntil)
+			if err != nil {
+				return http.StatusInternalServerError, wrapDBError(err)
+			}
+
+			if blockedUntil > payload.Now {
+				return http.StatusTooManyRequests, errors.Wrap(errorer.ErrLoginThrottled, errorer.ErrLoginThrottled.Error())
+			}
+			if lastFailedAt < payload.WindowStart {
+				failures = 0
+			}
+
+			counter := entity.LoginThrottleCounter{Key: key, FailedAttempts: failures + 1}
+			if delay := payload.Throttles[key.Scope].Delay(counter.FailedAttempts); delay > 0 {
+				counter.BlockedUntil = payload.Now + delay.Milliseconds()
+			}
+
+			var blocked sql.NullInt64
+			if counter.BlockedUntil > 0 {
+				blocked = sql.NullInt64{Int64: counter.BlockedUntil, Valid: true}
+			}
+			_, err = tx.ExecContext(ctx, `
+				UPDATE login_throttles SET failed_attempts = $1, last_failed_at = $2, blocked_until = $3
+				WHERE scope = $4 AND throttle_key = $5
+			`, counter.FailedAttempts, payload.Now, blocked, key.Scope, key.Key)
+			if err != nil {
+				return http.StatusInternalServerError, wrapDBError(err)
+			}
+
+			counters = append(counters, counter)
+		}
+
+		return http.StatusOK, nil
+	})
+	if err != nil {
+		return nil, code, err
+	}
+
+	return counters, code, nil
+}
+
+// ReleaseLoginAttempt takes back an attempt claimed on counter once it turned out to be a correct
+// login, along with the block the claim put in place unless a later claim replaced it
+func (r *UserRepositoryImpl) ReleaseLoginAttempt(ctx context.Context, counter entity.LoginThrottleCounter) (int, error) {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE login_throttles SET
+			failed_attempts = GREATEST(failed_attempts - 1, 0),
+			blocked_until = CASE WHEN blocked_until = $1 THEN NULL ELSE blocked_until END
+		WHERE scope = $2 AND throttle_key = $3
+	`, counter.BlockedUntil, counter.Key.Scope, counter.Key.Key)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return http.StatusOK, nil
+}
+
+func (r *UserRepositoryImpl) ResetLoginFailures(ctx context.Context, key entity.LoginThrottleKey) (int, error) {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM login_throttles WHERE scope = $1 AND throttle_key = $2`, key.Scope, key.Key)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return http.StatusOK, nil
+}
+
+// UnlockLogin lifts the lockout of an account and forgets its failed logins
+func (r *UserRepositoryImpl) UnlockLogin(ctx context.Context, payload entity.UnlockLogin) (int, error) {
+	return withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		_, err := tx.ExecContext(ctx, `DELETE FROM login_throttles WHERE scope = $1 AND throttle_key = $2`,
+			entity.LoginThrottleScopeAccount, payload.Email)
+		if err != nil {
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+
+		err = insertAuditLog(ctx, tx, entity.AuditLog{
+			ActorID:    payload.AdminID,
+			Action:     entity.AuditActionLoginUnlocked,
+			TargetType: entity.AuditTargetUser,
+			TargetID:   payload.UserID,
+			CreatedAt:  payload.UnlockedAt,
+		})
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		return http.StatusOK, nil
+	})
+}
+
+// DeleteStaleLoginThrottles drops counters with no failure since windowStart that no longer block anything
+func (r *UserRepositoryImpl) DeleteStaleLoginThrottles(ctx context.Context, windowStart int64, now int64) (int64, int, error) {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM login_throttles WHERE last_failed_at < $1 AND COALESCE(blocked_until, 0) <= $2
+	`, windowStart, now)
+	if err != nil {
+		return 0, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	n, _ := res.RowsAffected()
+
+	return n, http.StatusOK, nil
+}
@@ -0,0 +1,315 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/pkg/errors"
+)
+
+const userMfaColumns = `user_id, secret, COALESCE(enabled_at, 0), last_used_step, failed_attempts, COALESCE(locked_until, 0), created_at, updated_at`
+
+func (r *UserRepositoryImpl) GetMfa(ctx context.Context, userID string) (*entity.UserMfa, int, error) {
+	mfa := entity.UserMfa{}
+	err := r.db.QueryRowContext(ctx, `SELECT `+userMfaColumns+` FROM user_mfa WHERE user_id = $1`, userID).Scan(
+		&mfa.UserID, &mfa.Secret, &mfa.EnabledAt, &mfa.LastUsedStep, &mfa.FailedAttempts, &mfa.LockedUntil, &mfa.CreatedAt, &mfa.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, http.StatusNotFound, errors.Wrap(errorer.ErrMfaNotEnrolled, errorer.ErrMfaNotEnrolled.Error())
+		}
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return &mfa, http.StatusOK, nil
+}
+
+// SaveMfaSecret starts or restarts an enrollment, the secret of an enabled one is never replaced
+func (r *UserRepositoryImpl) SaveMfaSecret(ctx context.Context, mfa entity.UserMfa) (int, error) {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO user_mfa (user_id, secret, created_at, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_used_step = 0, updated_at = EXCLUDED.updated_at
+		WHERE user_mfa.enabled_at IS NULL
+	`, mfa.UserID, mfa.Secret, mfa.CreatedAt, mfa.UpdatedAt)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return http.StatusConflict, errors.Wrap(errorer.ErrMfaAlreadyEnabled, errorer.ErrMfaAlreadyEnabled.Error())
+	}
+
+	return http.StatusOK, nil
+}
+
+func (r *UserRepositoryImpl) EnableMfa(ctx context.Context, payload entity.EnableMfa) (int, error) {
+	return withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE user_mfa SET enabled_at = $1, last_used_step = $2, updated_at = $1
+			WHERE user_id = $3 AND enabled_at IS NULL AND last_used_step < $2
+		`, payload.EnabledAt, payload.Step, payload.UserID)
+		if err != nil {
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			// lost to a concurrent confirmation or the code was already used
+			return http.StatusBadRequest, errors.Wrap(errorer.ErrInvalidMfaCode, errorer.ErrInvalidMfaCode.Error())
+		}
+
+		code, err := replaceRecoveryCodes(ctx, tx, payload.UserID, payload.RecoveryCodes)
+		if err != nil {
+			return code, err
+		}
+
+		err = insertAuditLog(ctx, tx, entity.AuditLog{
+			ActorID:    payload.UserID,
+			Action:     entity.AuditActionMfaEnabled,
+			TargetType: entity.AuditTargetUser,
+			TargetID:   payload.UserID,
+			CreatedAt:  payload.EnabledAt,
+		})
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		return http.StatusOK, nil
+	})
+}
+
+// DisableMfa removes the enrollment along with its recovery codes and pending challenges
+func (r *UserRepositoryImpl) DisableMfa(ctx context.Context, userID string, disabledAt int64) (int, error) {
+	return withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		res, err := tx.ExecContext(ctx, `DELETE FROM user_mfa WHERE user_id = $1 AND enabled_at IS NOT NULL`, userID)
+		if err != nil {
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return http.StatusConflict, errors.Wrap(errorer.ErrMfaNotEnabled, errorer.ErrMfaNotEnabled.Error())
+		}
+
+		for _, query := range []string{
+			`DELETE FROM mfa_recovery_codes WHERE user_id = $1`,
+			`DELETE FROM mfa_challenges WHERE user_id = $1`,
+		} {
+			if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+				return http.StatusInternalServerError, wrapDBError(err)
+			}
+		}
+
+		err = insertAuditLog(ctx, tx, entity.AuditLog{
+			ActorID:    userID,
+			Action:     entity.AuditActionMfaDisabled,
+			TargetType: entity.AuditTargetUser,
+			TargetID:   userID,
+			CreatedAt:  disabledAt,
+		})
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		return http.StatusOK, nil
+	})
+}
+
+// UseMfaStep accepts a TOTP code of step once, codes of the same or an earlier step are rejected after it
+func (r *UserRepositoryImpl) UseMfaStep(ctx context.Context, userID string, step int64) (int, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE user_mfa SET last_used_step = $1 WHERE user_id = $2 AND enabled_at IS NOT NULL AND last_used_step < $1
+	`, step, userID)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return http.StatusUnauthorized, errors.Wrap(errorer.ErrInvalidMfaCode, errorer.ErrInvalidMfaCode.Error())
+	}
+
+	return http.StatusOK, nil
+}
+
+// ClaimMfaAttempt counts a code entered for the user's enabled 2FA before it is checked, so concurrent
+// guesses can't get past the limit. The returned enrollment's FailedAttempts includes the claimed attempt.
+func (r *UserRepositoryImpl) ClaimMfaAttempt(ctx context.Context, payload entity.ClaimMfaAttempt) (*entity.UserMfa, int, error) {
+	mfa := entity.UserMfa{}
+	code, err := withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		err := tx.QueryRowContext(ctx, `SELECT `+userMfaColumns+` FROM user_mfa WHERE user_id = $1 AND enabled_at IS NOT NULL FOR UPDATE`, payload.UserID).Scan(
+			&mfa.UserID, &mfa.Secret, &mfa.EnabledAt, &mfa.LastUsedStep, &mfa.FailedAttempts, &mfa.LockedUntil, &mfa.CreatedAt, &mfa.UpdatedAt,
+		)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return http.StatusConflict, errors.Wrap(errorer.ErrMfaNotEnabled, errorer.ErrMfaNotEnabled.Error())
+			}
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+
+		if mfa.LockedUntil > payload.Now {
+			return http.StatusLocked, errors.Wrap(errorer.ErrMfaLocked, errorer.ErrMfaLocked.Error())
+		}
+		if mfa.LockedUntil != 0 {
+			// the lockout ran out, the user gets a fresh set of attempts
+			mfa.FailedAttempts = 0
+			mfa.LockedUntil = 0
+		}
+		if mfa.FailedAttempts >= payload.MaxAttempts {
+			// the last attempts are still being checked, the one that fails last locks code entry
+			return http.StatusLocked, errors.Wrap(errorer.ErrMfaLocked, errorer.ErrMfaLocked.Error())
+		}
+
+		mfa.FailedAttempts++
+		_, err = tx.ExecContext(ctx, `UPDATE user_mfa SET failed_attempts = $1, locked_until = NULL WHERE user_id = $2`,
+			mfa.FailedAttempts, payload.UserID)
+		if err != nil {
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+
+		return http.StatusOK, nil
+	})
+	if err != nil {
+		return nil, code, err
+	}
+
+	return &mfa, code, nil
+}
+
+// ResetMfaAttempts clears the failed attempts after a correct code
+func (r *UserRepositoryImpl) ResetMfaAttempts(ctx context.Context, userID string) (int, error) {
+	_, err := r.db.ExecContext(ctx, `UPDATE user_mfa SET failed_attempts = 0 WHERE user_id = $1 AND locked_until IS NULL`, userID)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return http.StatusOK, nil
+}
+
+func (r *UserRepositoryImpl) LockMfa(ctx context.Context, userID string, lockedAt int64, lockedUntil int64) (int, error) {
+	return withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		_, err := tx.ExecContext(ctx, `UPDATE user_mfa SET failed_attempts = 0, locked_until = $1 WHERE user_id = $2`, lockedUntil, userID)
+		if err != nil {
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+
+		err = insertAuditLog(ctx, tx, entity.AuditLog{
+			ActorID:    userID,
+			Action:     entity.AuditActionMfaLocked,
+			TargetType: entity.AuditTargetUser,
+			TargetID:   userID,
+			Metadata:   map[string]interface{}{"lockedUntil": lockedUntil},
+			CreatedAt:  lockedAt,
+		})
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		return http.StatusOK, nil
+	})
+}
+
+func (r *UserRepositoryImpl) UseMfaRecoveryCode(ctx context.Context, userID string, codeHash string, usedAt int64) (int, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE mfa_recovery_codes SET used_at = $1 WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL
+	`, usedAt, userID, codeHash)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return http.StatusUnauthorized, errors.Wrap(errorer.ErrInvalidMfaCode, errorer.ErrInvalidMfaCode.Error())
+	}
+
+	return http.StatusOK, nil
+}
+
+// ReplaceMfaRecoveryCodes invalidates every recovery code of the user in favour of a new set
+func (r *UserRepositoryImpl) ReplaceMfaRecoveryCodes(ctx context.Context, payload entity.ReplaceMfaRecoveryCodes) (int, error) {
+	return withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		code, err := replaceRecoveryCodes(ctx, tx, payload.UserID, payload.RecoveryCodes)
+		if err != nil {
+			return code, err
+		}
+
+		err = insertAuditLog(ctx, tx, entity.AuditLog{
+			ActorID:    payload.UserID,
+			Action:     entity.AuditActionMfaRecoveryReissued,
+			TargetType: entity.AuditTargetUser,
+			TargetID:   payload.UserID,
+			CreatedAt:  payload.CreatedAt,
+		})
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		return http.StatusOK, nil
+	})
+}
+
+func (r *UserRepositoryImpl) CreateMfaChallenge(ctx context.Context, challenge entity.MfaChallenge) (int, error) {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO mfa_challenges (id, user_id, token_hash, expires_at, created_at) VALUES ($1, $2, $3, $4, $5)
+	`, challenge.ID, challenge.UserID, challenge.TokenHash, challenge.ExpiresAt, challenge.CreatedAt)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return http.StatusCreated, nil
+}
+
+// ClaimMfaChallengeAttempt counts a code entered against the challenge before it is checked, so
+// concurrent guesses can't get past maxAttempts. A challenge out of attempts is treated as expired.
+func (r *UserRepositoryImpl) ClaimMfaChallengeAttempt(ctx context.Context, tokenHash string, now int64, maxAttempts int) (*entity.MfaChallenge, int, error) {
+	challenge := entity.MfaChallenge{}
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE mfa_challenges SET attempts = attempts + 1
+		WHERE token_hash = $1 AND expires_at > $2 AND attempts < $3
+		RETURNING id, user_id, token_hash, attempts, expires_at, created_at
+	`, tokenHash, now, maxAttempts).Scan(&challenge.ID, &challenge.UserID, &challenge.TokenHash, &challenge.Attempts, &challenge.ExpiresAt, &challenge.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, http.StatusUnauthorized, errors.Wrap(errorer.ErrInvalidMfaChallenge, errorer.ErrInvalidMfaChallenge.Error())
+		}
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return &challenge, http.StatusOK, nil
+}
+
+// DeleteMfaChallenge consumes a challenge, only one caller gets to consume it
+func (r *UserRepositoryImpl) DeleteMfaChallenge(ctx context.Context, id string) (int, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM mfa_challenges WHERE id = $1`, id)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return http.StatusUnauthorized, errors.Wrap(errorer.ErrInvalidMfaChallenge, errorer.ErrInvalidMfaChallenge.Error())
+	}
+
+	return http.StatusOK, nil
+}
+
+func (r *UserRepositoryImpl) DeleteExpiredMfaChallenges(ctx context.Context, now int64) (int64, int, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM mfa_challenges WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	n, _ := res.RowsAffected()
+
+	return n, http.StatusOK, nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID string, codes []entity.MfaRecoveryCode) (int, error) {
+	_, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return http.StatusInternalServerError, wrapDBError(err)
+	}
+
+	for _, v := range codes {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO mfa_recovery_codes (id, user_id, code_hash, created_at) VALUES ($1, $2, $3, $4)
+		`, v.ID, userID, v.CodeHash, v.CreatedAt)
+		if err != nil {
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+	}
+
+	return http.StatusOK, nil
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/pkg/errors"
+)
+
+// SetPin sets or replaces the transaction PIN of a user, which also lifts a lockout
+func (r *UserRepositoryImpl) SetPin(ctx context.Context, pin entity.UserPin) (int, error) {
+	return withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO user_pins (user_id, pin_hash, created_at, updated_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id) DO UPDATE SET
+				pin_hash = EXCLUDED.pin_hash, failed_attempts = 0, locked_until = NULL, updated_at = EXCLUDED.updated_at
+		`, pin.UserID, pin.PinHash, pin.CreatedAt, pin.UpdatedAt)
+		if err != nil {
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+
+		err = insertAuditLog(ctx, tx, entity.AuditLog{
+			ActorID:    pin.UserID,
+			Action:     entity.AuditActionPinSet,
+			TargetType: entity.AuditTargetUser,
+			TargetID:   pin.UserID,
+			CreatedAt:  pin.UpdatedAt,
+		})
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		return http.StatusOK, nil
+	})
+}
+
+// ClaimPinAttempt counts a PIN entry before it is checked, so concurrent guesses can't get past the
+// limit. The returned PIN's FailedAttempts includes the claimed attempt.
+func (r *UserRepositoryImpl) ClaimPinAttempt(ctx context.Context, payload entity.ClaimPinAttempt) (*entity.UserPin, int, error) {
+	pin := entity.UserPin{}
+	code, err := withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		err := tx.QueryRowContext(ctx, `
+			SELECT user_id, pin_hash, failed_attempts, COALESCE(locked_until, 0), created_at, updated_at
+			FROM user_pins WHERE user_id = $1 FOR UPDATE
+		`, payload.UserID).Scan(&pin.UserID, &pin.PinHash, &pin.FailedAttempts, &pin.LockedUntil, &pin.CreatedAt, &pin.UpdatedAt)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return http.StatusForbidden, errors.Wrap(errorer.ErrPinNotSet, errorer.ErrPinNotSet.Error())
+			}
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+
+		if pin.LockedUntil > payload.Now {
+			return http.StatusLocked, errors.Wrap(errorer.ErrPinLocked, errorer.ErrPinLocked.Error())
+		}
+		if pin.LockedUntil != 0 {
+			// the lockout ran out, the user gets a fresh set of attempts
+			pin.FailedAttempts = 0
+			pin.LockedUntil = 0
+		}
+		if pin.FailedAttempts >= payload.MaxAttempts {
+			// the last attempts are still being checked, the one that fails last locks the PIN
+			return http.StatusLocked, errors.Wrap(errorer.ErrPinLocked, errorer.ErrPinLocked.Error())
+		}
+
+		pin.FailedAttempts++
+		_, err = tx.ExecContext(ctx, `UPDATE user_pins SET failed_attempts = $1, locked_until = NULL WHERE user_id = $2`,
+			pin.FailedAttempts, payload.UserID)
+		if err != nil {
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+
+		return http.StatusOK, nil
+	})
+	if err != nil {
+		return nil, code, err
+	}
+
+	return &pin, code, nil
+}
+
+// ResetPinAttempts clears the failed attempts after a correct PIN
+func (r *UserRepositoryImpl) ResetPinAttempts(ctx context.Context, userID string) (int, error) {
+	_, err := r.db.ExecContext(ctx, `UPDATE user_pins SET failed_attempts = 0 WHERE user_id = $1 AND locked_until IS NULL`, userID)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return http.StatusOK, nil
+}
+
+func (r *UserRepositoryImpl) LockPin(ctx context.Context, userID string, lockedAt int64, lockedUntil int64) (int, error) {
+	return withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		_, err := tx.ExecContext(ctx, `UPDATE user_pins SET failed_attempts = 0, locked_until = $1 WHERE user_id = $2`, lockedUntil, userID)
+		if err != nil {
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+
+		err = insertAuditLog(ctx, tx, entity.AuditLog{
+			ActorID:    userID,
+			Action:     entity.AuditActionPinLocked,
+			TargetType: entity.AuditTargetUser,
+			TargetID:   userID,
+			Metadata:   map[string]interface{}{"lockedUntil": lockedUntil},
+			CreatedAt:  lockedAt,
+		})
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		return http.StatusOK, nil
+	})
+}
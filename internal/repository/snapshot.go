@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/pkg/errors"
+)
+
+const snapshotInterval = int64(24 * time.Hour / time.Millisecond)
+
+// CreateBalanceSnapshots records every wallet's balance at each UTC midnight up to until that has no snapshot yet.
+// Each day is built from the day before plus that day's history, the first one from the whole history.
+func (r *BalanceRepositoryImpl) CreateBalanceSnapshots(ctx context.Context, until int64) (int, int, error) {
+	until -= until % snapshotInterval
+
+	var latest int64
+	err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(snapshot_at), 0) FROM balance_snapshots`).Scan(&latest)
+	if err != nil {
+		return 0, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	next := until
+	if latest > 0 {
+		next = latest + snapshotInterval
+	}
+
+	days := 0
+	for ; next <= until; next += snapshotInterval {
+		_, err := r.db.ExecContext(ctx, `
+			INSERT INTO balance_snapshots (user_id, currency, snapshot_at, balance)
+			SELECT user_id, currency, $1, SUM(balance) FROM (
+				SELECT user_id, currency, balance FROM balance_snapshots WHERE snapshot_at = $2
+				UNION ALL
+				SELECT user_id, currency, balance FROM balances_history WHERE created_at >= $2 AND created_at < $1
+			) AS balances
+			GROUP BY user_id, currency
+			ON CONFLICT (user_id, currency, snapshot_at) DO NOTHING
+		`, next, latest)
+		if err != nil {
+			return days, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+		}
+		latest = next
+		days++
+	}
+
+	return days, http.StatusOK, nil
+}
+
+// GetBalancesAt returns the user's balance per currency as of at, inclusive, starting from the latest snapshot before it
+func (r *BalanceRepositoryImpl) GetBalancesAt(ctx context.Context, userID string, at int64) ([]entity.Balance, int, error) {
+	var snapshotAt int64
+	err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(snapshot_at), 0) FROM balance_snapshots WHERE snapshot_at <= $1`, at).Scan(&snapshotAt)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT currency, SUM(balance) FROM (
+			SELECT currency, balance FROM balance_snapshots WHERE user_id = $1 AND snapshot_at = $2
+			UNION ALL
+			SELECT currency, balance FROM balances_history WHERE user_id = $1 AND created_at >= $2 AND created_at <= $3
+		) AS balances
+		GROUP BY currency
+		ORDER BY currency
+	`, userID, snapshotAt, at)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	defer rows.Close()
+
+	var balances []entity.Balance
+	for rows.Next() {
+		balance := entity.Balance{UserID: userID}
+		if err := rows.Scan(&balance.Currency, &balance.Balance); err != nil {
+			return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+		}
+		balances = append(balances, balance)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return balances, http.StatusOK, nil
+}
@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/pkg/errors"
+)
+
+const standingOrderColumns = `id, user_id, description, schedule_type, cron_expression, timezone, start_at, amount, currency, bank_account_number, bank_name, COALESCE(beneficiary_id, ''), status, COALESCE(next_run_at, 0), attempts, COALESCE(last_run_at, 0), COALESCE(last_transaction_id, ''), last_error, COALESCE(last_failed_at, 0), created_at, updated_at`
+
+func (r *BalanceRepositoryImpl) CreateStandingOrder(ctx context.Context, order entity.StandingOrder) (int, error) {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO standing_orders (id, user_id, description, schedule_type, cron_expression, timezone, start_at, amount, currency, bank_account_number, bank_name, beneficiary_id, status, next_run_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	`,
+		order.ID,
+		order.UserID,
+		order.Description,
+		order.ScheduleType,
+		order.CronExpression,
+		order.Timezone,
+		order.StartAt,
+		order.Amount,
+		order.Currency,
+		order.BankAccountNumber,
+		order.BankName,
+		nullableString(order.BeneficiaryID),
+		order.Status,
+		nullableInt64(order.NextRunAt),
+		order.CreatedAt,
+		order.UpdatedAt,
+	)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return http.StatusCreated, nil
+}
+
+func (r *BalanceRepositoryImpl) GetStandingOrders(ctx context.Context, userID string) ([]entity.StandingOrder, int, error) {
+	var orders []entity.StandingOrder
+	rows, err := r.db.QueryContext(ctx, `SELECT `+standingOrderColumns+` FROM standing_orders WHERE user_id = $1 ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		order, err := scanStandingOrder(rows)
+		if err != nil {
+			return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+		}
+		orders = append(orders, *order)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return orders, http.StatusOK, nil
+}
+
+func (r *BalanceRepositoryImpl) GetStandingOrder(ctx context.Context, id string, userID string) (*entity.StandingOrder, int, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+standingOrderColumns+` FROM standing_orders WHERE id = $1 AND user_id = $2`, id, userID)
+	order, err := scanStandingOrder(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, http.StatusNotFound, errors.Wrap(errorer.ErrStandingOrderNotFound, errorer.ErrStandingOrderNotFound.Error())
+		}
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return order, http.StatusOK, nil
+}
+
+// UpdateStandingOrder saves the user editable parts of an order, completed and cancelled orders cannot change anymore
+func (r *BalanceRepositoryImpl) UpdateStandingOrder(ctx context.Context, order entity.StandingOrder) (int, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE standing_orders SET
+			description = $1, schedule_type = $2, cron_expression = $3, timezone = $4, start_at = $5,
+			amount = $6, currency = $7, bank_account_number = $8, bank_name = $9, beneficiary_id = $10,
+			status = $11, next_run_at = $12, attempts = 0, updated_at = $13
+		WHERE id = $14 AND user_id = $15 AND status NOT IN ($16, $17)
+	`,
+		order.Description, order.ScheduleType, order.CronExpression, order.Timezone, order.StartAt,
+		order.Amount, order.Currency, order.BankAccountNumber, order.BankName, nullableString(order.BeneficiaryID),
+		order.Status, nullableInt64(order.NextRunAt), order.UpdatedAt,
+		order.ID, order.UserID, entity.StandingOrderStatusCompleted, entity.StandingOrderStatusCancelled,
+	)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return http.StatusConflict, errors.Wrap(errorer.ErrStandingOrderClosed, errorer.ErrStandingOrderClosed.Error())
+	}
+
+	return http.StatusOK, nil
+}
+
+// ClaimStandingOrders leases due active orders to the calling scheduler. The returned orders keep the
+// run they are due for in NextRunAt, while the stored one moves to the end of the lease.
+func (r *BalanceRepositoryImpl) ClaimStandingOrders(ctx context.Context, payload entity.ClaimStandingOrders) ([]entity.StandingOrder, int, error) {
+	var orders []entity.StandingOrder
+	rows, err := r.db.QueryContext(ctx, `
+		WITH due AS (
+			SELECT id, next_run_at FROM standing_orders WHERE status = $1 AND next_run_at <= $2
+			ORDER BY next_run_at LIMIT $3 FOR UPDATE SKIP LOCKED
+		)
+		UPDATE standing_orders s SET next_run_at = $4
+		FROM due WHERE s.id = due.id
+		RETURNING s.id, s.user_id, s.description, s.schedule_type, s.cron_expression, s.timezone, s.start_at, s.amount, s.currency,
+			s.bank_account_number, s.bank_name, COALESCE(s.beneficiary_id, ''), s.status, due.next_run_at, s.attempts, COALESCE(s.last_run_at, 0),
+			COALESCE(s.last_transaction_id, ''), s.last_error, COALESCE(s.last_failed_at, 0), s.created_at, s.updated_at
+	`, entity.StandingOrderStatusActive, payload.Now, payload.Limit, payload.LeaseUntil)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		order, err := scanStandingOrder(rows)
+		if err != nil {
+			return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+		}
+		orders = append(orders, *order)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return orders, http.StatusOK, nil
+}
+
+// ExecuteStandingOrder creates the run's transaction and schedules the next run in one database transaction,
+// so a run is never booked twice nor skipped
+func (r *BalanceRepositoryImpl) ExecuteStandingOrder(ctx context.Context, payload entity.ExecuteStandingOrder) (int, error) {
+	return withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE standing_orders SET
+				status = $1, next_run_at = $2, attempts = 0, last_run_at = $3, last_transaction_id = $4, last_error = '', updated_at = $3
+			WHERE id = $5 AND status = $6 AND next_run_at = $7
+		`,
+			payload.Status, nullableInt64(payload.NextRunAt), payload.Transaction.CreatedAt, payload.Transaction.ID,
+			payload.OrderID, entity.StandingOrderStatusActive, payload.LeasedUntil,
+		)
+		if err != nil {
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return http.StatusConflict, errors.Wrap(errorer.ErrStandingOrderChanged, errorer.ErrStandingOrderChanged.Error())
+		}
+
+		return r.createOutboundTransaction(ctx, tx, payload.Transaction, payload.Checks)
+	})
+}
+
+// FailStandingOrder records why a run failed and when it is tried next
+func (r *BalanceRepositoryImpl) FailStandingOrder(ctx context.Context, payload entity.FailStandingOrder) (int, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE standing_orders SET
+			status = $1, next_run_at = $2, attempts = $3, last_error = $4, last_failed_at = $5, updated_at = $5
+		WHERE id = $6 AND status = $7 AND next_run_at = $8
+	`,
+		payload.Status, nullableInt64(payload.NextRunAt), payload.Attempts, payload.Error, payload.FailedAt,
+		payload.OrderID, entity.StandingOrderStatusActive, payload.LeasedUntil,
+	)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return http.StatusConflict, errors.Wrap(errorer.ErrStandingOrderChanged, errorer.ErrStandingOrderChanged.Error())
+	}
+
+	return http.StatusOK, nil
+}
+
+func scanStandingOrder(row rowScanner) (*entity.StandingOrder, error) {
+	order := entity.StandingOrder{}
+	err := row.Scan(
+		&order.ID, &order.UserID, &order.Description, &order.ScheduleType, &order.CronExpression, &order.Timezone, &order.StartAt,
+		&order.Amount, &order.Currency, &order.BankAccountNumber, &order.BankName, &order.BeneficiaryID, &order.Status, &order.NextRunAt, &order.Attempts,
+		&order.LastRunAt, &order.LastTransactionID, &order.LastError, &order.LastFailedAt, &order.CreatedAt, &order.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+// nullableInt64 stores zero as NULL
+func nullableInt64(v int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: v, Valid: v != 0}
+}
+
+// nullableString stores an empty string as NULL
+func nullableString(v string) sql.NullString {
+	return sql.NullString{String: v, Valid: v != ""}
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token entity.RefreshToken) (int, error)
+	// RotateRefreshToken marks the presented token used and stores its successor. Presenting a token that
+	// was already used revokes its whole family, since either the client or an attacker holds a stolen copy.
+	RotateRefreshToken(ctx context.Context, payload entity.RotateRefreshToken) (*entity.RefreshToken, int, error)
+	// RevokeRefreshToken revokes the family of the user's token, signing out every session of that login
+	RevokeRefreshToken(ctx context.Context, payload entity.RevokeRefreshToken) (int, error)
+	RevokeAccessToken(ctx context.Context, payload entity.RevokeAccessToken) (int, error)
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, int, error)
+	DeleteExpiredTokens(ctx context.Context, now int64) (int64, int, error)
+}
+
+func NewTokenRepository(logger zerolog.Logger, db *sql.DB) TokenRepository {
+	return &TokenRepositoryImpl{
+		logger: logger,
+		db:     db,
+	}
+}
+
+type TokenRepositoryImpl struct {
+	logger zerolog.Logger
+	db     *sql.DB
+}
+
+const refreshTokenColumns = `id, family_id, user_id, token_hash, expires_at, created_at, COALESCE(used_at, 0), COALESCE(revoked_at, 0)`
+
+func (r *TokenRepositoryImpl) CreateRefreshToken(ctx context.Context, token entity.RefreshToken) (int, error) {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (id, family_id, user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, token.ID, token.FamilyID, token.UserID, token.TokenHash, token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return http.StatusCreated, nil
+}
+
+func (r *TokenRepositoryImpl) RotateRefreshToken(ctx context.Context, payload entity.RotateRefreshToken) (*entity.RefreshToken, int, error) {
+	next := payload.Next
+	reused := false
+	code, err := withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		reused = false
+		row := tx.QueryRowContext(ctx, `SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE`, payload.TokenHash)
+		current, err := scanRefreshToken(row)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return http.StatusUnauthorized, errors.Wrap(errorer.ErrInvalidRefreshToken, errorer.ErrInvalidRefreshToken.Error())
+			}
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+
+		if current.UsedAt != 0 {
+			// the family is revoked in this transaction, the caller still gets an error once it commits
+			reused = true
+			return revokeRefreshTokenFamily(ctx, tx, current.FamilyID, next.CreatedAt)
+		}
+		if current.RevokedAt != 0 || current.ExpiresAt <= next.CreatedAt {
+			return http.StatusUnauthorized, errors.Wrap(errorer.ErrInvalidRefreshToken, errorer.ErrInvalidRefreshToken.Error())
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE refresh_tokens SET used_at = $1 WHERE id = $2`, next.CreatedAt, current.ID)
+		if err != nil {
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+
+		next.FamilyID = current.FamilyID
+		next.UserID = current.UserID
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO refresh_tokens (id, family_id, user_id, token_hash, expires_at, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, next.ID, next.FamilyID, next.UserID, next.TokenHash, next.ExpiresAt, next.CreatedAt)
+		if err != nil {
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+
+		return http.StatusOK, nil
+	})
+	if err != nil {
+		return nil, code, err
+	}
+	if reused {
+		return nil, http.StatusUnauthorized, errors.Wrap(errorer.ErrRefreshTokenReused, errorer.ErrRefreshTokenReused.Error())
+	}
+
+	return &next, code, nil
+}
+
+func (r *TokenRepositoryImpl) RevokeRefreshToken(ctx context.Context, payload entity.RevokeRefreshToken) (int, error) {
+	return withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		var familyID string
+		err := tx.QueryRowContext(ctx, `SELECT family_id FROM refresh_tokens WHERE token_hash = $1 AND user_id = $2`,
+			payload.TokenHash, payload.UserID).Scan(&familyID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return http.StatusUnauthorized, errors.Wrap(errorer.ErrInvalidRefreshToken, errorer.ErrInvalidRefreshToken.Error())
+			}
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+
+		return revokeRefreshTokenFamily(ctx, tx, familyID, payload.RevokedAt)
+	})
+}
+
+func (r *TokenRepositoryImpl) RevokeAccessToken(ctx context.Context, payload entity.RevokeAccessToken) (int, error) {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO revoked_access_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING`,
+		payload.JTI, payload.ExpiresAt)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return http.StatusOK, nil
+}
+
+func (r *TokenRepositoryImpl) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, int, error) {
+	var revoked bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM revoked_access_tokens WHERE jti = $1)`, jti).Scan(&revoked)
+	if err != nil {
+		return false, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return revoked, http.StatusOK, nil
+}
+
+// DeleteExpiredTokens drops refresh tokens and denylist entries that could no longer be used anyway
+func (r *TokenRepositoryImpl) DeleteExpiredTokens(ctx context.Context, now int64) (int64, int, error) {
+	var deleted int64
+	for _, query := range []string{
+		`DELETE FROM refresh_tokens WHERE expires_at <= $1`,
+		`DELETE FROM revoked_access_tokens WHERE expires_at <= $1`,
+	} {
+		res, err := r.db.ExecContext(ctx, query, now)
+		if err != nil {
+			return deleted, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+		}
+		n, _ := res.RowsAffected()
+		deleted += n
+	}
+
+	return deleted, http.StatusOK, nil
+}
+
+func revokeRefreshTokenFamily(ctx context.Context, tx *sql.Tx, familyID string, revokedAt int64) (int, error) {
+	_, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = $1 WHERE family_id = $2 AND revoked_at IS NULL`, revokedAt, familyID)
+	if err != nil {
+		return http.StatusInternalServerError, wrapDBError(err)
+	}
+
+	return http.StatusOK, nil
+}
+
+func scanRefreshToken(row rowScanner) (*entity.RefreshToken, error) {
+	token := entity.RefreshToken{}
+	err := row.Scan(&token.ID, &token.FamilyID, &token.UserID, &token.TokenHash, &token.ExpiresAt, &token.CreatedAt, &token.UsedAt, &token.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/pkg/errors"
+)
+
+const topUpColumns = `id, user_id, amount, currency, proof_image_url, sender_bank_account_number, sender_bank_name, status, COALESCE(reviewer_id, ''), rejection_reason, COALESCE(reviewed_at, 0), created_at`
+
+// CreateTopUp records a top up waiting for review, the wallet is only credited once it is approved
+func (r *BalanceRepositoryImpl) CreateTopUp(ctx context.Context, topUp entity.TopUp) (int, error) {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO topups (id, user_id, amount, currency, proof_image_url, sender_bank_account_number, sender_bank_name, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`,
+		topUp.ID,
+		topUp.UserID,
+		topUp.Amount,
+		topUp.Currency,
+		topUp.ProofImageURL,
+		topUp.SenderBankAccountNumber,
+		topUp.SenderBankName,
+		topUp.Status,
+		topUp.CreatedAt,
+	)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return http.StatusCreated, nil
+}
+
+func (r *BalanceRepositoryImpl) GetTopUps(ctx context.Context, payload entity.GetTopUps) ([]entity.TopUp, int, error) {
+	var topUps []entity.TopUp
+	rows, err := r.db.QueryContext(ctx, `SELECT `+topUpColumns+` FROM topups WHERE status = $1 ORDER BY created_at LIMIT $2 OFFSET $3`,
+		payload.Status, payload.Limit, payload.Offset)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		topUp, err := scanTopUp(rows)
+		if err != nil {
+			return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+		}
+		topUps = append(topUps, *topUp)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return topUps, http.StatusOK, nil
+}
+
+// ReviewTopUp approves or rejects a pending top up. Approval credits the wallet, both outcomes are
+// written to the balance history and the audit log in the same database transaction.
+func (r *BalanceRepositoryImpl) ReviewTopUp(ctx context.Context, payload entity.ReviewTopUp) (*entity.TopUp, int, error) {
+	var topUp *entity.TopUp
+	code, err := withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		row := tx.QueryRowContext(ctx, `SELECT `+topUpColumns+` FROM topups WHERE id = $1 FOR UPDATE`, payload.TopUpID)
+		var err error
+		topUp, err = scanTopUp(row)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return http.StatusNotFound, errors.Wrap(errorer.ErrTopUpNotFound, errorer.ErrTopUpNotFound.Error())
+			}
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+
+		if topUp.Status != entity.TopUpStatusPendingReview {
+			return http.StatusConflict, errors.Wrap(errorer.ErrTopUpReviewed, errorer.ErrTopUpReviewed.Error())
+		}
+		if topUp.UserID == payload.ReviewerID {
+			return http.StatusForbidden, errors.Wrap(errorer.ErrTopUpSelfReview, errorer.ErrTopUpSelfReview.Error())
+		}
+
+		history := entity.BalanceHistory{
+			ID:                      common.GenerateULID(),
+			TransactionID:           topUp.ID,
+			UserID:                  topUp.UserID,
+			Currency:                topUp.Currency,
+			ProofImageURL:           topUp.ProofImageURL,
+			CreatedAt:               payload.ReviewedAt,
+			SourceBankAccountNumber: topUp.SenderBankAccountNumber,
+			SourceBankName:          topUp.SenderBankName,
+			Type:                    entity.BalanceHistoryTypeTopUpRejected,
+		}
+		action := entity.AuditActionTopUpRejected
+		topUp.Status = entity.TopUpStatusRejected
+		topUp.RejectionReason = payload.Reason
+
+		var balanceAfter int64
+		if payload.Approve {
+			balance, code, err := r.applyBalanceDelta(ctx, tx, topUp.UserID, topUp.Currency, topUp.Amount)
+			if err != nil {
+				return code, err
+			}
+			balanceAfter = balance.Balance
+
+			history.Balance = topUp.Amount
+			history.Type = entity.BalanceHistoryTypeTopUp
+			action = entity.AuditActionTopUpApproved
+			topUp.Status = entity.TopUpStatusApproved
+			topUp.RejectionReason = ""
+		} else {
+			// a rejection leaves the balance as it is, the row is locked so the recorded value stays accurate
+			err = tx.QueryRowContext(ctx, `SELECT balance FROM balances WHERE user_id = $1 AND currency = $2 FOR UPDATE`, topUp.UserID, topUp.Currency).Scan(&balanceAfter)
+			if err != nil && err != sql.ErrNoRows {
+				return http.StatusInternalServerError, wrapDBError(err)
+			}
+		}
+		if err := r.insertHistory(ctx, tx, history, balanceAfter); err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		if payload.Approve {
+			// money enters from the external bank once an admin has checked the proof of transfer
+			err = r.postJournal(ctx, tx, entity.Journal{
+				TransactionID: topUp.ID,
+				ReferenceID:   history.ID,
+				Description:   "top up",
+				CreatedAt:     payload.ReviewedAt,
+				Postings: []entity.Posting{
+					entity.SystemPosting(entity.LedgerAccountExternalBankInflow, entity.LedgerAccountTypeAsset, topUp.Currency, topUp.Amount),
+					entity.WalletPosting(topUp.UserID, topUp.Currency, topUp.Amount),
+				},
+			})
+			if err != nil {
+				return http.StatusInternalServerError, err
+			}
+		}
+
+		topUp.ReviewerID = payload.ReviewerID
+		topUp.ReviewedAt = payload.ReviewedAt
+		_, err = tx.ExecContext(ctx, `UPDATE topups SET status = $1, reviewer_id = $2, rejection_reason = $3, reviewed_at = $4 WHERE id = $5`,
+			topUp.Status, topUp.ReviewerID, topUp.RejectionReason, topUp.ReviewedAt, topUp.ID)
+		if err != nil {
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+
+		err = insertAuditLog(ctx, tx, entity.AuditLog{
+			ActorID:    payload.ReviewerID,
+			Action:     action,
+			TargetType: entity.AuditTargetTopUp,
+			TargetID:   topUp.ID,
+			Reason:     payload.Reason,
+			Metadata: map[string]interface{}{
+				"userId":   topUp.UserID,
+				"amount":   topUp.Amount,
+				"currency": topUp.Currency,
+			},
+			CreatedAt: payload.ReviewedAt,
+		})
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		return http.StatusOK, nil
+	})
+	if err != nil {
+		return nil, code, err
+	}
+
+	return topUp, code, nil
+}
+
+func scanTopUp(row rowScanner) (*entity.TopUp, error) {
+	topUp := entity.TopUp{}
+	err := row.Scan(
+		&topUp.ID, &topUp.UserID, &topUp.Amount, &topUp.Currency, &topUp.ProofImageURL,
+		&topUp.SenderBankAccountNumber, &topUp.SenderBankName, &topUp.Status, &topUp.ReviewerID,
+		&topUp.RejectionReason, &topUp.ReviewedAt, &topUp.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &topUp, nil
+}
@@ -0,0 +1,293 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/ovrrtd/openidea-bank/internal/model/money"
+	"github.com/pkg/errors"
+)
+
+const transactionColumns = `id, user_id, amount, currency, bank_account_number, bank_name, status, COALESCE(gateway_reference, ''), failure_reason, attempts, poll_errors, next_attempt_at, COALESCE(standing_order_id, ''), COALESCE(beneficiary_id, ''), COALESCE(settled_at, 0), created_at, updated_at`
+
+// CreateOutboundTransaction debits the wallet into payout clearing and queues the transfer for the bank.
+// The transaction must stay within the user's transfer limits and, for a recipient account still cooling off, its limit.
+func (r *BalanceRepositoryImpl) CreateOutboundTransaction(ctx context.Context, transaction entity.Transaction, checks entity.OutboundChecks) (int, error) {
+	return withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		return r.createOutboundTransaction(ctx, tx, transaction, checks)
+	})
+}
+
+func (r *BalanceRepositoryImpl) createOutboundTransaction(ctx context.Context, tx *sql.Tx, transaction entity.Transaction, checks entity.OutboundChecks) (int, error) {
+	balance, code, err := r.applyBalanceDelta(ctx, tx, transaction.UserID, transaction.Currency, -transaction.Amount)
+	if err != nil {
+		return code, err
+	}
+	// the debit above locked the balance row, so concurrent transactions in this currency are counted one after another
+	if code, err := r.checkRecipientCoolingOff(ctx, tx, transaction, checks.CoolingOff); err != nil {
+		return code, err
+	}
+	if code, err := r.checkTransferLimit(ctx, tx, transaction.UserID, transaction.Currency, transaction.Amount, checks.TransferLimit); err != nil {
+		return code, err
+	}
+
+	history := entity.BalanceHistory{
+		ID:                      common.GenerateULID(),
+		TransactionID:           transaction.ID,
+		UserID:                  transaction.UserID,
+		Balance:                 -transaction.Amount,
+		Currency:                transaction.Currency,
+		CreatedAt:               transaction.CreatedAt,
+		SourceBankAccountNumber: transaction.BankAccountNumber,
+		SourceBankName:          transaction.BankName,
+		Type:                    entity.BalanceHistoryTypeTransaction,
+		StandingOrderID:         transaction.StandingOrderID,
+	}
+	if err := r.insertHistory(ctx, tx, history, balance.Balance); err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	// the money waits in clearing until the bank settles or rejects the transfer
+	err = r.postJournal(ctx, tx, entity.Journal{
+		TransactionID: transaction.ID,
+		ReferenceID:   history.ID,
+		Description:   "transaction",
+		CreatedAt:     transaction.CreatedAt,
+		Postings: []entity.Posting{
+			entity.WalletPosting(transaction.UserID, transaction.Currency, -transaction.Amount),
+			entity.SystemPosting(entity.LedgerAccountPayoutClearing, entity.LedgerAccountTypeLiability, transaction.Currency, -transaction.Amount),
+		},
+	})
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO transactions (id, user_id, amount, currency, bank_account_number, bank_name, status, next_attempt_at, standing_order_id, beneficiary_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`,
+		transaction.ID,
+		transaction.UserID,
+		transaction.Amount,
+		transaction.Currency,
+		transaction.BankAccountNumber,
+		transaction.BankName,
+		transaction.Status,
+		transaction.NextAttemptAt,
+		nullableString(transaction.StandingOrderID),
+		nullableString(transaction.BeneficiaryID),
+		transaction.CreatedAt,
+		transaction.UpdatedAt,
+	)
+	if err != nil {
+		return http.StatusInternalServerError, wrapDBError(err)
+	}
+
+	return http.StatusOK, nil
+}
+
+func (r *BalanceRepositoryImpl) GetTransaction(ctx context.Context, id string, userID string) (*entity.Transaction, int, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+transactionColumns+` FROM transactions WHERE id = $1 AND user_id = $2`, id, userID)
+	transaction, err := scanTransaction(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, http.StatusNotFound, errors.Wrap(errorer.ErrTransactionNotFound, errorer.ErrTransactionNotFound.Error())
+		}
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return transaction, http.StatusOK, nil
+}
+
+// FindTransactionByID looks a transaction up regardless of its owner, for checks that do not act on behalf of a user
+func (r *BalanceRepositoryImpl) FindTransactionByID(ctx context.Context, id string) (*entity.Transaction, int, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+transactionColumns+` FROM transactions WHERE id = $1`, id)
+	transaction, err := scanTransaction(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, http.StatusNotFound, errors.Wrap(errorer.ErrTransactionNotFound, errorer.ErrTransactionNotFound.Error())
+		}
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return transaction, http.StatusOK, nil
+}
+
+// ClaimTransactions leases due transactions in a status to the calling worker
+func (r *BalanceRepositoryImpl) ClaimTransactions(ctx context.Context, payload entity.ClaimTransactions) ([]entity.Transaction, int, error) {
+	args := []interface{}{payload.LeaseUntil, payload.Status, payload.Now, payload.Limit}
+	settledFilter := ""
+	if payload.SettledAfter > 0 {
+		args = append(args, payload.SettledAfter)
+		settledFilter = "AND settled_at >= $5"
+	}
+
+	var transactions []entity.Transaction
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE transactions SET next_attempt_at = $1
+		WHERE id IN (
+			SELECT id FROM transactions WHERE status = $2 AND next_attempt_at <= $3 `+settledFilter+`
+			ORDER BY next_attempt_at LIMIT $4 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+transactionColumns, args...)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+		}
+		transactions = append(transactions, *transaction)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return transactions, http.StatusOK, nil
+}
+
+// UpdateTransactionStatus moves a transaction from one status to the next and settles the money accordingly,
+// failed and returned transfers are refunded to the wallet in the same database transaction. A transfer
+// returned after it completed is refunded out of the bank outflow its settlement went to.
+func (r *BalanceRepositoryImpl) UpdateTransactionStatus(ctx context.Context, payload entity.UpdateTransactionStatus) (int, error) {
+	return withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		var gatewayReference sql.NullString
+		if payload.GatewayReference != "" {
+			gatewayReference = sql.NullString{String: payload.GatewayReference, Valid: true}
+		}
+
+		row := tx.QueryRowContext(ctx, `
+			UPDATE transactions SET
+				status = $1, gateway_reference = COALESCE($2, gateway_reference), failure_reason = $3,
+				attempts = $4, poll_errors = $5, next_attempt_at = $6, updated_at = $7,
+				settled_at = CASE WHEN $1 = $10 THEN COALESCE(settled_at, $7) ELSE settled_at END
+			WHERE id = $8 AND status = $9
+			RETURNING `+transactionColumns,
+			payload.ToStatus, gatewayReference, payload.FailureReason,
+			payload.Attempts, payload.PollErrors, payload.NextAttemptAt, payload.UpdatedAt,
+			payload.ID, payload.FromStatus, entity.TransactionStatusCompleted,
+		)
+		transaction, err := scanTransaction(row)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return http.StatusConflict, errors.Wrap(errorer.ErrTransactionStatusChanged, errorer.ErrTransactionStatusChanged.Error())
+			}
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+
+		if payload.FromStatus == payload.ToStatus {
+			return http.StatusOK, nil
+		}
+		switch {
+		case transaction.Status == entity.TransactionStatusCompleted:
+			err = r.postJournal(ctx, tx, entity.Journal{
+				TransactionID: transaction.ID,
+				ReferenceID:   transaction.GatewayReference,
+				Description:   "transaction settled",
+				CreatedAt:     payload.UpdatedAt,
+				Postings: []entity.Posting{
+					entity.SystemPosting(entity.LedgerAccountPayoutClearing, entity.LedgerAccountTypeLiability, transaction.Currency, transaction.Amount),
+					entity.SystemPosting(entity.LedgerAccountExternalBankOutflow, entity.LedgerAccountTypeAsset, transaction.Currency, -transaction.Amount),
+				},
+			})
+			if err != nil {
+				return http.StatusInternalServerError, err
+			}
+		case transaction.Refunded():
+			return r.refundTransaction(ctx, tx, *transaction, payload.FromStatus == entity.TransactionStatusCompleted, payload.UpdatedAt)
+		}
+
+		return http.StatusOK, nil
+	})
+}
+
+// refundTransaction credits the wallet with what an admin has not reversed of the transaction yet. The money
+// comes back from payout clearing, or from the bank outflow once the transfer was settled.
+func (r *BalanceRepositoryImpl) refundTransaction(ctx context.Context, tx *sql.Tx, transaction entity.Transaction, settled bool, now int64) (int, error) {
+	// lock the debit before the balance like ReverseTransaction does
+	var historyID string
+	var debited, reversed int64
+	err := tx.QueryRowContext(ctx, `
+		SELECT id, -balance, reversed_amount FROM balances_history WHERE transaction_id = $1 AND type = $2 FOR UPDATE
+	`, transaction.ID, entity.BalanceHistoryTypeTransaction).Scan(&historyID, &debited, &reversed)
+	if err != nil {
+		return http.StatusInternalServerError, wrapDBError(err)
+	}
+	remaining, err := money.New(debited, transaction.Currency).Sub(money.New(reversed, transaction.Currency))
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(errorer.ErrAmountOverflow, err.Error())
+	}
+	if remaining.Amount <= 0 {
+		return http.StatusOK, nil
+	}
+
+	balance, code, err := r.applyBalanceDelta(ctx, tx, transaction.UserID, transaction.Currency, remaining.Amount)
+	if err != nil {
+		return code, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE balances_history SET reversed_amount = $1 WHERE id = $2`, debited, historyID)
+	if err != nil {
+		return http.StatusInternalServerError, wrapDBError(err)
+	}
+
+	refundID := common.GenerateULID()
+	err = r.insertHistory(ctx, tx, entity.BalanceHistory{
+		ID:                      common.GenerateULID(),
+		TransactionID:           refundID,
+		UserID:                  transaction.UserID,
+		Balance:                 remaining.Amount,
+		Currency:                transaction.Currency,
+		CreatedAt:               now,
+		SourceBankAccountNumber: transaction.BankAccountNumber,
+		SourceBankName:          transaction.BankName,
+		Type:                    entity.BalanceHistoryTypeRefund,
+		ReversalOf:              transaction.ID,
+	}, balance.Balance)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	source := entity.SystemPosting(entity.LedgerAccountPayoutClearing, entity.LedgerAccountTypeLiability, transaction.Currency, remaining.Amount)
+	if settled {
+		source = entity.SystemPosting(entity.LedgerAccountExternalBankOutflow, entity.LedgerAccountTypeAsset, transaction.Currency, remaining.Amount)
+	}
+	err = r.postJournal(ctx, tx, entity.Journal{
+		TransactionID: refundID,
+		ReferenceID:   transaction.ID,
+		Description:   "transaction " + transaction.Status,
+		CreatedAt:     now,
+		Postings: []entity.Posting{
+			source,
+			entity.WalletPosting(transaction.UserID, transaction.Currency, remaining.Amount),
+		},
+	})
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	return http.StatusOK, nil
+}
+
+func scanTransaction(row rowScanner) (*entity.Transaction, error) {
+	transaction := entity.Transaction{}
+	err := row.Scan(
+		&transaction.ID, &transaction.UserID, &transaction.Amount, &transaction.Currency,
+		&transaction.BankAccountNumber, &transaction.BankName, &transaction.Status, &transaction.GatewayReference,
+		&transaction.FailureReason, &transaction.Attempts, &transaction.PollErrors, &transaction.NextAttemptAt, &transaction.StandingOrderID, &transaction.BeneficiaryID,
+		&transaction.SettledAt, &transaction.CreatedAt, &transaction.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &transaction, nil
+}
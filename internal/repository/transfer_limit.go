@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/ovrrtd/openidea-bank/internal/model/money"
+	"github.com/pkg/errors"
+)
+
+func (r *BalanceRepositoryImpl) GetTransferLimits(ctx context.Context, userID string) ([]entity.TransferLimit, int, error) {
+	var limits []entity.TransferLimit
+	rows, err := r.db.QueryContext(ctx, `SELECT user_id, currency, per_transaction, daily, monthly, updated_at FROM transfer_limits WHERE user_id = $1 ORDER BY currency`, userID)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		limit := entity.TransferLimit{}
+		err := rows.Scan(&limit.UserID, &limit.Currency, &limit.PerTransaction, &limit.Daily, &limit.Monthly, &limit.UpdatedAt)
+		if err != nil {
+			return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+		}
+		limits = append(limits, limit)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return limits, http.StatusOK, nil
+}
+
+// UpsertTransferLimits overrides the default limits of a user for the given currencies
+func (r *BalanceRepositoryImpl) UpsertTransferLimits(ctx context.Context, payload entity.UpsertTransferLimits) (int, error) {
+	return withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		for _, limit := range payload.Limits {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO transfer_limits (user_id, currency, per_transaction, daily, monthly, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6)
+				ON CONFLICT (user_id, currency) DO UPDATE SET
+					per_transaction = EXCLUDED.per_transaction, daily = EXCLUDED.daily, monthly = EXCLUDED.monthly, updated_at = EXCLUDED.updated_at
+			`, payload.UserID, limit.Currency, limit.PerTransaction, limit.Daily, limit.Monthly, payload.UpdatedAt)
+			if err != nil {
+				return http.StatusInternalServerError, wrapDBError(err)
+			}
+
+			err = insertAuditLog(ctx, tx, entity.AuditLog{
+				ActorID:    payload.AdminID,
+				Action:     entity.AuditActionTransferLimitSet,
+				TargetType: entity.AuditTargetUser,
+				TargetID:   payload.UserID,
+				Metadata: map[string]interface{}{
+					"currency":       limit.Currency,
+					"perTransaction": limit.PerTransaction,
+					"daily":          limit.Daily,
+					"monthly":        limit.Monthly,
+				},
+				CreatedAt: payload.UpdatedAt,
+			})
+			if err != nil {
+				return http.StatusInternalServerError, err
+			}
+		}
+
+		return http.StatusOK, nil
+	})
+}
+
+// DeleteTransferLimit drops a user's override so the default limits apply again
+func (r *BalanceRepositoryImpl) DeleteTransferLimit(ctx context.Context, payload entity.DeleteTransferLimit) (int, error) {
+	return withTx(ctx, r.db, func(tx *sql.Tx) (int, error) {
+		res, err := tx.ExecContext(ctx, `DELETE FROM transfer_limits WHERE user_id = $1 AND currency = $2`, payload.UserID, payload.Currency)
+		if err != nil {
+			return http.StatusInternalServerError, wrapDBError(err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return http.StatusNotFound, errors.Wrap(errorer.ErrTransferLimitNotFound, errorer.ErrTransferLimitNotFound.Error())
+		}
+
+		err = insertAuditLog(ctx, tx, entity.AuditLog{
+			ActorID:    payload.AdminID,
+			Action:     entity.AuditActionTransferLimitReset,
+			TargetType: entity.AuditTargetUser,
+			TargetID:   payload.UserID,
+			Metadata:   map[string]interface{}{"currency": payload.Currency},
+			CreatedAt:  payload.DeletedAt,
+		})
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+
+		return http.StatusOK, nil
+	})
+}
+
+// outboundDebits lists what a user sent out of their wallet since $2: bank transactions the bank did not fail or return,
+// transfers to other users and captured holds. It takes the user id, the start, then the statuses and history types below.
+const outboundDebits = `
+	SELECT currency, amount, created_at FROM transactions
+	WHERE user_id = $1 AND created_at >= $2 AND status NOT IN ($3, $4)
+	UNION ALL
+	SELECT currency, -balance, created_at FROM balances_history
+	WHERE user_id = $1 AND created_at >= $2 AND type IN ($5, $6)
+`
+
+func outboundDebitsArgs(userID string, since int64) []interface{} {
+	return []interface{}{
+		userID, since,
+		entity.TransactionStatusFailed, entity.TransactionStatusReturned,
+		entity.BalanceHistoryTypeTransferOut, entity.BalanceHistoryTypeHoldCapture,
+	}
+}
+
+// GetTransferUsage sums what a user sent out per currency, see outboundDebits for what counts
+func (r *BalanceRepositoryImpl) GetTransferUsage(ctx context.Context, userID string, dayStart int64, monthStart int64) ([]entity.TransferUsage, int, error) {
+	var usages []entity.TransferUsage
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT currency, COALESCE(SUM(amount) FILTER (WHERE created_at >= $7), 0), COALESCE(SUM(amount), 0)
+		FROM (`+outboundDebits+`) debits
+		GROUP BY currency ORDER BY currency
+	`, append(outboundDebitsArgs(userID, monthStart), dayStart)...)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		usage := entity.TransferUsage{}
+		if err := rows.Scan(&usage.Currency, &usage.Daily, &usage.Monthly); err != nil {
+			return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+		}
+		usages = append(usages, usage)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+
+	return usages, http.StatusOK, nil
+}
+
+// checkTransferLimit rejects a debit of amount that would take the user past one of their limits in currency.
+// The caller must hold the lock on the balance row so concurrent debits are counted one after another,
+// and must not have recorded the debit yet.
+func (r *BalanceRepositoryImpl) checkTransferLimit(ctx context.Context, tx *sql.Tx, userID string, currency string, amount int64, check entity.TransferLimitCheck) (int, error) {
+	limit := check.Limit
+	if limit.PerTransaction > 0 && amount > limit.PerTransaction {
+		return http.StatusForbidden, errors.Wrap(errorer.ErrTransferLimitPerTransaction, errorer.ErrTransferLimitPerTransaction.Error())
+	}
+	if limit.Daily == 0 && limit.Monthly == 0 {
+		return http.StatusOK, nil
+	}
+
+	var daily, monthly int64
+	err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(amount) FILTER (WHERE created_at >= $7), 0), COALESCE(SUM(amount), 0)
+		FROM (`+outboundDebits+`) debits
+		WHERE currency = $8
+	`, append(outboundDebitsArgs(userID, check.MonthStart), check.DayStart, currency)...).Scan(&daily, &monthly)
+	if err != nil {
+		return http.StatusInternalServerError, wrapDBError(err)
+	}
+
+	// a sum past int64 is past any limit too
+	if total, err := money.New(daily, currency).Add(money.New(amount, currency)); limit.Daily > 0 && (err != nil || total.Amount > limit.Daily) {
+		return http.StatusForbidden, errors.Wrap(errorer.ErrTransferLimitDaily, errorer.ErrTransferLimitDaily.Error())
+	}
+	if total, err := money.New(monthly, currency).Add(money.New(amount, currency)); limit.Monthly > 0 && (err != nil || total.Amount > limit.Monthly) {
+		return http.StatusForbidden, errors.Wrap(errorer.ErrTransferLimitMonthly, errorer.ErrTransferLimitMonthly.Error())
+	}
+
+	return http.StatusOK, nil
+}
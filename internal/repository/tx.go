@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/pkg/errors"
+)
+
+const (
+	txMaxAttempts = 3
+	txRetryDelay  = 20 * time.Millisecond
+)
+
+var errRetryableTx = errors.New("retryable database error")
+
+// postgres error codes worth retrying the whole transaction for
+var retryableCodes = map[pq.ErrorCode]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+}
+
+// wrapDBError wraps a database error, marking the ones a fresh transaction could succeed on. A duplicate
+// key is a conflict withTx answers with 409, retrying would only run into it again.
+func wrapDBError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && retryableCodes[pqErr.Code] {
+		return errors.Wrap(errRetryableTx, err.Error())
+	}
+	if isUniqueViolation(err) {
+		return errors.Wrap(errorer.ErrDuplicate, err.Error())
+	}
+	return errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+}
+
+// isOutOfRange reports whether err is postgres rejecting a value too large for its column, e.g. a BIGINT overflow
+func isOutOfRange(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "22003"
+}
+
+// isUniqueViolation reports whether err is postgres rejecting a duplicate key
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// withTx runs fn in a transaction and commits it, retrying when fn or the commit fails with a retryable error.
+// Errors from a transaction that was rolled back are marked with errorer.NotExecuted, a failed commit is not
+// since it may have been applied anyway.
+func withTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) (int, error)) (int, error) {
+	var (
+		code int
+		err  error
+	)
+	for attempt := 1; attempt <= txMaxAttempts; attempt++ {
+		code, err = runTx(ctx, db, fn)
+		if errors.Cause(err) != errRetryableTx {
+			return code, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return http.StatusInternalServerError, errorer.NotExecuted(errors.Wrap(errorer.ErrInternalDatabase, ctx.Err().Error()))
+		case <-time.After(time.Duration(attempt) * txRetryDelay):
+		}
+	}
+
+	// the last attempt failed with a retryable error, postgres rolled it back
+	return http.StatusInternalServerError, errorer.NotExecuted(errors.Wrap(errorer.ErrInternalDatabase, err.Error()))
+}
+
+func runTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) (int, error)) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return http.StatusInternalServerError, errorer.NotExecuted(errors.Wrap(errorer.ErrInternalDatabase, err.Error()))
+	}
+	defer tx.Rollback()
+
+	code, err := fn(tx)
+	if err != nil {
+		if errors.Cause(err) == errorer.ErrDuplicate {
+			return http.StatusConflict, errorer.NotExecuted(err)
+		}
+		return code, errorer.NotExecuted(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		err = wrapDBError(err)
+		if errors.Cause(err) == errorer.ErrDuplicate {
+			return http.StatusConflict, err
+		}
+		return http.StatusInternalServerError, err
+	}
+
+	return code, nil
+}
@@ -17,7 +17,40 @@ type UserRepository interface {
 	Register(ctx context.Context, user entity.User) (*entity.User, int, error)
 	FindByEmail(ctx context.Context, email string) (*entity.User, int, error)
 	FindByID(ctx context.Context, id string) (*entity.User, int, error)
+	FindByPhone(ctx context.Context, phone string) (*entity.User, int, error)
 	UpdateByID(ctx context.Context, user entity.User) (*entity.User, int, error)
+
+	// Two-factor authentication
+	GetMfa(ctx context.Context, userID string) (*entity.UserMfa, int, error)
+	SaveMfaSecret(ctx context.Context, mfa entity.UserMfa) (int, error)
+	EnableMfa(ctx context.Context, payload entity.EnableMfa) (int, error)
+	DisableMfa(ctx context.Context, userID string, disabledAt int64) (int, error)
+	UseMfaStep(ctx context.Context, userID string, step int64) (int, error)
+	ClaimMfaAttempt(ctx context.Context, payload entity.ClaimMfaAttempt) (*entity.UserMfa, int, error)
+	ResetMfaAttempts(ctx context.Context, userID string) (int, error)
+	LockMfa(ctx context.Context, userID string, lockedAt int64, lockedUntil int64) (int, error)
+	UseMfaRecoveryCode(ctx context.Context, userID string, codeHash string, usedAt int64) (int, error)
+	ReplaceMfaRecoveryCodes(ctx context.Context, payload entity.ReplaceMfaRecoveryCodes) (int, error)
+	CreateMfaChallenge(ctx context.Context, challenge entity.MfaChallenge) (int, error)
+	ClaimMfaChallengeAttempt(ctx context.Context, tokenHash string, now int64, maxAttempts int) (*entity.MfaChallenge, int, error)
+	DeleteMfaChallenge(ctx context.Context, id string) (int, error)
+	DeleteExpiredMfaChallenges(ctx context.Context, now int64) (int64, int, error)
+
+	// Transaction PIN
+	SetPin(ctx context.Context, pin entity.UserPin) (int, error)
+	ClaimPinAttempt(ctx context.Context, payload entity.ClaimPinAttempt) (*entity.UserPin, int, error)
+	ResetPinAttempts(ctx context.Context, userID string) (int, error)
+	LockPin(ctx context.Context, userID string, lockedAt int64, lockedUntil int64) (int, error)
+
+	// Login throttling
+	ClaimLoginAttempt(ctx context.Context, payload entity.ClaimLoginAttempt) ([]entity.LoginThrottleCounter, int, error)
+	ReleaseLoginAttempt(ctx context.Context, counter entity.LoginThrottleCounter) (int, error)
+	ResetLoginFailures(ctx context.Context, key entity.LoginThrottleKey) (int, error)
+	UnlockLogin(ctx context.Context, payload entity.UnlockLogin) (int, error)
+	DeleteStaleLoginThrottles(ctx context.Context, windowStart int64, now int64) (int64, int, error)
+
+	// Audit
+	CreateAuditLog(ctx context.Context, log entity.AuditLog) (int, error)
 }
 
 func NewUserRepository(logger zerolog.Logger, db *sql.DB) UserRepository {
@@ -33,8 +66,12 @@ type UserRepositoryImpl struct {
 }
 
 func (r *UserRepositoryImpl) Register(ctx context.Context, newUser entity.User) (*entity.User, int, error) {
-	err := r.db.QueryRowContext(ctx, "INSERT INTO users (id, email, password, name) VALUES ($1, $2, $3, $4) RETURNING id",
-		common.GenerateULID(), newUser.Email, newUser.Password, newUser.Name).Scan(&newUser.ID)
+	var phone sql.NullString
+	if newUser.Phone != "" {
+		phone = sql.NullString{String: newUser.Phone, Valid: true}
+	}
+	err := r.db.QueryRowContext(ctx, "INSERT INTO users (id, email, password, name, phone) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		common.GenerateULID(), newUser.Email, newUser.Password, newUser.Name, phone).Scan(&newUser.ID)
 	if err != nil {
 		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
 	}
@@ -45,8 +82,8 @@ func (r *UserRepositoryImpl) Register(ctx context.Context, newUser entity.User)
 func (r *UserRepositoryImpl) FindByEmail(ctx context.Context, email string) (*entity.User, int, error) {
 	var user entity.User
 
-	row := r.db.QueryRowContext(ctx, "SELECT id, email, password, name FROM users WHERE email = $1", email)
-	err := row.Scan(&user.ID, &user.Email, &user.Password, &user.Name)
+	row := r.db.QueryRowContext(ctx, "SELECT id, email, password, name, COALESCE(phone, ''), role FROM users WHERE email = $1", email)
+	err := row.Scan(&user.ID, &user.Email, &user.Password, &user.Name, &user.Phone, &user.Role)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, http.StatusNotFound, errors.Wrap(errorer.ErrNotFound, errorer.ErrNotFound.Error())
@@ -59,8 +96,22 @@ func (r *UserRepositoryImpl) FindByEmail(ctx context.Context, email string) (*en
 func (r *UserRepositoryImpl) FindByID(ctx context.Context, id string) (*entity.User, int, error) {
 	var user entity.User
 
-	row := r.db.QueryRowContext(ctx, "SELECT id, email, password, name FROM users WHERE id = $1", id)
-	err := row.Scan(&user.ID, &user.Email, &user.Password, &user.Name)
+	row := r.db.QueryRowContext(ctx, "SELECT id, email, password, name, COALESCE(phone, ''), role FROM users WHERE id = $1", id)
+	err := row.Scan(&user.ID, &user.Email, &user.Password, &user.Name, &user.Phone, &user.Role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, http.StatusNotFound, errors.Wrap(errorer.ErrNotFound, errorer.ErrNotFound.Error())
+		}
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalDatabase, err.Error())
+	}
+	return &user, http.StatusOK, nil
+}
+
+func (r *UserRepositoryImpl) FindByPhone(ctx context.Context, phone string) (*entity.User, int, error) {
+	var user entity.User
+
+	row := r.db.QueryRowContext(ctx, "SELECT id, email, password, name, COALESCE(phone, ''), role FROM users WHERE phone = $1", phone)
+	err := row.Scan(&user.ID, &user.Email, &user.Password, &user.Name, &user.Phone, &user.Role)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, http.StatusNotFound, errors.Wrap(errorer.ErrNotFound, errorer.ErrNotFound.Error())
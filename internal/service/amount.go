@@ -0,0 +1,40 @@
+package service
+
+import (
+	"net/http"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/model/money"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/pkg/errors"
+)
+
+// parseAmount reads an amount sent in the major unit of currency into minor units, it has to be positive
+func parseAmount(amount request.Amount, currency string) (int64, int, error) {
+	minor, code, err := parseOptionalAmount(amount, currency)
+	if err != nil {
+		return 0, code, err
+	}
+	if minor == 0 {
+		return 0, http.StatusBadRequest, errors.Wrap(errorer.ErrInvalidAmount, "amount must be more than zero")
+	}
+
+	return minor, http.StatusOK, nil
+}
+
+// parseOptionalAmount is parseAmount for amounts where empty or zero has a meaning, both read as zero
+func parseOptionalAmount(amount request.Amount, currency string) (int64, int, error) {
+	if amount == "" {
+		return 0, http.StatusOK, nil
+	}
+
+	m, err := money.Parse(string(amount), currency)
+	if errors.Is(err, money.ErrOverflow) {
+		return 0, http.StatusBadRequest, errors.Wrap(errorer.ErrAmountOverflow, errorer.ErrAmountOverflow.Error())
+	}
+	if err != nil || m.Amount < 0 {
+		return 0, http.StatusBadRequest, errors.Wrap(errorer.ErrInvalidAmount, errorer.ErrInvalidAmount.Error())
+	}
+
+	return m.Amount, http.StatusOK, nil
+}
@@ -3,57 +3,198 @@ package service
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/ovrrtd/openidea-bank/internal/helper/common"
 	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
 	"github.com/ovrrtd/openidea-bank/internal/helper/validator"
 	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/ovrrtd/openidea-bank/internal/model/money"
 	"github.com/ovrrtd/openidea-bank/internal/model/request"
 	"github.com/ovrrtd/openidea-bank/internal/model/response"
 	"github.com/pkg/errors"
 )
 
-func (s *service) AddBalance(ctx context.Context, payload request.AddBalance) (int, error) {
+// AddBalance records a top up for an admin to review, it does not count toward the balance until approved
+func (s *service) AddBalance(ctx context.Context, payload request.AddBalance) (*response.TopUp, int, error) {
 	err := validator.ValidateStruct(&payload)
 	if err != nil {
-		return http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
 	}
 
 	// validate url proof image with regex
 	ok := common.ValidateUrl(payload.ProofImageURL)
 	if !ok {
-		return http.StatusBadRequest, errors.Wrap(errorer.ErrBadRequest, errorer.ErrBadRequest.Error())
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrBadRequest, errorer.ErrBadRequest.Error())
+	}
+	amount, code, err := parseAmount(payload.Balance, payload.Currency)
+	if err != nil {
+		return nil, code, err
+	}
+	bank, accountNumber, code, err := s.resolveBank(payload.BankCode, payload.BankAccountNumber)
+	if err != nil {
+		return nil, code, err
 	}
 
-	code, err := s.balanceRepo.UpsertBalance(ctx, entity.UpsertBalance{
+	topUp := entity.TopUp{
+		ID:                      common.GenerateULID(),
 		UserID:                  payload.UserID,
-		Balance:                 payload.Balance,
+		Amount:                  amount,
 		Currency:                payload.Currency,
 		ProofImageURL:           payload.ProofImageURL,
-		SenderBankAccountNumber: payload.BankAccountNumber,
-		SenderBankName:          payload.BankName,
-		TransactionID:           "",
-	})
+		SenderBankAccountNumber: accountNumber,
+		SenderBankName:          bank.Name,
+		Status:                  entity.TopUpStatusPendingReview,
+		CreatedAt:               time.Now().UnixMilli(),
+	}
+	code, err = s.balanceRepo.CreateTopUp(ctx, topUp)
+	if err != nil {
+		return nil, code, err
+	}
 
-	return code, err
+	return toTopUpResponse(topUp), code, nil
+}
+
+// CreateTransaction debits the wallet and queues the transfer for the bank, its status is tracked afterwards
+func (s *service) CreateTransaction(ctx context.Context, payload request.CreateTransaction) (*response.Transaction, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+	amount, code, err := parseAmount(payload.Balance, payload.Currency)
+	if err != nil {
+		return nil, code, err
+	}
+	if code, err := s.resolveRecipient(ctx, &payload); err != nil {
+		return nil, code, err
+	}
 
+	now := time.Now()
+	transaction := entity.Transaction{
+		ID:                common.GenerateULID(),
+		UserID:            payload.UserID,
+		Amount:            amount,
+		Currency:          payload.Currency,
+		BankAccountNumber: payload.BankAccountNumber,
+		BankName:          payload.BankName,
+		BeneficiaryID:     payload.BeneficiaryID,
+		Status:            entity.TransactionStatusPending,
+		NextAttemptAt:     now.UnixMilli(),
+		CreatedAt:         now.UnixMilli(),
+		UpdatedAt:         now.UnixMilli(),
+	}
+	checks, code, err := s.outboundChecks(ctx, payload.UserID, payload.Currency, now)
+	if err != nil {
+		return nil, code, err
+	}
+	code, err = s.balanceRepo.CreateOutboundTransaction(ctx, transaction, checks)
+	if err != nil {
+		return nil, code, err
+	}
+
+	return toTransactionResponse(transaction), code, nil
 }
-func (s *service) CreateTransaction(ctx context.Context, payload request.CreateTransaction) (int, error) {
+
+// CreateTransfer moves funds from the caller's wallet to another user found by id, email or phone
+func (s *service) CreateTransfer(ctx context.Context, payload request.CreateTransfer) (*response.Transfer, int, error) {
 	err := validator.ValidateStruct(&payload)
 	if err != nil {
-		return http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+	amount, code, err := parseAmount(payload.Balance, payload.Currency)
+	if err != nil {
+		return nil, code, err
 	}
 
-	code, err := s.balanceRepo.UpsertBalance(ctx, entity.UpsertBalance{
-		UserID:                  payload.UserID,
-		Balance:                 -payload.Balance,
-		Currency:                payload.Currency,
-		SenderBankAccountNumber: payload.BankAccountNumber,
-		SenderBankName:          payload.BankName,
-		TransactionID:           common.GenerateULID(),
+	var recipient *entity.User
+	switch {
+	case payload.RecipientUserID != "":
+		recipient, code, err = s.userRepo.FindByID(ctx, payload.RecipientUserID)
+	case payload.RecipientEmail != "":
+		recipient, code, err = s.userRepo.FindByEmail(ctx, payload.RecipientEmail)
+	case payload.RecipientPhone != "":
+		recipient, code, err = s.userRepo.FindByPhone(ctx, payload.RecipientPhone)
+	default:
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrBadRequest, "recipientUserId, recipientEmail or recipientPhone is required")
+	}
+	if err != nil {
+		if code == http.StatusNotFound {
+			return nil, http.StatusNotFound, errors.Wrap(errorer.ErrRecipientNotFound, errorer.ErrRecipientNotFound.Error())
+		}
+		return nil, code, err
+	}
+
+	if recipient.ID == payload.UserID {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrSelfTransfer, errorer.ErrSelfTransfer.Error())
+	}
+
+	check, code, err := s.transferLimitCheck(ctx, payload.UserID, payload.Currency, time.Now())
+	if err != nil {
+		return nil, code, err
+	}
+
+	transactionID := common.GenerateULID()
+	code, err = s.balanceRepo.Transfer(ctx, entity.Transfer{
+		TransactionID: transactionID,
+		SenderID:      payload.UserID,
+		RecipientID:   recipient.ID,
+		Currency:      payload.Currency,
+		Amount:        amount,
+		TransferLimit: check,
+	})
+	if err != nil {
+		return nil, code, err
+	}
+
+	return &response.Transfer{
+		TransactionID: transactionID,
+		Recipient: response.User{
+			ID:   recipient.ID,
+			Name: recipient.Name,
+		},
+		Balance:  money.Format(amount, payload.Currency),
+		Currency: payload.Currency,
+	}, code, nil
+}
+
+// ReverseTransaction refunds an outgoing transaction on behalf of an admin, recording their reason
+func (s *service) ReverseTransaction(ctx context.Context, payload request.ReverseTransaction) (*response.Reversal, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	amount, code, err := parseOptionalAmount(payload.Amount, payload.Currency)
+	if err != nil {
+		return nil, code, err
+	}
+
+	reversal, code, err := s.balanceRepo.ReverseTransaction(ctx, entity.Reversal{
+		TransactionID:         common.GenerateULID(),
+		OriginalTransactionID: payload.TransactionID,
+		Amount:                amount,
+		Currency:              payload.Currency,
+		Reason:                payload.Reason,
+		ActorID:               payload.ActorID,
+		CreatedAt:             time.Now().UnixMilli(),
 	})
+	if err != nil {
+		return nil, code, err
+	}
+
+	remaining, err := money.New(reversal.OriginalAmount, reversal.Currency).Sub(money.New(reversal.ReversedAmount, reversal.Currency))
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrAmountOverflow, err.Error())
+	}
 
-	return code, err
+	return &response.Reversal{
+		TransactionID:         reversal.TransactionID,
+		OriginalTransactionID: reversal.OriginalTransactionID,
+		Amount:                money.Format(reversal.Amount, reversal.Currency),
+		Currency:              reversal.Currency,
+		ReversedAmount:        money.Format(reversal.ReversedAmount, reversal.Currency),
+		RemainingAmount:       money.Format(remaining.Amount, reversal.Currency),
+	}, code, nil
 }
 
 func (s *service) GetBalances(ctx context.Context, userId string) ([]response.Balance, int, error) {
@@ -65,37 +206,87 @@ func (s *service) GetBalances(ctx context.Context, userId string) ([]response.Ba
 
 	for i, v := range entBalance {
 		balances[i] = response.Balance{
-			Balance:  v.Balance,
-			Currency: v.Currency,
+			Available: money.Format(v.Available(), v.Currency),
+			Held:      money.Format(v.Held, v.Currency),
+			Total:     money.Format(v.Balance, v.Currency),
+			Currency:  v.Currency,
 		}
 	}
 
 	return balances, code, nil
 }
 
-func (s *service) GetBalancesHistory(ctx context.Context, payload request.GetBalancesHistory) ([]response.GetBalancesHistory, int, error) {
+func (s *service) GetBalancesHistory(ctx context.Context, payload request.GetBalancesHistory) ([]response.GetBalancesHistory, *common.Meta, int, error) {
 	err := validator.ValidateStruct(&payload)
 	if err != nil {
-		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+		return nil, nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+	minAmount, code, err := parseOptionalAmount(payload.MinAmount, payload.Currency)
+	if err != nil {
+		return nil, nil, code, err
+	}
+	maxAmount, code, err := parseOptionalAmount(payload.MaxAmount, payload.Currency)
+	if err != nil {
+		return nil, nil, code, err
+	}
+	if maxAmount > 0 && maxAmount < minAmount {
+		return nil, nil, http.StatusBadRequest, errors.Wrap(errorer.ErrBadRequest, "maxAmount must not be below minAmount")
 	}
 
-	entBH, code, err := s.balanceRepo.GetBalancesHistory(ctx, entity.GetBalancesHistory{
+	filter := entity.GetBalancesHistory{
 		UserID: payload.UserID,
-		Limit:  payload.Limit,
-		Offset: payload.Offset,
-	})
+		// one extra row tells whether there is a next page
+		Limit:         payload.Limit + 1,
+		Offset:        payload.Offset,
+		Currency:      payload.Currency,
+		Direction:     payload.Direction,
+		From:          payload.From,
+		To:            payload.To,
+		MinAmount:     minAmount,
+		MaxAmount:     maxAmount,
+		BankName:      payload.BankName,
+		TransactionID: payload.TransactionID,
+		// later cursor pages only count when asked, the client has the total from the first page
+		CountTotal: payload.Cursor == "" || payload.WithTotal,
+	}
+	if payload.Cursor != "" {
+		filter.AfterCreatedAt, filter.AfterID, err = common.DecodeCursor(payload.Cursor)
+		if err != nil {
+			return nil, nil, http.StatusBadRequest, errors.Wrap(errorer.ErrBadRequest, err.Error())
+		}
+		// the cursor replaces the offset
+		filter.Offset = 0
+	}
 
+	entBH, total, code, err := s.balanceRepo.GetBalancesHistory(ctx, filter)
 	if err != nil {
-		return nil, code, err
+		return nil, nil, code, err
+	}
+
+	meta := &common.Meta{Limit: payload.Limit, Offset: filter.Offset}
+	if filter.CountTotal {
+		meta.Total = &total
+	}
+	if len(entBH) > payload.Limit {
+		entBH = entBH[:payload.Limit]
+		if payload.Limit > 0 {
+			last := entBH[len(entBH)-1]
+			meta.NextCursor = common.EncodeCursor(last.CreatedAt, last.ID)
+		}
 	}
 
 	gh := make([]response.GetBalancesHistory, len(entBH))
 	for i, v := range entBH {
 		gh[i] = response.GetBalancesHistory{
-			TransactionID: v.TransactionID,
-			Balance:       v.Balance,
-			Currency:      v.Currency,
-			CreatedAt:     v.CreatedAt,
+			TransactionID:      v.TransactionID,
+			Balance:            money.Format(v.Balance, v.Currency),
+			Currency:           v.Currency,
+			CreatedAt:          v.CreatedAt,
+			Type:               v.Type,
+			ReversalOf:         v.ReversalOf,
+			StandingOrderID:    v.StandingOrderID,
+			FxRate:             v.FxRate,
+			CounterpartyUserID: v.CounterpartyUserID,
 			Source: struct {
 				BankAccountNumber string `json:"bankAccountNumber"`
 				BankName          string `json:"bankName"`
@@ -104,7 +295,15 @@ func (s *service) GetBalancesHistory(ctx context.Context, payload request.GetBal
 				BankName:          v.SourceBankName,
 			},
 		}
+		if v.BalanceAfter != nil {
+			gh[i].BalanceBefore = money.Format(*v.BalanceBefore, v.Currency)
+			gh[i].BalanceAfter = money.Format(*v.BalanceAfter, v.Currency)
+		}
+		if v.ReversedAmount > 0 {
+			gh[i].ReversedAmount = money.Format(v.ReversedAmount, v.Currency)
+			gh[i].ReversalStatus = v.ReversalStatus()
+		}
 	}
 
-	return gh, code, nil
+	return gh, meta, code, nil
 }
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/bank"
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/model/response"
+	"github.com/pkg/errors"
+)
+
+func (s *service) GetBanks(ctx context.Context) ([]response.Bank, int, error) {
+	banks := s.cfg.Banks.Banks()
+	res := make([]response.Bank, len(banks))
+	for i, b := range banks {
+		res[i] = response.Bank{
+			Code:     b.Code,
+			Name:     b.Name,
+			SwiftBIC: b.SwiftBIC,
+			Country:  b.Country,
+		}
+		res[i].AccountNumber.Format = b.AccountNumber.Format
+		res[i].AccountNumber.Lengths = b.AccountNumber.Lengths
+	}
+
+	return res, http.StatusOK, nil
+}
+
+// resolveBank looks code up in the bank directory and returns the account number normalized by its rules
+func (s *service) resolveBank(code string, accountNumber string) (*bank.Bank, string, int, error) {
+	b, ok := s.cfg.Banks.Lookup(code)
+	if !ok {
+		return nil, "", http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(bank.ErrUnknownBank), bank.ErrUnknownBank.Error())
+	}
+
+	accountNumber, err := b.ValidateAccount(accountNumber)
+	if err != nil {
+		return nil, "", http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), err.Error())
+	}
+
+	return &b, accountNumber, http.StatusOK, nil
+}
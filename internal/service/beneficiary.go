@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/helper/validator"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/ovrrtd/openidea-bank/internal/model/response"
+	"github.com/pkg/errors"
+)
+
+func (s *service) CreateBeneficiary(ctx context.Context, payload request.CreateBeneficiary) (*response.Beneficiary, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+	bank, accountNumber, code, err := s.resolveBank(payload.BankCode, payload.BankAccountNumber)
+	if err != nil {
+		return nil, code, err
+	}
+
+	now := time.Now().UnixMilli()
+	beneficiary := entity.Beneficiary{
+		ID:                common.GenerateULID(),
+		UserID:            payload.UserID,
+		Nickname:          payload.Nickname,
+		BankAccountNumber: accountNumber,
+		BankName:          bank.Name,
+		IsFavorite:        payload.IsFavorite,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	code, err = s.balanceRepo.CreateBeneficiary(ctx, beneficiary)
+	if err != nil {
+		return nil, code, err
+	}
+
+	return s.toBeneficiaryResponse(beneficiary), code, nil
+}
+
+func (s *service) GetBeneficiaries(ctx context.Context, userID string) ([]response.Beneficiary, int, error) {
+	beneficiaries, code, err := s.balanceRepo.GetBeneficiaries(ctx, userID)
+	if err != nil {
+		return nil, code, err
+	}
+
+	res := make([]response.Beneficiary, len(beneficiaries))
+	for i, v := range beneficiaries {
+		res[i] = *s.toBeneficiaryResponse(v)
+	}
+
+	return res, code, nil
+}
+
+func (s *service) GetBeneficiary(ctx context.Context, payload request.GetBeneficiary) (*response.Beneficiary, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	beneficiary, code, err := s.balanceRepo.GetBeneficiary(ctx, payload.BeneficiaryID, payload.UserID)
+	if err != nil {
+		return nil, code, err
+	}
+
+	return s.toBeneficiaryResponse(*beneficiary), code, nil
+}
+
+func (s *service) UpdateBeneficiary(ctx context.Context, payload request.UpdateBeneficiary) (*response.Beneficiary, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	beneficiary, code, err := s.balanceRepo.GetBeneficiary(ctx, payload.BeneficiaryID, payload.UserID)
+	if err != nil {
+		return nil, code, err
+	}
+
+	beneficiary.Nickname = payload.Nickname
+	beneficiary.IsFavorite = payload.IsFavorite
+	beneficiary.UpdatedAt = time.Now().UnixMilli()
+	code, err = s.balanceRepo.UpdateBeneficiary(ctx, *beneficiary)
+	if err != nil {
+		return nil, code, err
+	}
+
+	return s.toBeneficiaryResponse(*beneficiary), code, nil
+}
+
+func (s *service) DeleteBeneficiary(ctx context.Context, payload request.GetBeneficiary) (int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	return s.balanceRepo.DeleteBeneficiary(ctx, payload.BeneficiaryID, payload.UserID)
+}
+
+// resolveRecipient fills in the recipient account of a transaction, either from the saved beneficiary
+// or by checking the account given inline against the bank directory
+func (s *service) resolveRecipient(ctx context.Context, transaction *request.CreateTransaction) (int, error) {
+	if transaction.BeneficiaryID == "" {
+		bank, accountNumber, code, err := s.resolveBank(transaction.BankCode, transaction.BankAccountNumber)
+		if err != nil {
+			return code, err
+		}
+		transaction.BankAccountNumber = accountNumber
+		transaction.BankName = bank.Name
+		return code, nil
+	}
+
+	beneficiary, code, err := s.balanceRepo.GetBeneficiary(ctx, transaction.BeneficiaryID, transaction.UserID)
+	if err != nil {
+		return code, err
+	}
+	transaction.BankAccountNumber = beneficiary.BankAccountNumber
+	transaction.BankName = beneficiary.BankName
+
+	return code, nil
+}
+
+// beneficiaryCoolingOff is the cap on recipient accounts first used less than the cooling off period before now
+func (s *service) beneficiaryCoolingOff(now time.Time) entity.BeneficiaryCoolingOff {
+	return entity.BeneficiaryCoolingOff{
+		Since: now.Add(-s.cfg.BeneficiaryCoolingOff).UnixMilli(),
+		Limit: s.cfg.BeneficiaryCoolingOffLimit,
+	}
+}
+
+func (s *service) toBeneficiaryResponse(beneficiary entity.Beneficiary) *response.Beneficiary {
+	res := &response.Beneficiary{
+		BeneficiaryID:     beneficiary.ID,
+		Nickname:          beneficiary.Nickname,
+		BankAccountNumber: beneficiary.BankAccountNumber,
+		BankName:          beneficiary.BankName,
+		IsFavorite:        beneficiary.IsFavorite,
+		CreatedAt:         beneficiary.CreatedAt,
+		UpdatedAt:         beneficiary.UpdatedAt,
+	}
+	if until := beneficiary.CreatedAt + s.cfg.BeneficiaryCoolingOff.Milliseconds(); until > time.Now().UnixMilli() {
+		res.CoolingOffUntil = until
+	}
+
+	return res
+}
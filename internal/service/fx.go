@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/helper/validator"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/ovrrtd/openidea-bank/internal/model/money"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/ovrrtd/openidea-bank/internal/model/response"
+	"github.com/pkg/errors"
+)
+
+// fxRateScale is the number of decimals rates are stored with
+const fxRateScale = 12
+
+func (s *service) UpsertFxRates(ctx context.Context, payload request.UpsertFxRates) (int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	now := time.Now().UnixMilli()
+	rates := make([]entity.FxRate, len(payload.Rates))
+	for i, v := range payload.Rates {
+		rate, ok := new(big.Rat).SetString(v.Rate)
+		if !ok || rate.Sign() <= 0 {
+			return http.StatusBadRequest, errors.Wrap(errorer.ErrBadRequest, "rate must be a positive decimal")
+		}
+		rates[i] = entity.FxRate{
+			BaseCurrency:  v.BaseCurrency,
+			QuoteCurrency: v.QuoteCurrency,
+			Rate:          rate.FloatString(fxRateScale),
+			UpdatedAt:     now,
+		}
+	}
+
+	return s.fxRepo.UpsertRates(ctx, rates)
+}
+
+func (s *service) GetFxRates(ctx context.Context) ([]response.FxRate, int, error) {
+	entRates, code, err := s.fxRepo.GetRates(ctx)
+	if err != nil {
+		return nil, code, err
+	}
+
+	rates := make([]response.FxRate, len(entRates))
+	for i, v := range entRates {
+		rates[i] = response.FxRate{
+			BaseCurrency:  v.BaseCurrency,
+			QuoteCurrency: v.QuoteCurrency,
+			Rate:          v.Rate,
+			UpdatedAt:     v.UpdatedAt,
+		}
+	}
+
+	return rates, code, nil
+}
+
+// CreateFxQuote locks the current rate for converting amount of one currency into another
+func (s *service) CreateFxQuote(ctx context.Context, payload request.CreateFxQuote) (*response.FxQuote, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	amount, code, err := parseAmount(payload.Amount, payload.FromCurrency)
+	if err != nil {
+		return nil, code, err
+	}
+	rate, code, err := s.fxRate(ctx, payload.FromCurrency, payload.ToCurrency)
+	if err != nil {
+		return nil, code, err
+	}
+
+	// quote with the rate as it is stored so the recorded rate reproduces the amounts,
+	// rates are per major unit so the exponents of both currencies are accounted for
+	rate, _ = new(big.Rat).SetString(rate.FloatString(fxRateScale))
+	to, err := money.New(amount, payload.FromCurrency).Convert(payload.ToCurrency, rate)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrAmountOverflow, errorer.ErrAmountOverflow.Error())
+	}
+	if to.Amount <= 0 {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrBadRequest, "amount is too small to convert")
+	}
+
+	now := time.Now()
+	quote := entity.FxQuote{
+		ID:           common.GenerateULID(),
+		UserID:       payload.UserID,
+		FromCurrency: payload.FromCurrency,
+		ToCurrency:   payload.ToCurrency,
+		FromAmount:   amount,
+		ToAmount:     to.Amount,
+		Rate:         rate.FloatString(fxRateScale),
+		CreatedAt:    now.UnixMilli(),
+		ExpiresAt:    now.Add(s.cfg.FxQuoteTTL).UnixMilli(),
+	}
+	code, err = s.fxRepo.CreateQuote(ctx, quote)
+	if err != nil {
+		return nil, code, err
+	}
+
+	return &response.FxQuote{
+		QuoteID:      quote.ID,
+		FromCurrency: quote.FromCurrency,
+		ToCurrency:   quote.ToCurrency,
+		FromAmount:   money.Format(quote.FromAmount, quote.FromCurrency),
+		ToAmount:     money.Format(quote.ToAmount, quote.ToCurrency),
+		Rate:         quote.Rate,
+		ExpiresAt:    quote.ExpiresAt,
+	}, code, nil
+}
+
+// ConvertFx executes a quote the user obtained earlier at its locked rate
+func (s *service) ConvertFx(ctx context.Context, payload request.ConvertFx) (*response.FxConversion, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	quote, code, err := s.balanceRepo.Convert(ctx, entity.FxConversion{
+		QuoteID:       payload.QuoteID,
+		UserID:        payload.UserID,
+		TransactionID: common.GenerateULID(),
+		ExecutedAt:    time.Now().UnixMilli(),
+	})
+	if err != nil {
+		return nil, code, err
+	}
+
+	return &response.FxConversion{
+		TransactionID: quote.TransactionID,
+		FromCurrency:  quote.FromCurrency,
+		ToCurrency:    quote.ToCurrency,
+		FromAmount:    money.Format(quote.FromAmount, quote.FromCurrency),
+		ToAmount:      money.Format(quote.ToAmount, quote.ToCurrency),
+		Rate:          quote.Rate,
+	}, code, nil
+}
+
+// fxRate returns how many units of quote one unit of base buys, falling back to the inverse pair
+func (s *service) fxRate(ctx context.Context, base string, quote string) (*big.Rat, int, error) {
+	inverse := false
+	rate, code, err := s.fxRepo.GetRate(ctx, base, quote)
+	if err != nil && code == http.StatusNotFound {
+		inverse = true
+		rate, code, err = s.fxRepo.GetRate(ctx, quote, base)
+	}
+	if err != nil {
+		return nil, code, err
+	}
+
+	r, ok := new(big.Rat).SetString(rate.Rate)
+	if !ok || r.Sign() <= 0 {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalServer, "invalid stored fx rate")
+	}
+	if inverse {
+		r.Inv(r)
+	}
+
+	return r, http.StatusOK, nil
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/helper/validator"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/ovrrtd/openidea-bank/internal/model/money"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/ovrrtd/openidea-bank/internal/model/response"
+	"github.com/pkg/errors"
+)
+
+// CreateHold reserves funds ahead of a payout, they stay in the balance but are no longer available
+func (s *service) CreateHold(ctx context.Context, payload request.CreateHold) (*response.Hold, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	amount, code, err := parseAmount(payload.Amount, payload.Currency)
+	if err != nil {
+		return nil, code, err
+	}
+
+	ttl := s.cfg.HoldTTL
+	if payload.ExpiresInSeconds > 0 {
+		ttl = time.Duration(payload.ExpiresInSeconds) * time.Second
+	}
+
+	now := time.Now()
+	hold, code, err := s.balanceRepo.CreateHold(ctx, entity.Hold{
+		ID:          common.GenerateULID(),
+		UserID:      payload.UserID,
+		Amount:      amount,
+		Currency:    payload.Currency,
+		Status:      entity.HoldStatusActive,
+		Description: payload.Description,
+		ExpiresAt:   now.Add(ttl).UnixMilli(),
+		CreatedAt:   now.UnixMilli(),
+		UpdatedAt:   now.UnixMilli(),
+	})
+	if err != nil {
+		return nil, code, err
+	}
+
+	return toHoldResponse(*hold), code, nil
+}
+
+func (s *service) GetHolds(ctx context.Context, userID string) ([]response.Hold, int, error) {
+	entHolds, code, err := s.balanceRepo.GetHolds(ctx, userID)
+	if err != nil {
+		return nil, code, err
+	}
+
+	holds := make([]response.Hold, len(entHolds))
+	for i, v := range entHolds {
+		holds[i] = *toHoldResponse(v)
+	}
+
+	return holds, code, nil
+}
+
+func (s *service) CaptureHold(ctx context.Context, payload request.SettleHold) (*response.Hold, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	hold, code, err := s.balanceRepo.GetHold(ctx, payload.HoldID, payload.UserID)
+	if err != nil {
+		return nil, code, err
+	}
+	now := time.Now()
+	check, code, err := s.transferLimitCheck(ctx, payload.UserID, hold.Currency, now)
+	if err != nil {
+		return nil, code, err
+	}
+
+	hold, code, err = s.balanceRepo.CaptureHold(ctx, entity.SettleHold{
+		HoldID:        payload.HoldID,
+		UserID:        payload.UserID,
+		TransactionID: common.GenerateULID(),
+		SettledAt:     now.UnixMilli(),
+		TransferLimit: check,
+	})
+	if err != nil {
+		return nil, code, err
+	}
+
+	return toHoldResponse(*hold), code, nil
+}
+
+func (s *service) ReleaseHold(ctx context.Context, payload request.SettleHold) (*response.Hold, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	hold, code, err := s.balanceRepo.ReleaseHold(ctx, entity.SettleHold{
+		HoldID:    payload.HoldID,
+		UserID:    payload.UserID,
+		SettledAt: time.Now().UnixMilli(),
+	})
+	if err != nil {
+		return nil, code, err
+	}
+
+	return toHoldResponse(*hold), code, nil
+}
+
+// ExpireHolds releases holds that were neither captured nor released in time
+func (s *service) ExpireHolds(ctx context.Context) error {
+	n, _, err := s.balanceRepo.ExpireHolds(ctx, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		s.log.Info().Int64("count", n).Msg("expired holds")
+	}
+
+	return nil
+}
+
+func toHoldResponse(hold entity.Hold) *response.Hold {
+	return &response.Hold{
+		HoldID:        hold.ID,
+		Amount:        money.Format(hold.Amount, hold.Currency),
+		Currency:      hold.Currency,
+		Status:        hold.Status,
+		Description:   hold.Description,
+		TransactionID: hold.TransactionID,
+		ExpiresAt:     hold.ExpiresAt,
+		CreatedAt:     hold.CreatedAt,
+	}
+}
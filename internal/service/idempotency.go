@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/helper/validator"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/ovrrtd/openidea-bank/internal/model/response"
+	"github.com/pkg/errors"
+)
+
+// ReserveIdempotencyKey claims the key for a new request, or returns the stored result of the first one
+func (s *service) ReserveIdempotencyKey(ctx context.Context, payload request.ReserveIdempotencyKey) (*response.IdempotentReplay, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	now := time.Now()
+	existing, code, err := s.idempotencyRepo.Reserve(ctx, entity.IdempotencyKey{
+		UserID:      payload.UserID,
+		Key:         payload.Key,
+		RequestHash: payload.RequestHash,
+		CreatedAt:   now.UnixMilli(),
+		ExpiresAt:   now.Add(s.cfg.IdempotencyRetention).UnixMilli(),
+	})
+	if err != nil {
+		return nil, code, err
+	}
+	if existing == nil {
+		return nil, code, nil
+	}
+
+	if existing.RequestHash != payload.RequestHash {
+		return nil, http.StatusUnprocessableEntity, errors.Wrap(errorer.ErrIdempotencyKeyReused, errorer.ErrIdempotencyKeyReused.Error())
+	}
+	if existing.StatusCode == 0 {
+		return nil, http.StatusConflict, errors.Wrap(errorer.ErrIdempotencyKeyInProgress, errorer.ErrIdempotencyKeyInProgress.Error())
+	}
+
+	return &response.IdempotentReplay{
+		StatusCode: existing.StatusCode,
+		Response:   existing.Response,
+	}, http.StatusOK, nil
+}
+
+// CompleteIdempotencyKey stores the result of the first request. A server error only frees the key for a retry
+// when the request is known not to have changed anything, otherwise the retry could apply it a second time.
+func (s *service) CompleteIdempotencyKey(ctx context.Context, payload request.CompleteIdempotencyKey) (int, error) {
+	if payload.StatusCode >= http.StatusInternalServerError && payload.NotExecuted {
+		return s.idempotencyRepo.Release(ctx, payload.UserID, payload.Key)
+	}
+
+	return s.idempotencyRepo.Complete(ctx, entity.IdempotencyKey{
+		UserID:     payload.UserID,
+		Key:        payload.Key,
+		StatusCode: payload.StatusCode,
+		Response:   payload.Response,
+	})
+}
+
+func (s *service) PurgeExpiredIdempotencyKeys(ctx context.Context) error {
+	n, _, err := s.idempotencyRepo.DeleteExpired(ctx, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		s.log.Info().Int64("count", n).Msg("purged expired idempotency keys")
+	}
+
+	return nil
+}
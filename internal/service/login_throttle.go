@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/helper/validator"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// loginFailureWindow is how long failed logins are remembered after the last one
+const loginFailureWindow = 24 * time.Hour
+
+var (
+	dummyPasswordHash     []byte
+	dummyPasswordHashOnce sync.Once
+)
+
+// UnlockUser lets an admin lift the login lockout of a user before it runs out
+func (s *service) UnlockUser(ctx context.Context, payload request.UnlockUser) (int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	user, code, err := s.userRepo.FindByID(ctx, payload.UserID)
+	if err != nil {
+		return code, err
+	}
+
+	return s.userRepo.UnlockLogin(ctx, entity.UnlockLogin{
+		UserID:     user.ID,
+		AdminID:    payload.AdminID,
+		Email:      normalizeLoginEmail(user.Email),
+		UnlockedAt: time.Now().UnixMilli(),
+	})
+}
+
+func (s *service) PurgeStaleLoginThrottles(ctx context.Context) error {
+	now := time.Now()
+	n, _, err := s.userRepo.DeleteStaleLoginThrottles(ctx, now.Add(-loginFailureWindow).UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		s.log.Info().Int64("count", n).Msg("purged stale login throttles")
+	}
+
+	return nil
+}
+
+// claimLoginAttempt counts the login as failed on the account and the client ip until it succeeds,
+// and turns it away while either of them is blocked
+func (s *service) claimLoginAttempt(ctx context.Context, keys []entity.LoginThrottleKey, now time.Time) ([]entity.LoginThrottleCounter, int, error) {
+	return s.userRepo.ClaimLoginAttempt(ctx, entity.ClaimLoginAttempt{
+		Keys: keys,
+		Throttles: map[string]entity.LoginThrottle{
+			entity.LoginThrottleScopeAccount: s.cfg.LoginThrottle,
+			entity.LoginThrottleScopeIP:      s.cfg.LoginIPThrottle,
+		},
+		Now:         now.UnixMilli(),
+		WindowStart: now.Add(-loginFailureWindow).UnixMilli(),
+	})
+}
+
+// loginFailed leaves the claimed attempt counted, the caller always gets the same error whether
+// the email is unknown or the password wrong
+func (s *service) loginFailed(counters []entity.LoginThrottleCounter) (int, error) {
+	s.logLoginLockouts(counters)
+	return http.StatusUnauthorized, errors.Wrap(errorer.ErrInvalidCredentials, errorer.ErrInvalidCredentials.Error())
+}
+
+func (s *service) logLoginLockouts(counters []entity.LoginThrottleCounter) {
+	for _, v := range counters {
+		throttle := s.cfg.LoginThrottle
+		if v.Key.Scope == entity.LoginThrottleScopeIP {
+			throttle = s.cfg.LoginIPThrottle
+		}
+		if v.FailedAttempts >= throttle.MaxAttempts {
+			s.log.Warn().Str("scope", v.Key.Scope).Str("key", v.Key.Key).Int("failures", v.FailedAttempts).Msg("login locked out")
+		}
+	}
+}
+
+// loginSucceeded forgets the failures of the account, the client ip only gets its claimed attempt
+// back so logging into one account doesn't clear the guesses made at others
+func (s *service) loginSucceeded(ctx context.Context, counters []entity.LoginThrottleCounter) (int, error) {
+	for _, v := range counters {
+		var (
+			code int
+			err  error
+		)
+		if v.Key.Scope == entity.LoginThrottleScopeAccount {
+			code, err = s.userRepo.ResetLoginFailures(ctx, v.Key)
+		} else {
+			code, err = s.userRepo.ReleaseLoginAttempt(ctx, v)
+		}
+		if err != nil {
+			return code, err
+		}
+	}
+
+	return http.StatusOK, nil
+}
+
+func loginThrottleKeys(email string, ip string) []entity.LoginThrottleKey {
+	keys := []entity.LoginThrottleKey{{Scope: entity.LoginThrottleScopeAccount, Key: normalizeLoginEmail(email)}}
+	if ip != "" {
+		keys = append(keys, entity.LoginThrottleKey{Scope: entity.LoginThrottleScopeIP, Key: ip})
+	}
+	return keys
+}
+
+func normalizeLoginEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// comparePasswordOfUnknownUser spends as long as checking a real password, so response times don't
+// give away which emails have an account
+func (s *service) comparePasswordOfUnknownUser(password string) {
+	dummyPasswordHashOnce.Do(func() {
+		dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), s.cfg.Salt)
+	})
+	_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+}
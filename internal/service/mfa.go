@@ -0,0 +1,374 @@
+package service
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/helper/totp"
+	"github.com/ovrrtd/openidea-bank/internal/helper/validator"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/ovrrtd/openidea-bank/internal/model/response"
+	"github.com/pkg/errors"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	mfaChallengeTTL         = 5 * time.Minute
+	mfaChallengeMaxAttempts = 5
+	mfaRecoveryCodeCount    = 10
+	// recovery codes leave out characters that are easy to mix up when copied by hand
+	mfaRecoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	// mfaMaxAttempts wrong codes in a row lock code entry for mfaLockout, across challenges and sessions
+	mfaMaxAttempts = 10
+	mfaLockout     = 30 * time.Minute
+)
+
+// EnrollMfa starts a TOTP enrollment, it only takes effect once a code from it is confirmed with VerifyMfa
+func (s *service) EnrollMfa(ctx context.Context, userID string) (*response.MfaEnrollment, int, error) {
+	user, code, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, code, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, err.Error())
+	}
+	sealed, err := s.sealMfaSecret(secret)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, err.Error())
+	}
+	now := time.Now().UnixMilli()
+	code, err = s.userRepo.SaveMfaSecret(ctx, entity.UserMfa{
+		UserID:    userID,
+		Secret:    sealed,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		return nil, code, err
+	}
+
+	uri := totp.URI(s.cfg.MfaIssuer, user.Email, secret)
+	qr, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, err.Error())
+	}
+
+	return &response.MfaEnrollment{
+		Secret:     secret,
+		OtpauthURI: uri,
+		QrCode:     base64.StdEncoding.EncodeToString(qr),
+	}, http.StatusOK, nil
+}
+
+// VerifyMfa confirms an enrollment with a code from the authenticator app and issues the recovery codes
+func (s *service) VerifyMfa(ctx context.Context, payload request.MfaCode) (*response.MfaRecoveryCodes, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	mfa, code, err := s.userRepo.GetMfa(ctx, payload.UserID)
+	if err != nil {
+		return nil, code, err
+	}
+	if mfa.EnabledAt != 0 {
+		return nil, http.StatusConflict, errors.Wrap(errorer.ErrMfaAlreadyEnabled, errorer.ErrMfaAlreadyEnabled.Error())
+	}
+	secret, err := s.openMfaSecret(mfa.Secret)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, err.Error())
+	}
+
+	now := time.Now()
+	step, ok := totp.Validate(secret, strings.TrimSpace(payload.Code), now)
+	if !ok {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInvalidMfaCode, errorer.ErrInvalidMfaCode.Error())
+	}
+
+	codes, records, err := newRecoveryCodes(payload.UserID, now)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, err.Error())
+	}
+	code, err = s.userRepo.EnableMfa(ctx, entity.EnableMfa{
+		UserID:        payload.UserID,
+		Step:          step,
+		RecoveryCodes: records,
+		EnabledAt:     now.UnixMilli(),
+	})
+	if err != nil {
+		return nil, code, err
+	}
+
+	return &response.MfaRecoveryCodes{RecoveryCodes: codes}, http.StatusOK, nil
+}
+
+// DisableMfa turns two-factor authentication off, it takes a current code so a stolen session alone can't
+func (s *service) DisableMfa(ctx context.Context, payload request.MfaCode) (int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	now := time.Now()
+	code, err := s.checkEnabledMfaCode(ctx, payload.UserID, payload.Code, now)
+	if err != nil {
+		return code, err
+	}
+
+	return s.userRepo.DisableMfa(ctx, payload.UserID, now.UnixMilli())
+}
+
+// RegenerateMfaRecoveryCodes replaces the recovery codes of the user, the old ones stop working
+func (s *service) RegenerateMfaRecoveryCodes(ctx context.Context, payload request.MfaCode) (*response.MfaRecoveryCodes, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	now := time.Now()
+	code, err := s.checkEnabledMfaCode(ctx, payload.UserID, payload.Code, now)
+	if err != nil {
+		return nil, code, err
+	}
+
+	codes, records, err := newRecoveryCodes(payload.UserID, now)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, err.Error())
+	}
+	code, err = s.userRepo.ReplaceMfaRecoveryCodes(ctx, entity.ReplaceMfaRecoveryCodes{
+		UserID:        payload.UserID,
+		RecoveryCodes: records,
+		CreatedAt:     now.UnixMilli(),
+	})
+	if err != nil {
+		return nil, code, err
+	}
+
+	return &response.MfaRecoveryCodes{RecoveryCodes: codes}, http.StatusOK, nil
+}
+
+// LoginMfa finishes a login that Login answered with a challenge, in exchange for a TOTP or recovery code
+func (s *service) LoginMfa(ctx context.Context, payload request.LoginMfa) (*response.Login, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	now := time.Now()
+	challenge, code, err := s.userRepo.ClaimMfaChallengeAttempt(ctx, hashToken(payload.ChallengeToken), now.UnixMilli(), mfaChallengeMaxAttempts)
+	if err != nil {
+		return nil, code, err
+	}
+	// codes count against the user as well, otherwise a stolen password could keep starting new challenges
+	code, err = s.checkEnabledMfaCode(ctx, challenge.UserID, payload.Code, now)
+	if err != nil {
+		switch errors.Cause(err) {
+		case errorer.ErrInvalidMfaCode:
+			// the attempt was already counted, the challenge stops working once it runs out of them
+			return nil, http.StatusUnauthorized, err
+		case errorer.ErrMfaNotEnabled:
+			return nil, http.StatusUnauthorized, errors.Wrap(errorer.ErrInvalidMfaChallenge, errorer.ErrInvalidMfaChallenge.Error())
+		}
+		return nil, code, err
+	}
+
+	code, err = s.userRepo.DeleteMfaChallenge(ctx, challenge.ID)
+	if err != nil {
+		return nil, code, err
+	}
+	user, code, err := s.userRepo.FindByID(ctx, challenge.UserID)
+	if err != nil {
+		return nil, code, err
+	}
+	tokens, code, err := s.issueTokens(ctx, user.ID)
+	if err != nil {
+		return nil, code, err
+	}
+
+	return &response.Login{
+		Name:         user.Name,
+		Email:        user.Email,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	}, http.StatusOK, nil
+}
+
+// mfaChallenge returns the challenge login hands out instead of tokens when the user has 2FA enabled,
+// a nil challenge means the user has not
+func (s *service) mfaChallenge(ctx context.Context, userID string) (*response.Login, int, error) {
+	mfa, code, err := s.userRepo.GetMfa(ctx, userID)
+	if err != nil {
+		if code == http.StatusNotFound {
+			return nil, http.StatusOK, nil
+		}
+		return nil, code, err
+	}
+	if mfa.EnabledAt == 0 {
+		return nil, http.StatusOK, nil
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, err.Error())
+	}
+	now := time.Now()
+	code, err = s.userRepo.CreateMfaChallenge(ctx, entity.MfaChallenge{
+		ID:        common.GenerateULID(),
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		ExpiresAt: now.Add(mfaChallengeTTL).UnixMilli(),
+		CreatedAt: now.UnixMilli(),
+	})
+	if err != nil {
+		return nil, code, err
+	}
+
+	return &response.Login{
+		Status:             response.LoginStatusMfaRequired,
+		ChallengeToken:     token,
+		ChallengeExpiresIn: int64(mfaChallengeTTL.Seconds()),
+	}, http.StatusOK, nil
+}
+
+// checkEnabledMfaCode lets a request through only with a valid code of the user's enabled 2FA. Every
+// code entered counts against the user until one is right, too many wrong ones lock code entry.
+func (s *service) checkEnabledMfaCode(ctx context.Context, userID string, mfaCode string, now time.Time) (int, error) {
+	mfa, code, err := s.userRepo.ClaimMfaAttempt(ctx, entity.ClaimMfaAttempt{
+		UserID:      userID,
+		MaxAttempts: mfaMaxAttempts,
+		Now:         now.UnixMilli(),
+	})
+	if err != nil {
+		return code, err
+	}
+
+	ok, code, err := s.useMfaCode(ctx, mfa, mfaCode, now)
+	if err != nil {
+		return code, err
+	}
+	if ok {
+		return s.userRepo.ResetMfaAttempts(ctx, userID)
+	}
+	if mfa.FailedAttempts < mfaMaxAttempts {
+		return http.StatusBadRequest, errors.Wrap(errorer.ErrInvalidMfaCode, errorer.ErrInvalidMfaCode.Error())
+	}
+
+	s.log.Warn().Str("userId", userID).Msg("two-factor authentication locked after too many wrong codes")
+	code, err = s.userRepo.LockMfa(ctx, userID, now.UnixMilli(), now.Add(mfaLockout).UnixMilli())
+	if err != nil {
+		return code, err
+	}
+	return http.StatusLocked, errors.Wrap(errorer.ErrMfaLocked, errorer.ErrMfaLocked.Error())
+}
+
+// useMfaCode spends a TOTP code or a recovery code, either only works once
+func (s *service) useMfaCode(ctx context.Context, mfa *entity.UserMfa, mfaCode string, now time.Time) (bool, int, error) {
+	mfaCode = strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(mfaCode))
+
+	var code int
+	var err error
+	if len(mfaCode) == totp.Digits && strings.Trim(mfaCode, "0123456789") == "" {
+		secret, sealErr := s.openMfaSecret(mfa.Secret)
+		if sealErr != nil {
+			return false, http.StatusInternalServerError, errors.Wrap(sealErr, sealErr.Error())
+		}
+		step, ok := totp.Validate(secret, mfaCode, now)
+		if !ok {
+			return false, http.StatusOK, nil
+		}
+		code, err = s.userRepo.UseMfaStep(ctx, mfa.UserID, step)
+	} else {
+		code, err = s.userRepo.UseMfaRecoveryCode(ctx, mfa.UserID, hashToken(mfaCode), now.UnixMilli())
+	}
+	if err != nil {
+		if errors.Cause(err) == errorer.ErrInvalidMfaCode {
+			return false, http.StatusOK, nil
+		}
+		return false, code, err
+	}
+
+	return true, http.StatusOK, nil
+}
+
+// newRecoveryCodes returns codes to show the user once and the records storing their hashes
+func newRecoveryCodes(userID string, now time.Time) ([]string, []entity.MfaRecoveryCode, error) {
+	codes := make([]string, mfaRecoveryCodeCount)
+	records := make([]entity.MfaRecoveryCode, mfaRecoveryCodeCount)
+	max := big.NewInt(int64(len(mfaRecoveryCodeAlphabet)))
+	for i := range codes {
+		raw := make([]byte, 10)
+		for j := range raw {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return nil, nil, err
+			}
+			raw[j] = mfaRecoveryCodeAlphabet[n.Int64()]
+		}
+		codes[i] = string(raw[:5]) + "-" + string(raw[5:])
+		records[i] = entity.MfaRecoveryCode{
+			ID:        common.GenerateULID(),
+			UserID:    userID,
+			CodeHash:  hashToken(string(raw)),
+			CreatedAt: now.UnixMilli(),
+		}
+	}
+
+	return codes, records, nil
+}
+
+// sealMfaSecret encrypts a TOTP secret for storage with AES-GCM, unlike passwords it has to be read back
+func (s *service) sealMfaSecret(secret string) (string, error) {
+	gcm, err := s.mfaCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+func (s *service) openMfaSecret(sealed string) (string, error) {
+	gcm, err := s.mfaCipher()
+	if err != nil {
+		return "", err
+	}
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	if len(raw) < gcm.NonceSize() {
+		return "", errors.New("sealed mfa secret is too short")
+	}
+	secret, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(secret), nil
+}
+
+func (s *service) mfaCipher() (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(s.cfg.MfaSecretKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/helper/validator"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// SetTransactionPin sets or changes the PIN money movements are confirmed with, it asks for the
+// password so a stolen access token alone can't replace it. Wrong passwords count toward the login
+// throttle of the account and the client ip, so the endpoint can't be used to guess the password.
+func (s *service) SetTransactionPin(ctx context.Context, payload request.SetPin) (int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+	if isWeakPin(payload.Pin) {
+		return http.StatusBadRequest, errors.Wrap(errorer.ErrWeakPin, errorer.ErrWeakPin.Error())
+	}
+
+	user, code, err := s.userRepo.FindByID(ctx, payload.UserID)
+	if err != nil {
+		return code, err
+	}
+	now := time.Now()
+	counters, code, err := s.claimLoginAttempt(ctx, loginThrottleKeys(user.Email, payload.IP), now)
+	if err != nil {
+		return code, err
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(payload.Password))
+	if err != nil {
+		s.logLoginLockouts(counters)
+		_, auditErr := s.userRepo.CreateAuditLog(ctx, entity.AuditLog{
+			ActorID:    user.ID,
+			Action:     entity.AuditActionPasswordFailed,
+			TargetType: entity.AuditTargetUser,
+			TargetID:   user.ID,
+			Reason:     "wrong password setting the transaction pin",
+			Metadata:   map[string]interface{}{"ip": payload.IP},
+			CreatedAt:  now.UnixMilli(),
+		})
+		if auditErr != nil {
+			s.log.Error().Err(auditErr).Str("userId", user.ID).Msg("failed to audit a wrong password")
+		}
+		return http.StatusForbidden, errors.Wrap(errorer.ErrWrongPassword, errorer.ErrWrongPassword.Error())
+	}
+	if code, err := s.loginSucceeded(ctx, counters); err != nil {
+		return code, err
+	}
+
+	hashedPin, err := bcrypt.GenerateFromPassword([]byte(payload.Pin), s.cfg.Salt)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(err, err.Error())
+	}
+	return s.userRepo.SetPin(ctx, entity.UserPin{
+		UserID:    payload.UserID,
+		PinHash:   string(hashedPin),
+		CreatedAt: now.UnixMilli(),
+		UpdatedAt: now.UnixMilli(),
+	})
+}
+
+// VerifyTransactionPin checks the PIN of a money movement, PinMaxAttempts wrong ones in a row lock it for PinLockout
+func (s *service) VerifyTransactionPin(ctx context.Context, payload request.VerifyPin) (int, error) {
+	if payload.Pin == "" {
+		return http.StatusForbidden, errors.Wrap(errorer.ErrPinRequired, errorer.ErrPinRequired.Error())
+	}
+
+	now := time.Now()
+	pin, code, err := s.userRepo.ClaimPinAttempt(ctx, entity.ClaimPinAttempt{
+		UserID:      payload.UserID,
+		MaxAttempts: s.cfg.PinMaxAttempts,
+		Now:         now.UnixMilli(),
+		LockUntil:   now.Add(s.cfg.PinLockout).UnixMilli(),
+	})
+	if err != nil {
+		return code, err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(pin.PinHash), []byte(payload.Pin))
+	if err == nil {
+		return s.userRepo.ResetPinAttempts(ctx, payload.UserID)
+	}
+	if pin.FailedAttempts < s.cfg.PinMaxAttempts {
+		return http.StatusForbidden, errors.Wrap(errorer.ErrInvalidPin, errorer.ErrInvalidPin.Error())
+	}
+
+	s.log.Warn().Str("userId", payload.UserID).Msg("transaction pin locked after too many wrong attempts")
+	code, err = s.userRepo.LockPin(ctx, payload.UserID, now.UnixMilli(), now.Add(s.cfg.PinLockout).UnixMilli())
+	if err != nil {
+		return code, err
+	}
+	return http.StatusLocked, errors.Wrap(errorer.ErrPinLocked, errorer.ErrPinLocked.Error())
+}
+
+// isWeakPin rejects PINs of one repeated digit and straight runs like 123456 or 987654
+func isWeakPin(pin string) bool {
+	same, up, down := true, true, true
+	for i := 1; i < len(pin); i++ {
+		diff := int(pin[i]) - int(pin[i-1])
+		same = same && diff == 0
+		up = up && diff == 1
+		down = down && diff == -1
+	}
+	return same || up || down
+}
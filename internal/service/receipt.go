@@ -0,0 +1,77 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/helper/receipt"
+	"github.com/ovrrtd/openidea-bank/internal/helper/validator"
+	"github.com/ovrrtd/openidea-bank/internal/model/money"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/ovrrtd/openidea-bank/internal/model/response"
+	"github.com/pkg/errors"
+)
+
+// GetTransactionReceipt renders a PDF receipt for one of the user's transactions
+func (s *service) GetTransactionReceipt(ctx context.Context, payload request.GetTransaction) ([]byte, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	transaction, code, err := s.balanceRepo.GetTransaction(ctx, payload.TransactionID, payload.UserID)
+	if err != nil {
+		return nil, code, err
+	}
+
+	signature := receipt.Sign([]byte(s.cfg.ReceiptSecret), transaction.ID, transaction.Amount, transaction.Currency, transaction.CreatedAt)
+	var pdf bytes.Buffer
+	err = receipt.Render(&pdf, receipt.Receipt{
+		TransactionID:     transaction.ID,
+		Status:            transaction.Status,
+		Amount:            money.Format(transaction.Amount, transaction.Currency),
+		Currency:          transaction.Currency,
+		BankName:          transaction.BankName,
+		BankAccountNumber: transaction.BankAccountNumber,
+		CreatedAt:         time.UnixMilli(transaction.CreatedAt),
+		VerifyURL:         receipt.VerifyURL(s.cfg.PublicURL, transaction.ID, signature),
+	})
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(errorer.ErrInternalServer, err.Error())
+	}
+
+	return pdf.Bytes(), http.StatusOK, nil
+}
+
+// VerifyReceipt checks the signature from a receipt's QR code against the stored transaction.
+// Unknown transactions and bad signatures get the same answer so the endpoint cannot be used to probe ids.
+func (s *service) VerifyReceipt(ctx context.Context, payload request.VerifyReceipt) (*response.ReceiptVerification, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	transaction, code, err := s.balanceRepo.FindTransactionByID(ctx, payload.TransactionID)
+	if err != nil && code != http.StatusNotFound {
+		return nil, code, err
+	}
+	if err != nil || !receipt.Verify([]byte(s.cfg.ReceiptSecret), payload.Signature, transaction.ID, transaction.Amount, transaction.Currency, transaction.CreatedAt) {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrReceiptInvalid, errorer.ErrReceiptInvalid.Error())
+	}
+
+	res := &response.ReceiptVerification{
+		Valid:         true,
+		TransactionID: transaction.ID,
+		Status:        transaction.Status,
+		Balance:       money.Format(transaction.Amount, transaction.Currency),
+		Currency:      transaction.Currency,
+		CreatedAt:     transaction.CreatedAt,
+	}
+	res.Recipient.BankAccountNumber = receipt.MaskAccountNumber(transaction.BankAccountNumber)
+	res.Recipient.BankName = transaction.BankName
+
+	return res, http.StatusOK, nil
+}
@@ -2,8 +2,14 @@ package service
 
 import (
 	"context"
+	"io"
 	"mime/multipart"
+	"time"
 
+	"github.com/ovrrtd/openidea-bank/internal/helper/bank"
+	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/helper/jwt"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
 	"github.com/ovrrtd/openidea-bank/internal/model/request"
 	"github.com/ovrrtd/openidea-bank/internal/model/response"
 	"github.com/ovrrtd/openidea-bank/internal/repository"
@@ -16,19 +22,127 @@ type Service interface {
 	Register(ctx context.Context, payload request.Register) (*response.Register, int, error)
 	Login(ctx context.Context, payload request.Login) (*response.Login, int, error)
 	GetUserByID(ctx context.Context, id string) (*response.User, int, error)
+	UnlockUser(ctx context.Context, payload request.UnlockUser) (int, error)
+	PurgeStaleLoginThrottles(ctx context.Context) error
+	RefreshToken(ctx context.Context, payload request.RefreshToken) (*response.Token, int, error)
+	Logout(ctx context.Context, payload request.Logout) (int, error)
+	VerifyAccessToken(ctx context.Context, token string) (*common.UserClaims, int, error)
+	GetJWKS(ctx context.Context) (*jwt.JWKS, int, error)
+	PurgeExpiredTokens(ctx context.Context) error
+	// Two-factor authentication
+	EnrollMfa(ctx context.Context, userID string) (*response.MfaEnrollment, int, error)
+	VerifyMfa(ctx context.Context, payload request.MfaCode) (*response.MfaRecoveryCodes, int, error)
+	DisableMfa(ctx context.Context, payload request.MfaCode) (int, error)
+	RegenerateMfaRecoveryCodes(ctx context.Context, payload request.MfaCode) (*response.MfaRecoveryCodes, int, error)
+	LoginMfa(ctx context.Context, payload request.LoginMfa) (*response.Login, int, error)
+	// Transaction PIN
+	SetTransactionPin(ctx context.Context, payload request.SetPin) (int, error)
+	VerifyTransactionPin(ctx context.Context, payload request.VerifyPin) (int, error)
 	// s3
 	UploadImage(ctx context.Context, file *multipart.FileHeader) (string, int, error)
 
 	// Balance
-	AddBalance(ctx context.Context, payload request.AddBalance) (int, error)
-	CreateTransaction(ctx context.Context, payload request.CreateTransaction) (int, error)
+	AddBalance(ctx context.Context, payload request.AddBalance) (*response.TopUp, int, error)
+	CreateTransaction(ctx context.Context, payload request.CreateTransaction) (*response.Transaction, int, error)
+	GetTransaction(ctx context.Context, payload request.GetTransaction) (*response.Transaction, int, error)
+	GetTransactionReceipt(ctx context.Context, payload request.GetTransaction) ([]byte, int, error)
+	VerifyReceipt(ctx context.Context, payload request.VerifyReceipt) (*response.ReceiptVerification, int, error)
+	ProcessOutboundTransactions(ctx context.Context) error
+	CreateTransfer(ctx context.Context, payload request.CreateTransfer) (*response.Transfer, int, error)
+	ReverseTransaction(ctx context.Context, payload request.ReverseTransaction) (*response.Reversal, int, error)
 	GetBalances(ctx context.Context, userId string) ([]response.Balance, int, error)
-	GetBalancesHistory(ctx context.Context, payload request.GetBalancesHistory) ([]response.GetBalancesHistory, int, error)
+	GetBalancesAt(ctx context.Context, payload request.GetBalancesAt) ([]response.BalanceAt, int, error)
+	SnapshotBalances(ctx context.Context) error
+	PrepareStatement(ctx context.Context, payload request.GetStatement) (*Statement, int, error)
+	WriteStatement(ctx context.Context, stmt *Statement, w io.Writer) error
+	GetBalancesHistory(ctx context.Context, payload request.GetBalancesHistory) ([]response.GetBalancesHistory, *common.Meta, int, error)
+
+	// Top up review
+	GetTopUps(ctx context.Context, payload request.GetTopUps) ([]response.TopUp, int, error)
+	ApproveTopUp(ctx context.Context, payload request.ReviewTopUp) (*response.TopUp, int, error)
+	RejectTopUp(ctx context.Context, payload request.ReviewTopUp) (*response.TopUp, int, error)
+
+	// Transfer limits
+	GetTransferLimits(ctx context.Context, userID string) ([]response.TransferLimit, int, error)
+	SetUserTransferLimits(ctx context.Context, payload request.SetTransferLimits) ([]response.TransferLimit, int, error)
+	ResetUserTransferLimit(ctx context.Context, payload request.ResetTransferLimit) ([]response.TransferLimit, int, error)
+
+	// Bank directory
+	GetBanks(ctx context.Context) ([]response.Bank, int, error)
+
+	// Beneficiary
+	CreateBeneficiary(ctx context.Context, payload request.CreateBeneficiary) (*response.Beneficiary, int, error)
+	GetBeneficiaries(ctx context.Context, userID string) ([]response.Beneficiary, int, error)
+	GetBeneficiary(ctx context.Context, payload request.GetBeneficiary) (*response.Beneficiary, int, error)
+	UpdateBeneficiary(ctx context.Context, payload request.UpdateBeneficiary) (*response.Beneficiary, int, error)
+	DeleteBeneficiary(ctx context.Context, payload request.GetBeneficiary) (int, error)
+
+	// Standing order
+	CreateStandingOrder(ctx context.Context, payload request.CreateStandingOrder) (*response.StandingOrder, int, error)
+	GetStandingOrders(ctx context.Context, userID string) ([]response.StandingOrder, int, error)
+	GetStandingOrder(ctx context.Context, payload request.GetStandingOrder) (*response.StandingOrder, int, error)
+	UpdateStandingOrder(ctx context.Context, payload request.UpdateStandingOrder) (*response.StandingOrder, int, error)
+	CancelStandingOrder(ctx context.Context, payload request.GetStandingOrder) (*response.StandingOrder, int, error)
+	RunStandingOrders(ctx context.Context) error
+
+	// Hold
+	CreateHold(ctx context.Context, payload request.CreateHold) (*response.Hold, int, error)
+	GetHolds(ctx context.Context, userID string) ([]response.Hold, int, error)
+	CaptureHold(ctx context.Context, payload request.SettleHold) (*response.Hold, int, error)
+	ReleaseHold(ctx context.Context, payload request.SettleHold) (*response.Hold, int, error)
+	ExpireHolds(ctx context.Context) error
+
+	// FX
+	UpsertFxRates(ctx context.Context, payload request.UpsertFxRates) (int, error)
+	GetFxRates(ctx context.Context) ([]response.FxRate, int, error)
+	CreateFxQuote(ctx context.Context, payload request.CreateFxQuote) (*response.FxQuote, int, error)
+	ConvertFx(ctx context.Context, payload request.ConvertFx) (*response.FxConversion, int, error)
+
+	// Idempotency
+	ReserveIdempotencyKey(ctx context.Context, payload request.ReserveIdempotencyKey) (*response.IdempotentReplay, int, error)
+	CompleteIdempotencyKey(ctx context.Context, payload request.CompleteIdempotencyKey) (int, error)
+	PurgeExpiredIdempotencyKeys(ctx context.Context) error
 }
 
 type Config struct {
-	Salt      int
-	JwtSecret string
+	Salt int
+	// JwtKeys signs access tokens and verifies the ones presented back
+	JwtKeys *jwt.KeySet
+	// AccessTokenTTL is kept short since access tokens are only revoked on logout, RefreshTokenTTL
+	// is how long a login lasts without being refreshed
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// MfaIssuer names the bank in authenticator apps, MfaSecretKey encrypts the stored TOTP secrets
+	MfaIssuer    string
+	MfaSecretKey string
+	// PinMaxAttempts wrong transaction PINs in a row lock PIN entry for PinLockout
+	PinMaxAttempts int
+	PinLockout     time.Duration
+	// LoginThrottle and LoginIPThrottle slow down password guessing per account and per client ip
+	LoginThrottle   entity.LoginThrottle
+	LoginIPThrottle entity.LoginThrottle
+
+	IdempotencyRetention time.Duration
+	FxQuoteTTL           time.Duration
+	HoldTTL              time.Duration
+	// ReceiptSecret signs the verification links printed on receipts, PublicURL is where they point
+	ReceiptSecret string
+	PublicURL     string
+	// TransferMaxAttempts is how often submitting a transaction to the bank is tried before it fails,
+	// and how many status checks in a row may go unanswered before a transfer is left unresolved
+	TransferMaxAttempts int
+	// TransferReturnWindow is how long after settling the bank is still asked whether a transfer was returned
+	TransferReturnWindow time.Duration
+	// StandingOrderMaxAttempts is how often a standing order run is tried before it is given up
+	StandingOrderMaxAttempts int
+	// BeneficiaryCoolingOffLimit caps the total sent per currency, in minor units, to a recipient account
+	// during the first BeneficiaryCoolingOff after it was saved as a beneficiary or first sent to
+	BeneficiaryCoolingOff      time.Duration
+	BeneficiaryCoolingOffLimit int64
+	// TransferLimits are the default limits per currency, users can be given their own by an admin
+	TransferLimits map[string]entity.TransferLimit
+	// Banks is the directory bank codes in requests are checked against
+	Banks *bank.Directory
 }
 
 type service struct {
@@ -37,6 +151,11 @@ type service struct {
 	userRepo    repository.UserRepository
 	s3Repo      repository.S3Repository
 	balanceRepo repository.BalanceRepository
+
+	idempotencyRepo repository.IdempotencyRepository
+	tokenRepo       repository.TokenRepository
+	fxRepo          repository.FxRepository
+	bankGateway     repository.BankGateway
 }
 
 func New(
@@ -45,6 +164,10 @@ func New(
 	userRepo repository.UserRepository,
 	s3Repo repository.S3Repository,
 	balanceRepo repository.BalanceRepository,
+	idempotencyRepo repository.IdempotencyRepository,
+	tokenRepo repository.TokenRepository,
+	fxRepo repository.FxRepository,
+	bankGateway repository.BankGateway,
 ) Service {
 	return &service{
 		cfg:         cfg,
@@ -52,5 +175,10 @@ func New(
 		userRepo:    userRepo,
 		s3Repo:      s3Repo,
 		balanceRepo: balanceRepo,
+
+		idempotencyRepo: idempotencyRepo,
+		tokenRepo:       tokenRepo,
+		fxRepo:          fxRepo,
+		bankGateway:     bankGateway,
 	}
 }
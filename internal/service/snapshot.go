@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/helper/validator"
+	"github.com/ovrrtd/openidea-bank/internal/model/money"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/ovrrtd/openidea-bank/internal/model/response"
+	"github.com/pkg/errors"
+)
+
+// snapshotGrace leaves room for entries stamped just before midnight but committed after it
+const snapshotGrace = 5 * time.Minute
+
+// GetBalancesAt returns what the user's balance was per currency at an instant in the past
+func (s *service) GetBalancesAt(ctx context.Context, payload request.GetBalancesAt) ([]response.BalanceAt, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+	at, err := time.Parse(time.RFC3339, payload.At)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrBadRequest, "at must be an RFC3339 time")
+	}
+
+	if _, code, err := s.userRepo.FindByID(ctx, payload.UserID); err != nil {
+		return nil, code, err
+	}
+
+	balances, code, err := s.balanceRepo.GetBalancesAt(ctx, payload.UserID, at.UnixMilli())
+	if err != nil {
+		return nil, code, err
+	}
+
+	res := make([]response.BalanceAt, len(balances))
+	for i, v := range balances {
+		res[i] = response.BalanceAt{
+			Balance:  money.Format(v.Balance, v.Currency),
+			Currency: v.Currency,
+			At:       payload.At,
+		}
+	}
+
+	return res, code, nil
+}
+
+// SnapshotBalances records the daily balance snapshots point in time queries start from
+func (s *service) SnapshotBalances(ctx context.Context) error {
+	days, _, err := s.balanceRepo.CreateBalanceSnapshots(ctx, time.Now().Add(-snapshotGrace).UnixMilli())
+	if err != nil {
+		return err
+	}
+	if days > 0 {
+		s.log.Info().Int("days", days).Msg("created balance snapshots")
+	}
+
+	return nil
+}
@@ -0,0 +1,332 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/helper/schedule"
+	"github.com/ovrrtd/openidea-bank/internal/helper/validator"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/ovrrtd/openidea-bank/internal/model/money"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/ovrrtd/openidea-bank/internal/model/response"
+	"github.com/pkg/errors"
+)
+
+const (
+	standingOrderBatchSize = 50
+	standingOrderLease     = time.Minute
+	// standingOrderRetryDelay is the wait before retrying a failed run, doubled on every further failure
+	standingOrderRetryDelay = 5 * time.Minute
+)
+
+func (s *service) CreateStandingOrder(ctx context.Context, payload request.CreateStandingOrder) (*response.StandingOrder, int, error) {
+	payload.Transaction.UserID = payload.UserID
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+	if code, err := s.resolveRecipient(ctx, &payload.Transaction); err != nil {
+		return nil, code, err
+	}
+
+	now := time.Now()
+	order := entity.StandingOrder{
+		ID:        common.GenerateULID(),
+		UserID:    payload.UserID,
+		Status:    entity.StandingOrderStatusActive,
+		CreatedAt: now.UnixMilli(),
+		UpdatedAt: now.UnixMilli(),
+	}
+	if code, err := applyStandingOrderChanges(&order, payload.Description, payload.Schedule, payload.Transaction, now); err != nil {
+		return nil, code, err
+	}
+
+	code, err := s.balanceRepo.CreateStandingOrder(ctx, order)
+	if err != nil {
+		return nil, code, err
+	}
+
+	return toStandingOrderResponse(order), code, nil
+}
+
+func (s *service) GetStandingOrders(ctx context.Context, userID string) ([]response.StandingOrder, int, error) {
+	orders, code, err := s.balanceRepo.GetStandingOrders(ctx, userID)
+	if err != nil {
+		return nil, code, err
+	}
+
+	res := make([]response.StandingOrder, len(orders))
+	for i, v := range orders {
+		res[i] = *toStandingOrderResponse(v)
+	}
+
+	return res, code, nil
+}
+
+func (s *service) GetStandingOrder(ctx context.Context, payload request.GetStandingOrder) (*response.StandingOrder, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	order, code, err := s.balanceRepo.GetStandingOrder(ctx, payload.StandingOrderID, payload.UserID)
+	if err != nil {
+		return nil, code, err
+	}
+
+	return toStandingOrderResponse(*order), code, nil
+}
+
+// UpdateStandingOrder replaces an order's schedule and transaction, a failed order becomes active again
+func (s *service) UpdateStandingOrder(ctx context.Context, payload request.UpdateStandingOrder) (*response.StandingOrder, int, error) {
+	payload.Transaction.UserID = payload.UserID
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+	if code, err := s.resolveRecipient(ctx, &payload.Transaction); err != nil {
+		return nil, code, err
+	}
+
+	order, code, err := s.balanceRepo.GetStandingOrder(ctx, payload.StandingOrderID, payload.UserID)
+	if err != nil {
+		return nil, code, err
+	}
+
+	now := time.Now()
+	if code, err := applyStandingOrderChanges(order, payload.Description, payload.Schedule, payload.Transaction, now); err != nil {
+		return nil, code, err
+	}
+	switch {
+	case payload.Status != "":
+		order.Status = payload.Status
+	case order.Status == entity.StandingOrderStatusFailed:
+		order.Status = entity.StandingOrderStatusActive
+	}
+	order.Attempts = 0
+	order.UpdatedAt = now.UnixMilli()
+
+	code, err = s.balanceRepo.UpdateStandingOrder(ctx, *order)
+	if err != nil {
+		return nil, code, err
+	}
+
+	return toStandingOrderResponse(*order), code, nil
+}
+
+// CancelStandingOrder stops an order for good, it stays listed with its last run
+func (s *service) CancelStandingOrder(ctx context.Context, payload request.GetStandingOrder) (*response.StandingOrder, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	order, code, err := s.balanceRepo.GetStandingOrder(ctx, payload.StandingOrderID, payload.UserID)
+	if err != nil {
+		return nil, code, err
+	}
+
+	order.Status = entity.StandingOrderStatusCancelled
+	order.NextRunAt = 0
+	order.UpdatedAt = time.Now().UnixMilli()
+	code, err = s.balanceRepo.UpdateStandingOrder(ctx, *order)
+	if err != nil {
+		return nil, code, err
+	}
+
+	return toStandingOrderResponse(*order), code, nil
+}
+
+// RunStandingOrders executes the standing orders that are due. A failed run is retried with a growing delay,
+// after the last attempt it is given up: one-off orders fail, recurring ones wait for their next run.
+func (s *service) RunStandingOrders(ctx context.Context) error {
+	now := time.Now()
+	lease := now.Add(standingOrderLease).UnixMilli()
+	orders, _, err := s.balanceRepo.ClaimStandingOrders(ctx, entity.ClaimStandingOrders{
+		Now:        now.UnixMilli(),
+		LeaseUntil: lease,
+		Limit:      standingOrderBatchSize,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, o := range orders {
+		spec, err := standingOrderSpec(o)
+		if err != nil {
+			s.log.Error().Err(err).Str("standingOrderId", o.ID).Msg("standing order has an invalid schedule")
+			continue
+		}
+
+		// runs missed while the scheduler was down collapse into this one
+		status := entity.StandingOrderStatusActive
+		var nextRunAt int64
+		if next, ok := schedule.Next(spec, time.Now()); ok {
+			nextRunAt = next.UnixMilli()
+		} else {
+			status = entity.StandingOrderStatusCompleted
+		}
+
+		checks, _, err := s.outboundChecks(ctx, o.UserID, o.Currency, time.Now())
+		if err != nil {
+			s.log.Error().Err(err).Str("standingOrderId", o.ID).Msg("failed to get transfer limits for standing order")
+			continue
+		}
+
+		runAt := time.Now().UnixMilli()
+		transaction := entity.Transaction{
+			ID:                common.GenerateULID(),
+			UserID:            o.UserID,
+			Amount:            o.Amount,
+			Currency:          o.Currency,
+			BankAccountNumber: o.BankAccountNumber,
+			BankName:          o.BankName,
+			BeneficiaryID:     o.BeneficiaryID,
+			Status:            entity.TransactionStatusPending,
+			NextAttemptAt:     runAt,
+			StandingOrderID:   o.ID,
+			CreatedAt:         runAt,
+			UpdatedAt:         runAt,
+		}
+		code, err := s.balanceRepo.ExecuteStandingOrder(ctx, entity.ExecuteStandingOrder{
+			OrderID:     o.ID,
+			LeasedUntil: lease,
+			Transaction: transaction,
+			Checks:      checks,
+			NextRunAt:   nextRunAt,
+			Status:      status,
+		})
+		if err == nil {
+			s.log.Info().Str("standingOrderId", o.ID).Str("transactionId", transaction.ID).Msg("standing order executed")
+			continue
+		}
+		if code == http.StatusConflict {
+			s.log.Warn().Err(err).Str("standingOrderId", o.ID).Msg("standing order changed before it ran")
+			continue
+		}
+
+		failure := entity.FailStandingOrder{
+			OrderID:     o.ID,
+			LeasedUntil: lease,
+			Attempts:    o.Attempts + 1,
+			NextRunAt:   time.Now().Add(standingOrderRetryDelay << o.Attempts).UnixMilli(),
+			Status:      entity.StandingOrderStatusActive,
+			Error:       errors.Cause(err).Error(),
+			FailedAt:    time.Now().UnixMilli(),
+		}
+		if failure.Attempts >= s.cfg.StandingOrderMaxAttempts {
+			failure.Attempts = 0
+			failure.NextRunAt = nextRunAt
+			failure.Status = entity.StandingOrderStatusActive
+			if status == entity.StandingOrderStatusCompleted {
+				failure.Status = entity.StandingOrderStatusFailed
+			}
+		}
+		s.log.Warn().Err(err).Str("standingOrderId", o.ID).Int("attempt", o.Attempts+1).Str("status", failure.Status).Msg("standing order run failed")
+
+		if _, err := s.balanceRepo.FailStandingOrder(ctx, failure); err != nil {
+			s.log.Error().Err(err).Str("standingOrderId", o.ID).Msg("failed to record standing order failure")
+		}
+	}
+
+	return nil
+}
+
+// applyStandingOrderChanges validates a schedule and transaction and sets them on order along with its next run
+func applyStandingOrderChanges(order *entity.StandingOrder, description string, sched request.StandingOrderSchedule, transaction request.CreateTransaction, now time.Time) (int, error) {
+	timezone := sched.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return http.StatusBadRequest, errors.Wrap(errorer.ErrInvalidSchedule, "unknown timezone "+timezone)
+	}
+
+	amount, code, err := parseAmount(transaction.Balance, transaction.Currency)
+	if err != nil {
+		return code, err
+	}
+
+	start := now
+	if sched.StartAt != "" {
+		start, err = time.Parse(time.RFC3339, sched.StartAt)
+		if err != nil {
+			return http.StatusBadRequest, errors.Wrap(errorer.ErrInvalidSchedule, "startAt must be an RFC3339 time")
+		}
+	}
+	spec := schedule.Spec{Type: sched.Type, Cron: sched.Cron, Start: start.In(loc)}
+	if err := schedule.Validate(spec); err != nil {
+		return http.StatusBadRequest, errors.Wrap(errorer.ErrInvalidSchedule, err.Error())
+	}
+
+	// the start itself counts as a run when it is still ahead
+	after := now
+	if start.After(now) {
+		after = start.Add(-time.Millisecond)
+	}
+	next, ok := schedule.Next(spec, after)
+	if !ok {
+		return http.StatusBadRequest, errors.Wrap(errorer.ErrInvalidSchedule, "schedule has no run in the future")
+	}
+
+	order.Description = description
+	order.ScheduleType = sched.Type
+	order.CronExpression = sched.Cron
+	order.Timezone = timezone
+	order.StartAt = start.UnixMilli()
+	order.Amount = amount
+	order.Currency = transaction.Currency
+	order.BankAccountNumber = transaction.BankAccountNumber
+	order.BankName = transaction.BankName
+	order.BeneficiaryID = transaction.BeneficiaryID
+	order.NextRunAt = next.UnixMilli()
+
+	return http.StatusOK, nil
+}
+
+func standingOrderSpec(order entity.StandingOrder) (schedule.Spec, error) {
+	loc, err := time.LoadLocation(order.Timezone)
+	if err != nil {
+		return schedule.Spec{}, err
+	}
+
+	return schedule.Spec{
+		Type:  order.ScheduleType,
+		Cron:  order.CronExpression,
+		Start: time.UnixMilli(order.StartAt).In(loc),
+	}, nil
+}
+
+func toStandingOrderResponse(order entity.StandingOrder) *response.StandingOrder {
+	res := response.StandingOrder{
+		StandingOrderID:   order.ID,
+		Description:       order.Description,
+		Status:            order.Status,
+		NextRunAt:         order.NextRunAt,
+		LastRunAt:         order.LastRunAt,
+		LastTransactionID: order.LastTransactionID,
+		Attempts:          order.Attempts,
+		LastError:         order.LastError,
+		LastFailedAt:      order.LastFailedAt,
+		CreatedAt:         order.CreatedAt,
+		UpdatedAt:         order.UpdatedAt,
+	}
+	if spec, err := standingOrderSpec(order); err == nil {
+		res.Schedule.StartAt = spec.Start.Format(time.RFC3339)
+	}
+	res.Schedule.Type = order.ScheduleType
+	res.Schedule.Cron = order.CronExpression
+	res.Schedule.Timezone = order.Timezone
+	res.Transaction.BankAccountNumber = order.BankAccountNumber
+	res.Transaction.BankName = order.BankName
+	res.Transaction.BeneficiaryID = order.BeneficiaryID
+	res.Transaction.Currency = order.Currency
+	res.Transaction.Balance = money.Format(order.Amount, order.Currency)
+
+	return &res
+}
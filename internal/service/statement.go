@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/helper/statement"
+	"github.com/ovrrtd/openidea-bank/internal/helper/validator"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/pkg/errors"
+)
+
+// Statement is a statement ready to be written, its opening and closing balances already known
+type Statement struct {
+	Format      string
+	ContentType string
+	Filename    string
+
+	header statement.Header
+	filter entity.GetBalancesHistory
+}
+
+// PrepareStatement validates a statement request and computes its balances, so errors surface before anything is streamed
+func (s *service) PrepareStatement(ctx context.Context, payload request.GetStatement) (*Statement, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+	from, err := time.Parse(time.RFC3339, payload.From)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrBadRequest, "from must be an RFC3339 time")
+	}
+	to, err := time.Parse(time.RFC3339, payload.To)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrBadRequest, "to must be an RFC3339 time")
+	}
+	if to.Before(from) {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrBadRequest, "to must not be before from")
+	}
+
+	user, code, err := s.userRepo.FindByID(ctx, payload.UserID)
+	if err != nil {
+		return nil, code, err
+	}
+
+	opening, code, err := s.balanceAt(ctx, payload.UserID, payload.Currency, from.UnixMilli()-1)
+	if err != nil {
+		return nil, code, err
+	}
+	closing, code, err := s.balanceAt(ctx, payload.UserID, payload.Currency, to.UnixMilli())
+	if err != nil {
+		return nil, code, err
+	}
+
+	header := statement.Header{
+		StatementID:    common.GenerateULID(),
+		AccountID:      user.ID,
+		AccountName:    user.Name,
+		Currency:       payload.Currency,
+		From:           from,
+		To:             to,
+		OpeningBalance: opening,
+		ClosingBalance: closing,
+		GeneratedAt:    time.Now(),
+	}
+
+	return &Statement{
+		Format:      payload.Format,
+		ContentType: statement.ContentType(payload.Format),
+		Filename:    statement.Filename(payload.Format, header),
+		header:      header,
+		filter: entity.GetBalancesHistory{
+			UserID:   payload.UserID,
+			Currency: payload.Currency,
+			From:     from.UnixMilli(),
+			To:       to.UnixMilli(),
+		},
+	}, http.StatusOK, nil
+}
+
+// WriteStatement streams the statement's entries from the history into w
+func (s *service) WriteStatement(ctx context.Context, stmt *Statement, w io.Writer) error {
+	sw, err := statement.NewWriter(stmt.Format, w)
+	if err != nil {
+		return err
+	}
+	if err := sw.WriteHeader(stmt.header); err != nil {
+		return err
+	}
+
+	_, err = s.balanceRepo.StreamBalancesHistory(ctx, stmt.filter, func(bh entity.BalanceHistory) error {
+		// rejected top ups and the like never moved money, they are not booked entries
+		if bh.Balance == 0 {
+			return nil
+		}
+		return sw.WriteEntry(statement.Entry{
+			ID:                bh.ID,
+			TransactionID:     bh.TransactionID,
+			Type:              bh.Type,
+			Amount:            bh.Balance,
+			BankName:          bh.SourceBankName,
+			BankAccountNumber: bh.SourceBankAccountNumber,
+			CreatedAt:         time.UnixMilli(bh.CreatedAt).In(stmt.header.From.Location()),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return sw.Close()
+}
+
+// balanceAt returns the user's balance in currency at the instant, zero if they never held it
+func (s *service) balanceAt(ctx context.Context, userID string, currency string, at int64) (int64, int, error) {
+	balances, code, err := s.balanceRepo.GetBalancesAt(ctx, userID, at)
+	if err != nil {
+		return 0, code, err
+	}
+	for _, b := range balances {
+		if b.Currency == currency {
+			return b.Balance, code, nil
+		}
+	}
+
+	return 0, code, nil
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/common"
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/helper/jwt"
+	"github.com/ovrrtd/openidea-bank/internal/helper/validator"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/ovrrtd/openidea-bank/internal/model/response"
+
+	jwtV5 "github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+)
+
+// RefreshToken trades a refresh token for a new access and refresh token pair
+func (s *service) RefreshToken(ctx context.Context, payload request.RefreshToken) (*response.Token, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	refreshToken, next, err := newRefreshToken(time.Now(), s.cfg.RefreshTokenTTL)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, err.Error())
+	}
+	rotated, code, err := s.tokenRepo.RotateRefreshToken(ctx, entity.RotateRefreshToken{
+		TokenHash: hashToken(payload.RefreshToken),
+		Next:      next,
+	})
+	if err != nil {
+		return nil, code, err
+	}
+
+	accessToken, err := s.newAccessToken(rotated.UserID, time.Now())
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, err.Error())
+	}
+
+	return &response.Token{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.cfg.AccessTokenTTL.Seconds()),
+	}, http.StatusOK, nil
+}
+
+// Logout revokes the access token of the request and, when given, the refresh token family it was issued with
+func (s *service) Logout(ctx context.Context, payload request.Logout) (int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	if payload.RefreshToken != "" {
+		code, err := s.tokenRepo.RevokeRefreshToken(ctx, entity.RevokeRefreshToken{
+			TokenHash: hashToken(payload.RefreshToken),
+			UserID:    payload.UserID,
+			RevokedAt: time.Now().UnixMilli(),
+		})
+		if err != nil {
+			return code, err
+		}
+	}
+
+	return s.tokenRepo.RevokeAccessToken(ctx, entity.RevokeAccessToken{
+		JTI:       payload.JTI,
+		ExpiresAt: payload.ExpiresAt,
+	})
+}
+
+// VerifyAccessToken checks the signature and expiry of an access token and that it was not revoked by a logout
+func (s *service) VerifyAccessToken(ctx context.Context, token string) (*common.UserClaims, int, error) {
+	claims := &common.UserClaims{}
+	err := jwt.VerifyJwt(token, claims, s.cfg.JwtKeys)
+	if err != nil {
+		if errors.Is(err, errorer.ErrUnauthorized) {
+			return nil, http.StatusUnauthorized, errors.Wrap(errorer.ErrUnauthorized, err.Error())
+		}
+		return nil, http.StatusForbidden, errors.Wrap(errorer.ErrForbidden, err.Error())
+	}
+	if claims.ID == "" {
+		return nil, http.StatusUnauthorized, errors.Wrap(errorer.ErrUnauthorized, errorer.ErrUnauthorized.Error())
+	}
+
+	revoked, code, err := s.tokenRepo.IsAccessTokenRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, code, err
+	}
+	if revoked {
+		return nil, http.StatusUnauthorized, errors.Wrap(errorer.ErrTokenRevoked, errorer.ErrTokenRevoked.Error())
+	}
+
+	return claims, http.StatusOK, nil
+}
+
+// GetJWKS publishes the public keys access tokens are signed with
+func (s *service) GetJWKS(ctx context.Context) (*jwt.JWKS, int, error) {
+	jwks := s.cfg.JwtKeys.JWKS()
+	return &jwks, http.StatusOK, nil
+}
+
+func (s *service) PurgeExpiredTokens(ctx context.Context) error {
+	now := time.Now().UnixMilli()
+	n, _, err := s.tokenRepo.DeleteExpiredTokens(ctx, now)
+	if err != nil {
+		return err
+	}
+	challenges, _, err := s.userRepo.DeleteExpiredMfaChallenges(ctx, now)
+	if err != nil {
+		return err
+	}
+	n += challenges
+	if n > 0 {
+		s.log.Info().Int64("count", n).Msg("purged expired tokens")
+	}
+
+	return nil
+}
+
+// issueTokens starts a new refresh token family for the user, one per login
+func (s *service) issueTokens(ctx context.Context, userID string) (*response.Token, int, error) {
+	now := time.Now()
+	accessToken, err := s.newAccessToken(userID, now)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, err.Error())
+	}
+
+	refreshToken, stored, err := newRefreshToken(now, s.cfg.RefreshTokenTTL)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.Wrap(err, err.Error())
+	}
+	stored.FamilyID = common.GenerateULID()
+	stored.UserID = userID
+	code, err := s.tokenRepo.CreateRefreshToken(ctx, stored)
+	if err != nil {
+		return nil, code, err
+	}
+
+	return &response.Token{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.cfg.AccessTokenTTL.Seconds()),
+	}, http.StatusOK, nil
+}
+
+func (s *service) newAccessToken(userID string, now time.Time) (string, error) {
+	return jwt.GenerateJwt(common.UserClaims{
+		Id: userID,
+		RegisteredClaims: jwtV5.RegisteredClaims{
+			ID:        common.GenerateULID(),
+			IssuedAt:  jwtV5.NewNumericDate(now),
+			ExpiresAt: jwtV5.NewNumericDate(now.Add(s.cfg.AccessTokenTTL)),
+		},
+	}, s.cfg.JwtKeys)
+}
+
+// newRefreshToken returns a random token for the client and the record storing its hash
+func newRefreshToken(now time.Time, ttl time.Duration) (string, entity.RefreshToken, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", entity.RefreshToken{}, err
+	}
+
+	return token, entity.RefreshToken{
+		ID:        common.GenerateULID(),
+		TokenHash: hashToken(token),
+		ExpiresAt: now.Add(ttl).UnixMilli(),
+		CreatedAt: now.UnixMilli(),
+	}, nil
+}
+
+// randomToken returns an opaque 256 bit token, only its hash is stored
+func randomToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
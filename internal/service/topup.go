@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/helper/validator"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/ovrrtd/openidea-bank/internal/model/money"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/ovrrtd/openidea-bank/internal/model/response"
+	"github.com/pkg/errors"
+)
+
+// GetTopUps lists top ups in a status for review, pending ones by default
+func (s *service) GetTopUps(ctx context.Context, payload request.GetTopUps) ([]response.TopUp, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+	if payload.Status == "" {
+		payload.Status = entity.TopUpStatusPendingReview
+	}
+
+	topUps, code, err := s.balanceRepo.GetTopUps(ctx, entity.GetTopUps{
+		Status: payload.Status,
+		Limit:  payload.Limit,
+		Offset: payload.Offset,
+	})
+	if err != nil {
+		return nil, code, err
+	}
+
+	res := make([]response.TopUp, len(topUps))
+	for i, v := range topUps {
+		res[i] = *toTopUpResponse(v)
+	}
+
+	return res, code, nil
+}
+
+func (s *service) ApproveTopUp(ctx context.Context, payload request.ReviewTopUp) (*response.TopUp, int, error) {
+	return s.reviewTopUp(ctx, payload, true)
+}
+
+// RejectTopUp turns a top up down without crediting the wallet, the reason is kept for the user and the audit log
+func (s *service) RejectTopUp(ctx context.Context, payload request.ReviewTopUp) (*response.TopUp, int, error) {
+	if l := len(payload.Reason); l < 5 || l > 255 {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrBadRequest, "reason must be between 5 and 255 characters")
+	}
+	return s.reviewTopUp(ctx, payload, false)
+}
+
+func (s *service) reviewTopUp(ctx context.Context, payload request.ReviewTopUp, approve bool) (*response.TopUp, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	topUp, code, err := s.balanceRepo.ReviewTopUp(ctx, entity.ReviewTopUp{
+		TopUpID:    payload.TopUpID,
+		ReviewerID: payload.ReviewerID,
+		Approve:    approve,
+		Reason:     payload.Reason,
+		ReviewedAt: time.Now().UnixMilli(),
+	})
+	if err != nil {
+		return nil, code, err
+	}
+
+	return toTopUpResponse(*topUp), code, nil
+}
+
+func toTopUpResponse(topUp entity.TopUp) *response.TopUp {
+	res := response.TopUp{
+		TopUpID:          topUp.ID,
+		UserID:           topUp.UserID,
+		Balance:          money.Format(topUp.Amount, topUp.Currency),
+		Currency:         topUp.Currency,
+		TransferProofImg: topUp.ProofImageURL,
+		Status:           topUp.Status,
+		ReviewerID:       topUp.ReviewerID,
+		RejectionReason:  topUp.RejectionReason,
+		ReviewedAt:       topUp.ReviewedAt,
+		CreatedAt:        topUp.CreatedAt,
+	}
+	res.Source.BankAccountNumber = topUp.SenderBankAccountNumber
+	res.Source.BankName = topUp.SenderBankName
+
+	return &res
+}
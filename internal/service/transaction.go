@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/helper/validator"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/ovrrtd/openidea-bank/internal/model/money"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/ovrrtd/openidea-bank/internal/model/response"
+	"github.com/pkg/errors"
+)
+
+const (
+	transactionBatchSize = 50
+	// transactionLease keeps a claimed transaction away from other workers while it is being handled
+	transactionLease = time.Minute
+	// transactionPollInterval is how long to wait before asking the bank again about a transfer
+	transactionPollInterval = 5 * time.Second
+	// transactionReturnPollInterval is how long to wait before asking the bank again whether a settled transfer was returned
+	transactionReturnPollInterval = time.Hour
+)
+
+func (s *service) GetTransaction(ctx context.Context, payload request.GetTransaction) (*response.Transaction, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	transaction, code, err := s.balanceRepo.GetTransaction(ctx, payload.TransactionID, payload.UserID)
+	if err != nil {
+		return nil, code, err
+	}
+
+	return toTransactionResponse(*transaction), code, nil
+}
+
+// ProcessOutboundTransactions submits pending transactions to the bank, follows up on the ones in flight
+// and watches settled ones for returns
+func (s *service) ProcessOutboundTransactions(ctx context.Context) error {
+	if err := s.submitPendingTransactions(ctx); err != nil {
+		return err
+	}
+	if err := s.pollProcessingTransactions(ctx); err != nil {
+		return err
+	}
+	return s.pollCompletedTransactions(ctx)
+}
+
+func (s *service) submitPendingTransactions(ctx context.Context) error {
+	now := time.Now()
+	transactions, _, err := s.balanceRepo.ClaimTransactions(ctx, entity.ClaimTransactions{
+		Status:     entity.TransactionStatusPending,
+		Now:        now.UnixMilli(),
+		LeaseUntil: now.Add(transactionLease).UnixMilli(),
+		Limit:      transactionBatchSize,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, t := range transactions {
+		update := entity.UpdateTransactionStatus{
+			ID:         t.ID,
+			FromStatus: entity.TransactionStatusPending,
+			ToStatus:   entity.TransactionStatusProcessing,
+			Attempts:   t.Attempts + 1,
+		}
+
+		// should recording the outcome below fail, the transaction is submitted again once its lease runs
+		// out, which the gateway answers with the same reference since Submit is idempotent on the id
+		reference, err := s.bankGateway.Submit(ctx, t)
+		if err != nil {
+			s.log.Warn().Err(err).Str("transactionId", t.ID).Int("attempt", update.Attempts).Msg("bank rejected transaction submission")
+			update.ToStatus = entity.TransactionStatusPending
+			update.FailureReason = err.Error()
+			// back off exponentially between submissions
+			update.NextAttemptAt = time.Now().Add(transactionPollInterval << update.Attempts).UnixMilli()
+			if update.Attempts >= s.cfg.TransferMaxAttempts {
+				update.ToStatus = entity.TransactionStatusFailed
+			}
+		} else {
+			update.GatewayReference = reference
+			update.NextAttemptAt = time.Now().Add(transactionPollInterval).UnixMilli()
+		}
+
+		update.UpdatedAt = time.Now().UnixMilli()
+		if _, err := s.balanceRepo.UpdateTransactionStatus(ctx, update); err != nil {
+			s.log.Error().Err(err).Str("transactionId", t.ID).Msg("failed to update transaction status")
+		}
+	}
+
+	return nil
+}
+
+func (s *service) pollProcessingTransactions(ctx context.Context) error {
+	now := time.Now()
+	transactions, _, err := s.balanceRepo.ClaimTransactions(ctx, entity.ClaimTransactions{
+		Status:     entity.TransactionStatusProcessing,
+		Now:        now.UnixMilli(),
+		LeaseUntil: now.Add(transactionLease).UnixMilli(),
+		Limit:      transactionBatchSize,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, t := range transactions {
+		update := entity.UpdateTransactionStatus{
+			ID:            t.ID,
+			FromStatus:    entity.TransactionStatusProcessing,
+			ToStatus:      entity.TransactionStatusProcessing,
+			Attempts:      t.Attempts,
+			NextAttemptAt: time.Now().Add(transactionPollInterval).UnixMilli(),
+		}
+
+		result, err := s.bankGateway.Status(ctx, t.GatewayReference)
+		if err != nil {
+			update.PollErrors = t.PollErrors + 1
+			s.log.Warn().Err(err).Str("transactionId", t.ID).Int("pollErrors", update.PollErrors).Msg("failed to get transaction status from bank")
+			update.FailureReason = err.Error()
+			// back off exponentially while the bank does not answer
+			update.NextAttemptAt = time.Now().Add(transactionPollInterval << update.PollErrors).UnixMilli()
+			if update.PollErrors >= s.cfg.TransferMaxAttempts {
+				// the money may or may not have left, so it stays debited until someone checks with the bank
+				update.ToStatus = entity.TransactionStatusUnresolved
+				s.log.Error().Err(err).Str("transactionId", t.ID).Str("gatewayReference", t.GatewayReference).Msg("bank stopped answering about transaction, leaving it unresolved")
+			}
+		} else {
+			update.ToStatus = result.Status
+			update.FailureReason = result.FailureReason
+		}
+
+		update.UpdatedAt = time.Now().UnixMilli()
+		if _, err := s.balanceRepo.UpdateTransactionStatus(ctx, update); err != nil {
+			s.log.Error().Err(err).Str("transactionId", t.ID).Msg("failed to update transaction status")
+			continue
+		}
+		if update.ToStatus != update.FromStatus {
+			s.log.Info().Str("transactionId", t.ID).Str("status", update.ToStatus).Msg("transaction status changed")
+		}
+	}
+
+	return nil
+}
+
+// pollCompletedTransactions asks the bank about transfers settled within the return window, a transfer
+// the bank returns is refunded to the wallet out of the bank outflow
+func (s *service) pollCompletedTransactions(ctx context.Context) error {
+	now := time.Now()
+	transactions, _, err := s.balanceRepo.ClaimTransactions(ctx, entity.ClaimTransactions{
+		Status:       entity.TransactionStatusCompleted,
+		Now:          now.UnixMilli(),
+		LeaseUntil:   now.Add(transactionLease).UnixMilli(),
+		Limit:        transactionBatchSize,
+		SettledAfter: now.Add(-s.cfg.TransferReturnWindow).UnixMilli(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, t := range transactions {
+		update := entity.UpdateTransactionStatus{
+			ID:            t.ID,
+			FromStatus:    entity.TransactionStatusCompleted,
+			ToStatus:      entity.TransactionStatusCompleted,
+			Attempts:      t.Attempts,
+			NextAttemptAt: time.Now().Add(transactionReturnPollInterval).UnixMilli(),
+		}
+
+		result, err := s.bankGateway.Status(ctx, t.GatewayReference)
+		if err != nil {
+			// the lease runs out and the transfer is asked about again like any other
+			s.log.Warn().Err(err).Str("transactionId", t.ID).Msg("failed to get settled transaction status from bank")
+			continue
+		}
+		if result.Status == entity.TransactionStatusReturned {
+			update.ToStatus = result.Status
+			update.FailureReason = result.FailureReason
+		}
+
+		update.UpdatedAt = time.Now().UnixMilli()
+		if _, err := s.balanceRepo.UpdateTransactionStatus(ctx, update); err != nil {
+			s.log.Error().Err(err).Str("transactionId", t.ID).Msg("failed to update transaction status")
+			continue
+		}
+		if update.ToStatus != update.FromStatus {
+			s.log.Info().Str("transactionId", t.ID).Str("status", update.ToStatus).Msg("settled transaction returned")
+		}
+	}
+
+	return nil
+}
+
+func toTransactionResponse(transaction entity.Transaction) *response.Transaction {
+	res := &response.Transaction{
+		TransactionID: transaction.ID,
+		Status:        transaction.Status,
+		Balance:       money.Format(transaction.Amount, transaction.Currency),
+		Currency:      transaction.Currency,
+		BeneficiaryID: transaction.BeneficiaryID,
+		FailureReason: transaction.FailureReason,
+		CreatedAt:     transaction.CreatedAt,
+		UpdatedAt:     transaction.UpdatedAt,
+	}
+	res.Recipient.BankAccountNumber = transaction.BankAccountNumber
+	res.Recipient.BankName = transaction.BankName
+
+	return res
+}
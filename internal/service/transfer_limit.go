@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
+	"github.com/ovrrtd/openidea-bank/internal/helper/validator"
+	"github.com/ovrrtd/openidea-bank/internal/model/entity"
+	"github.com/ovrrtd/openidea-bank/internal/model/money"
+	"github.com/ovrrtd/openidea-bank/internal/model/request"
+	"github.com/ovrrtd/openidea-bank/internal/model/response"
+	"github.com/pkg/errors"
+)
+
+// GetTransferLimits lists the limits of every currency the user has a limit in or sent money out of this month
+func (s *service) GetTransferLimits(ctx context.Context, userID string) ([]response.TransferLimit, int, error) {
+	now := time.Now().UTC()
+	dayStart, monthStart := limitPeriods(now)
+
+	overrides, code, err := s.balanceRepo.GetTransferLimits(ctx, userID)
+	if err != nil {
+		return nil, code, err
+	}
+	usages, code, err := s.balanceRepo.GetTransferUsage(ctx, userID, dayStart.UnixMilli(), monthStart.UnixMilli())
+	if err != nil {
+		return nil, code, err
+	}
+
+	limits := make(map[string]entity.TransferLimit, len(s.cfg.TransferLimits))
+	for currency, limit := range s.cfg.TransferLimits {
+		limits[currency] = limit
+	}
+	overridden := make(map[string]bool, len(overrides))
+	for _, limit := range overrides {
+		limits[limit.Currency] = limit
+		overridden[limit.Currency] = true
+	}
+	used := make(map[string]entity.TransferUsage, len(usages))
+	for _, usage := range usages {
+		used[usage.Currency] = usage
+		if _, ok := limits[usage.Currency]; !ok {
+			limits[usage.Currency] = entity.TransferLimit{Currency: usage.Currency}
+		}
+	}
+
+	res := make([]response.TransferLimit, 0, len(limits))
+	for currency, limit := range limits {
+		usage := used[currency]
+		item := response.TransferLimit{
+			Currency:   currency,
+			Daily:      toTransferLimitPeriod(limit.Daily, usage.Daily, currency, dayStart.AddDate(0, 0, 1)),
+			Monthly:    toTransferLimitPeriod(limit.Monthly, usage.Monthly, currency, monthStart.AddDate(0, 1, 0)),
+			Overridden: overridden[currency],
+		}
+		if limit.PerTransaction > 0 {
+			item.PerTransaction = money.Format(limit.PerTransaction, currency)
+		}
+		res = append(res, item)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Currency < res[j].Currency })
+
+	return res, http.StatusOK, nil
+}
+
+func (s *service) SetUserTransferLimits(ctx context.Context, payload request.SetTransferLimits) ([]response.TransferLimit, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	if _, code, err := s.userRepo.FindByID(ctx, payload.UserID); err != nil {
+		return nil, code, err
+	}
+
+	limits := make([]entity.TransferLimit, len(payload.Limits))
+	for i, v := range payload.Limits {
+		limits[i] = entity.TransferLimit{Currency: v.Currency}
+		for _, amount := range []struct {
+			value request.Amount
+			dst   *int64
+		}{
+			{v.PerTransaction, &limits[i].PerTransaction},
+			{v.Daily, &limits[i].Daily},
+			{v.Monthly, &limits[i].Monthly},
+		} {
+			minor, code, err := parseOptionalAmount(amount.value, v.Currency)
+			if err != nil {
+				return nil, code, err
+			}
+			*amount.dst = minor
+		}
+	}
+	code, err := s.balanceRepo.UpsertTransferLimits(ctx, entity.UpsertTransferLimits{
+		UserID:    payload.UserID,
+		AdminID:   payload.AdminID,
+		Limits:    limits,
+		UpdatedAt: time.Now().UnixMilli(),
+	})
+	if err != nil {
+		return nil, code, err
+	}
+
+	return s.GetTransferLimits(ctx, payload.UserID)
+}
+
+// ResetUserTransferLimit puts a user back on the default limits of a currency
+func (s *service) ResetUserTransferLimit(ctx context.Context, payload request.ResetTransferLimit) ([]response.TransferLimit, int, error) {
+	err := validator.ValidateStruct(&payload)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInputRequest(err), errorer.ErrInputRequest(err).Error())
+	}
+
+	code, err := s.balanceRepo.DeleteTransferLimit(ctx, entity.DeleteTransferLimit{
+		UserID:    payload.UserID,
+		AdminID:   payload.AdminID,
+		Currency:  payload.Currency,
+		DeletedAt: time.Now().UnixMilli(),
+	})
+	if err != nil {
+		return nil, code, err
+	}
+
+	return s.GetTransferLimits(ctx, payload.UserID)
+}
+
+// outboundChecks collects the caps an outbound transaction of the user in currency is held to
+func (s *service) outboundChecks(ctx context.Context, userID string, currency string, now time.Time) (entity.OutboundChecks, int, error) {
+	check, code, err := s.transferLimitCheck(ctx, userID, currency, now)
+	if err != nil {
+		return entity.OutboundChecks{}, code, err
+	}
+
+	return entity.OutboundChecks{
+		CoolingOff:    s.beneficiaryCoolingOff(now),
+		TransferLimit: check,
+	}, http.StatusOK, nil
+}
+
+// transferLimitCheck resolves the limit a debit of the user in currency is held to, their override or the default
+func (s *service) transferLimitCheck(ctx context.Context, userID string, currency string, now time.Time) (entity.TransferLimitCheck, int, error) {
+	limit, ok := s.cfg.TransferLimits[currency]
+	overrides, code, err := s.balanceRepo.GetTransferLimits(ctx, userID)
+	if err != nil {
+		return entity.TransferLimitCheck{}, code, err
+	}
+	for _, v := range overrides {
+		if v.Currency == currency {
+			limit, ok = v, true
+		}
+	}
+	if !ok {
+		limit = entity.TransferLimit{Currency: currency}
+	}
+
+	dayStart, monthStart := limitPeriods(now)
+	return entity.TransferLimitCheck{
+		Limit:      limit,
+		DayStart:   dayStart.UnixMilli(),
+		MonthStart: monthStart.UnixMilli(),
+	}, http.StatusOK, nil
+}
+
+// limitPeriods returns the start of the UTC day and month now falls in, the limits reset at both
+func limitPeriods(now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
+		time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func toTransferLimitPeriod(limit int64, used int64, currency string, resetsAt time.Time) response.TransferLimitPeriod {
+	res := response.TransferLimitPeriod{
+		Used:     money.Format(used, currency),
+		ResetsAt: resetsAt.UnixMilli(),
+	}
+	if limit > 0 {
+		remaining := limit - used
+		if remaining < 0 {
+			remaining = 0
+		}
+		res.Limit = money.Format(limit, currency)
+		res.Remaining = money.Format(remaining, currency)
+	}
+
+	return res
+}
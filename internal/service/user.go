@@ -8,14 +8,11 @@ import (
 
 	"github.com/ovrrtd/openidea-bank/internal/helper/common"
 	"github.com/ovrrtd/openidea-bank/internal/helper/errorer"
-	"github.com/ovrrtd/openidea-bank/internal/helper/jwt"
 	"github.com/ovrrtd/openidea-bank/internal/helper/validator"
 	"github.com/ovrrtd/openidea-bank/internal/model/entity"
 	"github.com/ovrrtd/openidea-bank/internal/model/request"
 	"github.com/ovrrtd/openidea-bank/internal/model/response"
 
-	jwtV5 "github.com/golang-jwt/jwt/v5"
-
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -48,6 +45,21 @@ func (s *service) Register(ctx context.Context, payload request.Register) (*resp
 
 	ent.Email = payload.Email
 
+	if payload.Phone != "" {
+		if !common.ValidatePhoneNumber(payload.Phone) {
+			return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInvalidPhone, errorer.ErrInvalidPhone.Error())
+		}
+
+		exist, code, err := s.userRepo.FindByPhone(ctx, payload.Phone)
+		if err != nil && code != http.StatusNotFound {
+			return nil, code, err
+		}
+		if exist != nil {
+			return nil, http.StatusConflict, errors.Wrap(errorer.ErrPhoneExist, errorer.ErrPhoneExist.Error())
+		}
+		ent.Phone = payload.Phone
+	}
+
 	// Hash the password before storing it
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(payload.Password), s.cfg.Salt)
 	if err != nil {
@@ -61,25 +73,18 @@ func (s *service) Register(ctx context.Context, payload request.Register) (*resp
 		return nil, code, err
 	}
 
-	// TODO: generate access token
-	userClaims := common.UserClaims{
-		Id: user.ID,
-		RegisteredClaims: jwtV5.RegisteredClaims{
-			IssuedAt:  jwtV5.NewNumericDate(time.Now()),
-			ExpiresAt: jwtV5.NewNumericDate(time.Now().Add(time.Hour * 24 * 30)),
-		},
-	}
-	tokenString, err := jwt.GenerateJwt(userClaims, s.cfg.JwtSecret)
-
+	tokens, code, err := s.issueTokens(ctx, user.ID)
 	if err != nil {
-		return nil, code, errors.Wrap(err, err.Error())
+		return nil, code, err
 	}
 
 	return &response.Register{
-		Name:        user.Name,
-		Email:       user.Email,
-		AccessToken: tokenString,
-	}, code, nil
+		Name:         user.Name,
+		Email:        user.Email,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	}, http.StatusCreated, nil
 }
 
 func (s *service) Login(ctx context.Context, payload request.Login) (*response.Login, int, error) {
@@ -95,34 +100,52 @@ func (s *service) Login(ctx context.Context, payload request.Login) (*response.L
 		return nil, http.StatusBadRequest, errors.Wrap(errorer.ErrInvalidEmail, errorer.ErrInvalidEmail.Error())
 	}
 
-	user, code, err := s.userRepo.FindByEmail(ctx, payload.Email)
+	counters, code, err := s.claimLoginAttempt(ctx, loginThrottleKeys(payload.Email, payload.IP), time.Now())
+	if err != nil {
+		return nil, code, err
+	}
 
+	user, code, err := s.userRepo.FindByEmail(ctx, payload.Email)
 	if err != nil {
+		if code != http.StatusNotFound {
+			return nil, code, err
+		}
+		s.comparePasswordOfUnknownUser(payload.Password)
+		code, err := s.loginFailed(counters)
 		return nil, code, err
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(payload.Password))
 	if err != nil {
-		return nil, http.StatusBadRequest, errors.Wrap(err, err.Error())
+		code, err := s.loginFailed(counters)
+		return nil, code, err
+	}
+	code, err = s.loginSucceeded(ctx, counters)
+	if err != nil {
+		return nil, code, err
 	}
 
-	userClaims := common.UserClaims{
-		Id: user.ID,
-		RegisteredClaims: jwtV5.RegisteredClaims{
-			IssuedAt:  jwtV5.NewNumericDate(time.Now()),
-			ExpiresAt: jwtV5.NewNumericDate(time.Now().Add(time.Hour * 24 * 30)),
-		},
+	challenge, code, err := s.mfaChallenge(ctx, user.ID)
+	if err != nil {
+		return nil, code, err
+	}
+	if challenge != nil {
+		challenge.Name = user.Name
+		challenge.Email = user.Email
+		return challenge, http.StatusOK, nil
 	}
-	tokenString, err := jwt.GenerateJwt(userClaims, s.cfg.JwtSecret)
 
+	tokens, code, err := s.issueTokens(ctx, user.ID)
 	if err != nil {
-		return nil, http.StatusInternalServerError, errors.Wrap(err, err.Error())
+		return nil, code, err
 	}
 
 	return &response.Login{
-		Name:        user.Name,
-		Email:       user.Email,
-		AccessToken: tokenString,
+		Name:         user.Name,
+		Email:        user.Email,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
 	}, http.StatusOK, nil
 }
 
@@ -135,5 +158,6 @@ func (s *service) GetUserByID(ctx context.Context, id string) (*response.User, i
 		ID:    user.ID,
 		Name:  user.Name,
 		Email: user.Email,
+		Role:  user.Role,
 	}, code, nil
 }
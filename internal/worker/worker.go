@@ -0,0 +1,27 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Run calls fn every interval until ctx is done, errors are logged and the loop carries on
+func Run(ctx context.Context, logger zerolog.Logger, name string, interval time.Duration, fn func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	logger.Info().Str("worker", name).Dur("interval", interval).Msg("worker started")
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info().Str("worker", name).Msg("worker stopped")
+			return
+		case <-ticker.C:
+			if err := fn(ctx); err != nil {
+				logger.Error().Stack().Err(err).Str("worker", name).Msg("worker run failed")
+			}
+		}
+	}
+}
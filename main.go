@@ -1,9 +1,18 @@
 package main
 
-import "github.com/ovrrtd/openidea-bank/cmd"
+import (
+	"os"
+
+	"github.com/ovrrtd/openidea-bank/cmd"
+)
 
 func main() {
-	if err := cmd.Server(); err != nil {
+	run := cmd.Server
+	if len(os.Args) > 1 && os.Args[1] == "backfill-balance-history" {
+		run = cmd.BackfillBalanceHistory
+	}
+
+	if err := run(); err != nil {
 		panic(err)
 	}
 }